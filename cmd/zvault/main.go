@@ -4,6 +4,7 @@ import (
 	"context"
 	"log/slog"
 	"os"
+	"path/filepath"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/zarlcorp/core/pkg/zapp"
@@ -21,6 +22,12 @@ func main() {
 	defer cancel()
 	_ = ctx // reserved for future use
 
+	if args, ok := cli.MultiCall(filepath.Base(os.Args[0]), os.Args[1:]); ok {
+		cli.Run(args, version)
+		_ = app.Close()
+		return
+	}
+
 	if len(os.Args) > 1 {
 		cli.Run(os.Args[1:], version)
 		_ = app.Close()
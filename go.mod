@@ -3,18 +3,22 @@ module github.com/zarlcorp/zvault
 go 1.26.0
 
 require (
+	filippo.io/age v1.2.1
 	github.com/charmbracelet/bubbles v1.0.0
 	github.com/charmbracelet/bubbletea v1.3.10
 	github.com/charmbracelet/lipgloss v1.1.0
 	github.com/zarlcorp/core/pkg/zapp v0.2.0
 	github.com/zarlcorp/core/pkg/zclipboard v0.1.0
+	github.com/zarlcorp/core/pkg/zcrypto v0.1.0
 	github.com/zarlcorp/core/pkg/zfilesystem v0.3.0
 	github.com/zarlcorp/core/pkg/zstore v0.1.0
 	github.com/zarlcorp/core/pkg/zstyle v0.5.11
+	golang.org/x/crypto v0.48.0
 	golang.org/x/term v0.40.0
 )
 
 require (
+	filippo.io/edwards25519 v1.1.0 // indirect
 	github.com/atotto/clipboard v0.1.4 // indirect
 	github.com/aymanbagabas/go-osc52/v2 v2.0.1 // indirect
 	github.com/charmbracelet/colorprofile v0.4.1 // indirect
@@ -34,10 +38,8 @@ require (
 	github.com/muesli/termenv v0.16.0 // indirect
 	github.com/rivo/uniseg v0.4.7 // indirect
 	github.com/xo/terminfo v0.0.0-20220910002029-abceb7e1c41e // indirect
-	github.com/zarlcorp/core/pkg/zcrypto v0.1.0 // indirect
 	github.com/zarlcorp/core/pkg/zoptions v0.1.0 // indirect
 	github.com/zarlcorp/core/pkg/zsync v0.1.0 // indirect
-	golang.org/x/crypto v0.48.0 // indirect
 	golang.org/x/sys v0.41.0 // indirect
 	golang.org/x/text v0.34.0 // indirect
 )
@@ -0,0 +1,141 @@
+// Package archive reads and writes zvault's JSON export: every secret and
+// task with all of their fields and timestamps, so a vault can be moved
+// between installs. An export can be sealed with a passphrase.
+package archive
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/zarlcorp/core/pkg/zcrypto"
+	"github.com/zarlcorp/zvault/internal/secret"
+	"github.com/zarlcorp/zvault/internal/task"
+)
+
+// Version is the archive format version written by Marshal.
+const Version = 1
+
+// Archive is the JSON export of a vault. IncludesValues is false when
+// secret values were redacted, in which case the archive can't be
+// imported.
+type Archive struct {
+	Version        int             `json:"version"`
+	ExportedAt     time.Time       `json:"exported_at"`
+	IncludesValues bool            `json:"includes_values"`
+	Secrets        []secret.Secret `json:"secrets"`
+	Tasks          []task.Task     `json:"tasks"`
+}
+
+// Marshal encodes an archive as indented JSON.
+func Marshal(a Archive) ([]byte, error) {
+	a.Version = Version
+	if a.Secrets == nil {
+		a.Secrets = []secret.Secret{}
+	}
+	if a.Tasks == nil {
+		a.Tasks = []task.Task{}
+	}
+	b, err := json.MarshalIndent(a, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("marshal archive: %w", err)
+	}
+	return append(b, '\n'), nil
+}
+
+// Unmarshal decodes an archive, rejecting versions newer than this build
+// understands.
+func Unmarshal(data []byte) (Archive, error) {
+	var a Archive
+	if err := json.Unmarshal(data, &a); err != nil {
+		return Archive{}, fmt.Errorf("parse archive: %w", err)
+	}
+	if a.Version < 1 {
+		return Archive{}, errors.New("not a zvault export")
+	}
+	if a.Version > Version {
+		return Archive{}, fmt.Errorf("archive version %d is newer than this zvault supports", a.Version)
+	}
+	return a, nil
+}
+
+// envelopeFormat marks a sealed export.
+const envelopeFormat = "zvault-encrypted"
+
+// envelope wraps a sealed export. The key is derived from the passphrase
+// with argon2id and the payload encrypted with AES-256-GCM.
+type envelope struct {
+	Format     string `json:"format"`
+	Version    int    `json:"version"`
+	KDF        string `json:"kdf"`
+	Salt       []byte `json:"salt"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// ErrPassphrase is returned by Open when the passphrase is wrong or the
+// envelope has been tampered with.
+var ErrPassphrase = errors.New("wrong passphrase or corrupted export")
+
+// Seal encrypts data with a passphrase and wraps it in a JSON envelope.
+func Seal(data []byte, passphrase string) ([]byte, error) {
+	if passphrase == "" {
+		return nil, errors.New("passphrase is empty")
+	}
+	key, salt, err := zcrypto.DeriveKey([]byte(passphrase), nil)
+	if err != nil {
+		return nil, fmt.Errorf("derive key: %w", err)
+	}
+	defer zcrypto.Erase(key)
+
+	ct, err := zcrypto.Encrypt(key, data)
+	if err != nil {
+		return nil, fmt.Errorf("encrypt: %w", err)
+	}
+	b, err := json.MarshalIndent(envelope{
+		Format:     envelopeFormat,
+		Version:    1,
+		KDF:        "argon2id",
+		Salt:       salt,
+		Ciphertext: ct,
+	}, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("marshal envelope: %w", err)
+	}
+	return append(b, '\n'), nil
+}
+
+// Open decrypts a sealed envelope.
+func Open(data []byte, passphrase string) ([]byte, error) {
+	var env envelope
+	if err := json.Unmarshal(data, &env); err != nil || env.Format != envelopeFormat {
+		return nil, errors.New("not an encrypted zvault export")
+	}
+	if env.Version != 1 || env.KDF != "argon2id" {
+		return nil, fmt.Errorf("unsupported envelope version %d (%s)", env.Version, env.KDF)
+	}
+
+	key, _, err := zcrypto.DeriveKey([]byte(passphrase), env.Salt)
+	if err != nil {
+		return nil, fmt.Errorf("derive key: %w", err)
+	}
+	defer zcrypto.Erase(key)
+
+	pt, err := zcrypto.Decrypt(key, env.Ciphertext)
+	if err != nil {
+		return nil, ErrPassphrase
+	}
+	return pt, nil
+}
+
+// Sealed reports whether data is an encrypted envelope.
+func Sealed(data []byte) bool {
+	if !bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")) {
+		return false
+	}
+	var env struct {
+		Format string `json:"format"`
+	}
+	return json.Unmarshal(data, &env) == nil && env.Format == envelopeFormat
+}
@@ -0,0 +1,127 @@
+package archive
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/zarlcorp/zvault/internal/secret"
+	"github.com/zarlcorp/zvault/internal/task"
+)
+
+func TestRoundTrip(t *testing.T) {
+	created := time.Date(2024, 3, 1, 9, 30, 0, 0, time.UTC)
+	due := time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC)
+	completed := time.Date(2024, 3, 20, 18, 0, 0, 0, time.UTC)
+
+	sec, err := secret.NewPassword("github", "https://github.com", "user", "pass123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sec.Fields["totp_secret"] = "JBSWY3DPEHPK3PXP"
+	sec.Tags = []string{"work", "dev"}
+	sec.CreatedAt = created
+	sec.UpdatedAt = created.Add(time.Hour)
+
+	tk := task.Task{
+		ID:          "abc12345",
+		Title:       "rotate keys",
+		Done:        true,
+		Priority:    task.PriorityHigh,
+		DueDate:     &due,
+		Tags:        []string{"ops"},
+		CreatedAt:   created,
+		CompletedAt: &completed,
+	}
+
+	in := Archive{
+		ExportedAt:     created.Add(48 * time.Hour),
+		IncludesValues: true,
+		Secrets:        []secret.Secret{sec},
+		Tasks:          []task.Task{tk},
+	}
+	data, err := Marshal(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := Unmarshal(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	in.Version = Version
+	if !reflect.DeepEqual(in, out) {
+		t.Fatalf("round trip mismatch:\n got %+v\nwant %+v", out, in)
+	}
+}
+
+func TestMarshalEmpty(t *testing.T) {
+	data, err := Marshal(Archive{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, err := Unmarshal(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.Secrets == nil || a.Tasks == nil {
+		t.Fatal("empty lists should encode as [] not null")
+	}
+}
+
+func TestUnmarshalRejects(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"not json", "# Secrets"},
+		{"no version", `{"secrets": []}`},
+		{"newer version", `{"version": 99}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Unmarshal([]byte(tt.data)); err == nil {
+				t.Fatal("expected error")
+			}
+		})
+	}
+}
+
+func TestSealOpen(t *testing.T) {
+	plain := []byte(`{"version": 1}`)
+	sealed, err := Seal(plain, "correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !Sealed(sealed) {
+		t.Fatal("Sealed = false for sealed data")
+	}
+	if Sealed(plain) {
+		t.Fatal("Sealed = true for plain data")
+	}
+
+	got, err := Open(sealed, "correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != string(plain) {
+		t.Fatalf("Open = %q, want %q", got, plain)
+	}
+
+	if _, err := Open(sealed, "wrong"); !errors.Is(err, ErrPassphrase) {
+		t.Fatalf("wrong passphrase: err = %v, want ErrPassphrase", err)
+	}
+}
+
+func TestSealEmptyPassphrase(t *testing.T) {
+	if _, err := Seal([]byte("x"), ""); err == nil {
+		t.Fatal("expected error for empty passphrase")
+	}
+}
+
+func TestOpenNotSealed(t *testing.T) {
+	if _, err := Open([]byte(`{"version": 1}`), "pw"); err == nil {
+		t.Fatal("expected error")
+	}
+}
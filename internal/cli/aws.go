@@ -0,0 +1,323 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/zarlcorp/zvault/internal/secret"
+	"github.com/zarlcorp/zvault/internal/vault"
+)
+
+// awsTag marks access keys created by zvault aws configure.
+const awsTag = "aws"
+
+// awsProcessCredential is the document credential_process must print.
+// https://docs.aws.amazon.com/sdkref/latest/guide/feature-process-credentials.html
+type awsProcessCredential struct {
+	Version         int    `json:"Version"`
+	AccessKeyID     string `json:"AccessKeyId"`
+	SecretAccessKey string `json:"SecretAccessKey"`
+	SessionToken    string `json:"SessionToken,omitempty"`
+	Expiration      string `json:"Expiration,omitempty"`
+}
+
+func awsCommand() *command {
+	return &command{
+		name:    "aws",
+		summary: "AWS credential_process provider",
+		help: `
+Credentials come from an apikey secret with these fields:
+  access_key_id       the access key ID (AKIA...)
+  secret_access_key   the secret access key (or the key field)
+  session_token       optional, for temporary credentials
+  expiration          optional, RFC 3339`,
+		subs: []*command{
+			{
+				name:     "credential-process",
+				summary:  "print credentials for the AWS CLI and SDKs",
+				args:     "<name>",
+				argNames: secretNames,
+				minArgs:  1,
+				maxArgs:  1,
+				run:      runAWSCredentialProcess,
+			},
+			{
+				name:     "configure",
+				summary:  "point an AWS profile at a secret",
+				args:     "<name>",
+				argNames: secretNames,
+				minArgs:  1,
+				maxArgs:  1,
+				help: `
+Write a credential_process line for the profile to $AWS_CONFIG_FILE or
+~/.aws/config. If the secret does not exist yet, ask for the keys and
+create it.`,
+				flags: []*flag{
+					{name: "profile", usage: "AWS profile (default \"default\")"},
+				},
+				run: runAWSConfigure,
+			},
+		},
+	}
+}
+
+func runAWSCredentialProcess(in *invocation) {
+	v := openVault()
+	sec, err := resolveSecret(v, in.args[0])
+	closeVault(v)
+	if err != nil {
+		errf("%v", err)
+		exit(1)
+	}
+
+	cred, err := awsCredentialFromSecret(sec)
+	if err != nil {
+		errf("%v", err)
+		exit(1)
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(cred); err != nil {
+		errf("%v", err)
+		exit(1)
+	}
+}
+
+// awsCredentialFromSecret builds the credential_process document from an
+// access key secret.
+func awsCredentialFromSecret(sec secret.Secret) (awsProcessCredential, error) {
+	id := sec.Fields["access_key_id"]
+	if id == "" {
+		return awsProcessCredential{}, fmt.Errorf("secret %q has no access_key_id field", sec.Name)
+	}
+	key := sec.Fields["secret_access_key"]
+	if key == "" {
+		key = sec.Fields["key"]
+	}
+	if key == "" {
+		return awsProcessCredential{}, fmt.Errorf("secret %q has no secret_access_key field", sec.Name)
+	}
+
+	cred := awsProcessCredential{
+		Version:         1,
+		AccessKeyID:     id,
+		SecretAccessKey: key,
+		SessionToken:    sec.Fields["session_token"],
+	}
+	if exp := sec.Fields["expiration"]; exp != "" {
+		t, err := time.Parse(time.RFC3339, exp)
+		if err != nil {
+			return awsProcessCredential{}, fmt.Errorf("secret %q: invalid expiration %q (want RFC 3339)", sec.Name, exp)
+		}
+		cred.Expiration = t.UTC().Format(time.RFC3339)
+	}
+	return cred, nil
+}
+
+// findAWSSecret resolves the secret aws configure points at. It reports
+// missing only when nothing matched, so that only then is it created;
+// an ambiguous name or a vault error is returned.
+func findAWSSecret(v *vault.Vault, name string) (secret.Secret, bool, error) {
+	sec, err := resolveSecret(v, name)
+	var nf *vault.NotFoundError
+	if errors.As(err, &nf) {
+		return secret.Secret{}, true, nil
+	}
+	return sec, false, err
+}
+
+func runAWSConfigure(in *invocation) {
+	profile := in.value("profile")
+	name := in.args[0]
+	if profile == "" {
+		profile = "default"
+	}
+
+	v := openVault()
+	sec, missing, err := findAWSSecret(v, name)
+	if err != nil {
+		closeVault(v)
+		errf("%v", err)
+		exit(1)
+	}
+	if missing {
+		if !promptConfirm(fmt.Sprintf("secret %q not found, create it?", name)) {
+			closeVault(v)
+			exit(1)
+		}
+		sec, err = newAWSSecret(name)
+		if err == nil {
+			err = v.Secrets().Add(sec)
+		}
+		if err != nil {
+			closeVault(v)
+			errf("store secret: %v", err)
+			exit(1)
+		}
+		fmt.Fprintf(os.Stderr, "%s %s stored\n", green(sec.ID), bold(sec.Name))
+	}
+	closeVault(v)
+
+	if _, err := awsCredentialFromSecret(sec); err != nil {
+		errf("%v", err)
+		exit(1)
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		errf("find zvault executable: %v", err)
+		exit(1)
+	}
+	command := awsQuote(exe) + " aws credential-process " + awsQuote(sec.Name)
+
+	path := awsConfigPath()
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		errf("read %s: %v", path, err)
+		exit(1)
+	}
+
+	updated := setAWSProfileValue(string(data), profile, "credential_process", command)
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		errf("%v", err)
+		exit(1)
+	}
+	if err := writePrivateFile(path, []byte(updated)); err != nil {
+		errf("write %s: %v", path, err)
+		exit(1)
+	}
+	fmt.Fprintf(os.Stderr, "%s profile %s in %s\n", green("configured"), bold(profile), path)
+
+	if awsCredentialsHasProfile(profile) {
+		fmt.Fprintln(os.Stderr, yellow(fmt.Sprintf("~/.aws/credentials still has keys for [%s]; remove them so the vault is used", profile)))
+	}
+}
+
+// newAWSSecret prompts for an access key pair.
+func newAWSSecret(name string) (secret.Secret, error) {
+	id := promptLine("access key id: ")
+	key := promptPassword("secret access key: ")
+	if id == "" || key == "" {
+		return secret.Secret{}, fmt.Errorf("access key id and secret access key are required")
+	}
+
+	sec, err := secret.NewAPIKey(name, "aws", key)
+	if err != nil {
+		return secret.Secret{}, err
+	}
+	sec.Fields["access_key_id"] = id
+	sec.Tags = []string{awsTag}
+	return sec, nil
+}
+
+func awsConfigPath() string {
+	if p := os.Getenv("AWS_CONFIG_FILE"); p != "" {
+		return p
+	}
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".aws", "config")
+}
+
+func awsCredentialsHasProfile(profile string) bool {
+	path := os.Getenv("AWS_SHARED_CREDENTIALS_FILE")
+	if path == "" {
+		home, _ := os.UserHomeDir()
+		path = filepath.Join(home, ".aws", "credentials")
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return false
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if strings.TrimSpace(line) == "["+profile+"]" {
+			return true
+		}
+	}
+	return false
+}
+
+// awsProfileSection is the section header of a profile in ~/.aws/config,
+// where every profile except default carries the "profile " prefix.
+func awsProfileSection(profile string) string {
+	if profile == "default" {
+		return "[default]"
+	}
+	return "[profile " + profile + "]"
+}
+
+// setAWSProfileValue sets key in the profile's section of an AWS config
+// file, adding the section if needed. Other lines are left untouched.
+func setAWSProfileValue(config, profile, key, value string) string {
+	header := awsProfileSection(profile)
+	entry := key + " = " + value
+
+	lines := strings.Split(strings.TrimRight(config, "\n"), "\n")
+	if config == "" {
+		lines = nil
+	}
+
+	start := -1
+	for i, line := range lines {
+		if sectionHeader(line) == header {
+			start = i
+			break
+		}
+	}
+
+	if start < 0 {
+		if len(lines) > 0 {
+			lines = append(lines, "")
+		}
+		lines = append(lines, header, entry)
+		return strings.Join(lines, "\n") + "\n"
+	}
+
+	end := len(lines)
+	for i := start + 1; i < len(lines); i++ {
+		if strings.HasPrefix(strings.TrimSpace(lines[i]), "[") {
+			end = i
+			break
+		}
+	}
+
+	for i := start + 1; i < end; i++ {
+		k, _, ok := strings.Cut(lines[i], "=")
+		if ok && strings.TrimSpace(k) == key {
+			lines[i] = entry
+			return strings.Join(lines, "\n") + "\n"
+		}
+	}
+
+	// insert after the last setting, before any blank lines that separate
+	// the next section
+	at := end
+	for at > start+1 && strings.TrimSpace(lines[at-1]) == "" {
+		at--
+	}
+	lines = append(lines[:at], append([]string{entry}, lines[at:]...)...)
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// sectionHeader normalises an ini section line such as "[ profile  dev ]".
+func sectionHeader(line string) string {
+	line = strings.TrimSpace(line)
+	if !strings.HasPrefix(line, "[") || !strings.HasSuffix(line, "]") {
+		return ""
+	}
+	return "[" + strings.Join(strings.Fields(line[1:len(line)-1]), " ") + "]"
+}
+
+// awsQuote double-quotes an argument of credential_process when needed;
+// the AWS CLI splits the command on whitespace outside quotes.
+func awsQuote(s string) string {
+	if s != "" && !strings.ContainsAny(s, " \t\"") {
+		return s
+	}
+	return `"` + strings.ReplaceAll(s, `"`, `\"`) + `"`
+}
@@ -2,9 +2,11 @@ package cli
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
 	"io"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 
@@ -14,54 +16,165 @@ import (
 	"github.com/zarlcorp/zvault/internal/vault"
 )
 
-// Run dispatches the top-level CLI subcommand.
+// Run parses args against the command tree and runs the command.
 func Run(args []string, version string) {
-	if len(args) == 0 {
-		printUsage()
-		os.Exit(1)
-	}
-
-	switch args[0] {
-	case "version":
-		fmt.Printf("zvault %s\n", version)
-	case "secret":
-		runSecret(args[1:])
-	case "task":
-		runTask(args[1:])
-	case "export":
-		runExport(args[1:])
-	case "completion":
-		runCompletion(args[1:])
-	case "help", "--help", "-h":
-		printUsage()
+	in, err := rootCommand(version).parse(args)
+	jsonOutput = in.has("json")
+	if in.given("password-fd") {
+		passwordFD = in.int("password-fd")
+	}
+	if err != nil {
+		errf("%v", err)
+		if !jsonOutput {
+			fmt.Fprintf(os.Stderr, "Run '%s --help' for usage.\n", in.cmd.path())
+		}
+		exit(1)
+	}
+
+	switch {
+	case in.has("help"):
+		in.cmd.writeHelp(os.Stderr)
+	case in.cmd.run == nil, len(in.cmd.subs) > 0 && len(in.args) == 0:
+		in.cmd.writeHelp(os.Stderr)
+		exit(1)
 	default:
-		errf("unknown command %q", args[0])
-		printUsage()
-		os.Exit(1)
+		in.cmd.run(in)
+	}
+}
+
+// rootCommand builds the command tree.
+func rootCommand(version string) *command {
+	return (&command{
+		name: "zvault",
+		help: `
+Commands that open the vault take its password from the first of these,
+and otherwise prompt for it on the terminal:
+
+  --password-fd <n>         a line read from file descriptor n
+  ZVAULT_PASSWORD_FILE      a file holding the password
+  ZVAULT_PASSWORD_COMMAND   a command that prints it, run by the shell
+  ZVAULT_PASSWORD           the password itself`,
+		flags: []*flag{
+			{name: "json", kind: boolFlag, usage: "print results and errors as JSON (secret, task, otp, export)"},
+			{name: "password-fd", kind: intFlag, usage: "read the vault password from this file descriptor"},
+			{name: "help", kind: boolFlag, short: 'h', usage: "show help"},
+		},
+		subs: []*command{
+			secretCommand(),
+			taskCommand(),
+			otpCommand(),
+			sshCommand(),
+			sshAgentCommand(),
+			gitCredentialCommand(),
+			dockerCredentialCommand(),
+			runCommand(),
+			injectCommand(),
+			envCommand(),
+			awsCommand(),
+			k8sCommand(),
+			netrcCommand(),
+			shareCommand(),
+			receiveCommand(),
+			importCommand(),
+			exportCommand(),
+			shellCommand(),
+			completionCommand(),
+			{
+				name:    "version",
+				summary: "print version",
+				run: func(*invocation) {
+					fmt.Printf("zvault %s\n", version)
+				},
+			},
+			{
+				name:    "help",
+				summary: "show help for a command",
+				args:    "[<command>...]",
+				maxArgs: unlimited,
+				run:     runHelp,
+			},
+			clipboardClearCommand(),
+			completeCommand(),
+		},
+	}).link()
+}
+
+func runHelp(in *invocation) {
+	c := in.cmd.parent
+	for _, name := range in.args {
+		sub := c.sub(name)
+		if sub == nil {
+			errfCode(codeUsage, "unknown command %q", strings.Join(in.args, " "))
+			exit(1)
+		}
+		c = sub
+	}
+	c.writeHelp(os.Stderr)
+}
+
+// MultiCall maps the name zvault was invoked under to CLI arguments, for
+// helpers that must be separate executables. It reports false for any
+// other name.
+func MultiCall(argv0 string, args []string) ([]string, bool) {
+	switch argv0 {
+	case "git-credential-zvault":
+		// credential.helper "zvault git-credential" runs
+		// git-credential-zvault git-credential <op>
+		if len(args) > 0 && args[0] == "git-credential" {
+			args = args[1:]
+		}
+		return append([]string{"git-credential"}, args...), true
+	case "docker-credential-zvault":
+		return append([]string{"docker-credential"}, args...), true
 	}
+	return nil, false
 }
 
-func printUsage() {
-	fmt.Fprint(os.Stderr, `Usage: zvault <command> [args]
+// exit ends the command with a status code. zvault shell replaces it to
+// end just the command line rather than the process.
+var exit = os.Exit
 
-Commands:
-  secret      manage secrets (store, get, list, delete, search)
-  task        manage tasks (add, list, done, edit, rm, clear)
-  export      export vault data as markdown
-  completion  generate shell completions
-  version     print version
-  help        show this help
+// session is the vault zvault shell keeps unlocked between command lines.
+// openVault returns it when set, and closeVault leaves it open.
+var session *vault.Vault
 
-Run 'zvault <command> --help' for command-specific help.
-`)
+// closeVault closes a vault from openVault, unless it's the shell's.
+func closeVault(v *vault.Vault) {
+	if v != session {
+		v.Close()
+	}
+}
+
+// passwordFD is the descriptor given with --password-fd, or -1. It is
+// read once; when zvault shell unlocks again, the password comes from the
+// environment or a prompt.
+var passwordFD = -1
+
+// masterPassword returns the password from --password-fd or the
+// environment, or "" when neither gives one.
+func masterPassword() (string, error) {
+	if passwordFD >= 0 {
+		fd := passwordFD
+		passwordFD = -1
+		return vault.PasswordFromFD(fd)
+	}
+	return vault.PasswordFromEnv()
 }
 
-// openVault prompts for the master password and opens the vault.
-// It reads from ZVAULT_PASSWORD env var first, then prompts interactively.
+// openVault gets the master password and opens the vault. The password
+// comes from --password-fd, ZVAULT_PASSWORD_FILE, ZVAULT_PASSWORD_COMMAND
+// or ZVAULT_PASSWORD, in that order, or else a prompt.
 func openVault() *vault.Vault {
+	if session != nil {
+		return session
+	}
 	dir := vault.DefaultDir()
 
-	password := vault.PasswordFromEnv()
+	password, err := masterPassword()
+	if err != nil {
+		errfCode(codeVault, "open vault: %v", err)
+		exit(1)
+	}
 	if password == "" {
 		password = promptPassword("vault password: ")
 	}
@@ -69,23 +182,35 @@ func openVault() *vault.Vault {
 	v, err := vault.Open(dir, password)
 	if err != nil {
 		if strings.Contains(err.Error(), "no such file") || strings.Contains(err.Error(), "does not exist") {
-			errf("vault not found — run the TUI to initialize: zvault")
-			os.Exit(1)
+			errfCode(codeVault, "vault not found — run the TUI to initialize: zvault")
+			exit(1)
 		}
-		errf("open vault: %v", err)
-		os.Exit(1)
+		errfCode(codeVault, "open vault: %v", err)
+		exit(1)
 	}
 	return v
 }
 
 // promptPassword reads a password from the terminal with masked input.
+// When stdin is piped (notes, credential helpers) it reads from /dev/tty.
 func promptPassword(prompt string) string {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		tty, err := os.Open("/dev/tty")
+		if err != nil {
+			errf("no terminal to prompt for a password (use --password-fd or ZVAULT_PASSWORD_FILE)")
+			exit(1)
+		}
+		defer tty.Close()
+		fd = int(tty.Fd())
+	}
+
 	fmt.Fprint(os.Stderr, prompt)
-	b, err := term.ReadPassword(int(os.Stdin.Fd()))
+	b, err := term.ReadPassword(fd)
 	fmt.Fprintln(os.Stderr) // newline after masked input
 	if err != nil {
 		errf("read password: %v", err)
-		os.Exit(1)
+		exit(1)
 	}
 	return string(b)
 }
@@ -111,6 +236,27 @@ func promptConfirm(prompt string) bool {
 	return false
 }
 
+// pickMatch asks which candidate of an *vault.AmbiguousError was meant
+// and returns its id. It reports false for other errors, when stdin or
+// stderr isn't a terminal, with --json, or when nothing valid is picked.
+func pickMatch(err error) (string, bool) {
+	var amb *vault.AmbiguousError
+	if !errors.As(err, &amb) || jsonOutput ||
+		!term.IsTerminal(int(os.Stdin.Fd())) || !term.IsTerminal(int(os.Stderr.Fd())) {
+		return "", false
+	}
+
+	fmt.Fprintf(os.Stderr, "%s %q matches %d:\n", amb.Kind, amb.Ref, len(amb.Matches))
+	for i, m := range amb.Matches {
+		fmt.Fprintf(os.Stderr, "  %d) %s  %s\n", i+1, m.Name, muted(m.ID))
+	}
+	n, err := strconv.Atoi(promptLine(fmt.Sprintf("which one? [1-%d] ", len(amb.Matches))))
+	if err != nil || n < 1 || n > len(amb.Matches) {
+		return "", false
+	}
+	return amb.Matches[n-1].ID, true
+}
+
 // readStdin reads all of stdin if it's piped (not a terminal).
 func readStdin() (string, bool) {
 	info, err := os.Stdin.Stat()
@@ -169,78 +315,45 @@ func parseIDs(s string) []string {
 	return ids
 }
 
-// formatDueDate formats a due date relative to today.
+// formatDueDate formats a due date relative to today, colored by how
+// soon it is.
 func formatDueDate(t *time.Time) string {
 	if t == nil {
 		return ""
 	}
+	switch diff := dueDays(*t); {
+	case diff <= 0:
+		return red(dueText(t))
+	case diff <= 7:
+		return yellow(dueText(t))
+	default:
+		return dueText(t)
+	}
+}
 
-	now := time.Now()
-	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
-	due := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
-	diff := int(due.Sub(today).Hours() / 24)
-
-	switch {
+// dueText describes a due date relative to today, without color.
+func dueText(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	switch diff := dueDays(*t); {
 	case diff < 0:
-		return red(fmt.Sprintf("overdue %dd", -diff))
+		return fmt.Sprintf("overdue %dd", -diff)
 	case diff == 0:
-		return red("today")
+		return "today"
 	case diff == 1:
-		return yellow("tomorrow")
+		return "tomorrow"
 	case diff <= 7:
-		return yellow(fmt.Sprintf("in %dd", diff))
+		return fmt.Sprintf("in %dd", diff)
 	default:
 		return t.Format("2006-01-02")
 	}
 }
 
-// hasFlag checks if a flag is present in args.
-func hasFlag(args []string, flag string) bool {
-	for _, a := range args {
-		if a == flag {
-			return true
-		}
-	}
-	return false
-}
-
-// flagValue returns the value of a flag (e.g., -t password returns "password").
-// Returns empty string if not found.
-func flagValue(args []string, flag string) string {
-	for i, a := range args {
-		if a == flag && i+1 < len(args) {
-			return args[i+1]
-		}
-	}
-	return ""
-}
-
-// stripFlags removes known flag-value pairs and bare flags from args, returning positional args.
-func stripFlags(args []string, valueFlags []string, bareFlags []string) []string {
-	vf := make(map[string]bool, len(valueFlags))
-	for _, f := range valueFlags {
-		vf[f] = true
-	}
-	bf := make(map[string]bool, len(bareFlags))
-	for _, f := range bareFlags {
-		bf[f] = true
-	}
-
-	var pos []string
-	skip := false
-	for _, a := range args {
-		if skip {
-			skip = false
-			continue
-		}
-		if vf[a] {
-			skip = true
-			continue
-		}
-		if bf[a] {
-			continue
-		}
-		pos = append(pos, a)
-	}
-	return pos
+// dueDays is the number of days from today to t, negative when past.
+func dueDays(t time.Time) int {
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	due := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	return int(due.Sub(today).Hours() / 24)
 }
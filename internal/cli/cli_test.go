@@ -1,9 +1,15 @@
 package cli
 
 import (
+	"os"
+	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
 	"time"
+
+	"github.com/zarlcorp/zvault/internal/secret"
+	"github.com/zarlcorp/zvault/internal/vault"
 )
 
 func TestParseDate(t *testing.T) {
@@ -143,105 +149,6 @@ func TestParsePriority(t *testing.T) {
 	}
 }
 
-func TestHasFlag(t *testing.T) {
-	args := []string{"--show", "-t", "password", "--verbose"}
-
-	if !hasFlag(args, "--show") {
-		t.Fatal("expected --show to be found")
-	}
-	if !hasFlag(args, "--verbose") {
-		t.Fatal("expected --verbose to be found")
-	}
-	if hasFlag(args, "--missing") {
-		t.Fatal("expected --missing to not be found")
-	}
-}
-
-func TestFlagValue(t *testing.T) {
-	args := []string{"-t", "password", "-n", "my secret", "--tags", "work,dev"}
-
-	tests := []struct {
-		flag string
-		want string
-	}{
-		{"-t", "password"},
-		{"-n", "my secret"},
-		{"--tags", "work,dev"},
-		{"--missing", ""},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.flag, func(t *testing.T) {
-			got := flagValue(args, tt.flag)
-			if got != tt.want {
-				t.Fatalf("flagValue(%q) = %q, want %q", tt.flag, got, tt.want)
-			}
-		})
-	}
-}
-
-func TestFlagValueAtEnd(t *testing.T) {
-	// flag at end without value
-	args := []string{"-t"}
-	got := flagValue(args, "-t")
-	if got != "" {
-		t.Fatalf("expected empty for flag at end, got %q", got)
-	}
-}
-
-func TestStripFlags(t *testing.T) {
-	tests := []struct {
-		name       string
-		args       []string
-		valueFlags []string
-		bareFlags  []string
-		want       []string
-	}{
-		{
-			"value flags",
-			[]string{"-p", "h", "-d", "tomorrow", "buy milk"},
-			[]string{"-p", "-d"},
-			nil,
-			[]string{"buy milk"},
-		},
-		{
-			"bare flags",
-			[]string{"--show", "abc123"},
-			nil,
-			[]string{"--show"},
-			[]string{"abc123"},
-		},
-		{
-			"mixed",
-			[]string{"-p", "h", "--tags", "work,dev", "--pending", "my", "task"},
-			[]string{"-p", "--tags"},
-			[]string{"--pending"},
-			[]string{"my", "task"},
-		},
-		{
-			"no flags",
-			[]string{"hello", "world"},
-			[]string{"-p"},
-			[]string{"--show"},
-			[]string{"hello", "world"},
-		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			got := stripFlags(tt.args, tt.valueFlags, tt.bareFlags)
-			if len(got) != len(tt.want) {
-				t.Fatalf("stripFlags() = %v, want %v", got, tt.want)
-			}
-			for i := range got {
-				if got[i] != tt.want[i] {
-					t.Fatalf("stripFlags()[%d] = %q, want %q", i, got[i], tt.want[i])
-				}
-			}
-		})
-	}
-}
-
 func TestFormatDueDate(t *testing.T) {
 	now := time.Now()
 	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
@@ -333,6 +240,7 @@ func TestContainsTag(t *testing.T) {
 }
 
 func TestCompletionOutput(t *testing.T) {
+	root := rootCommand("test")
 	tests := []struct {
 		name     string
 		script   string
@@ -340,18 +248,18 @@ func TestCompletionOutput(t *testing.T) {
 	}{
 		{
 			"bash",
-			bashCompletion,
-			[]string{"_zvault", "complete -F", "secret", "task", "export", "completion"},
+			bashCompletion(root),
+			[]string{"_zvault", "complete -F", `"zvault secret store:--type"`, "_command_offset", "zvault __complete --", `"zvault secret get") [[ -z $args ]] && _zvault_names`, "password apikey sshkey note"},
 		},
 		{
 			"zsh",
-			zshCompletion,
-			[]string{"#compdef zvault", "_zvault", "secret", "task", "export"},
+			zshCompletion(root),
+			[]string{"#compdef zvault", "_zvault_secret_store()", "{-t,--type}", "compdef _zvault zvault", "__zvault_names()", "'1: :__zvault_names'"},
 		},
 		{
 			"fish",
-			fishCompletion,
-			[]string{"complete -c zvault", "secret", "task", "export", "completion"},
+			fishCompletion(root),
+			[]string{"function __zvault_cmd", "complete -c zvault", "-l env-file", "-xa 'bash zsh fish'", "function __zvault_names"},
 		},
 	}
 
@@ -376,3 +284,114 @@ func TestNoColorEnvPresence(t *testing.T) {
 		t.Fatalf("expected plain text with NO_COLOR, got %q (plain: %q)", got, plain)
 	}
 }
+
+func TestParseFieldFlags(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "key")
+	if err := os.WriteFile(path, []byte("line one\nline two\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	in, err := rootCommand("test").parse([]string{"secret", "store",
+		"--field", "url=https://a=b", "--field", "notes=", "--field-from-file", "private_key=" + path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fields := map[string]string{"url": "old", "notes": "old"}
+	if err := parseFieldFlags(in, fields); err != nil {
+		t.Fatal(err)
+	}
+	if fields["url"] != "https://a=b" {
+		t.Errorf("url = %q", fields["url"])
+	}
+	if fields["private_key"] != "line one\nline two" {
+		t.Errorf("private_key = %q", fields["private_key"])
+	}
+
+	sec := secret.Secret{Fields: map[string]string{"url": "x", "notes": "n", "username": "u"}}
+	setFields(&sec, fields)
+	if _, ok := sec.Fields["notes"]; ok {
+		t.Error("empty value should remove the field")
+	}
+	if sec.Fields["username"] != "u" || sec.Fields["url"] != "https://a=b" {
+		t.Errorf("fields = %v", sec.Fields)
+	}
+
+	for _, bad := range []string{"nokey", "=value"} {
+		in, err := rootCommand("test").parse([]string{"secret", "edit", "x", "--field", bad})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := parseFieldFlags(in, map[string]string{}); err == nil {
+			t.Errorf("--field %q: expected error", bad)
+		}
+	}
+}
+
+func TestCompleteWords(t *testing.T) {
+	tests := []struct {
+		words  []string
+		kind   nameKind
+		prefix string
+	}{
+		{[]string{"secret", "get", ""}, secretNames, ""},
+		{[]string{"secret", "get", "gi"}, secretNames, "gi"},
+		{[]string{"secret", "get", "--show", "gi"}, secretNames, "gi"},
+		{[]string{"--json", "secret", "copy", "gi"}, secretNames, "gi"},
+		{[]string{"secret", "get", "github", ""}, noNames, ""},
+		{[]string{"secret", "get", "-"}, noNames, ""},
+		{[]string{"secret", "list", "--tag", "w"}, secretTags, "w"},
+		{[]string{"secret", "list", "--tag=w"}, secretTags, "w"},
+		{[]string{"secret", "list", "-t", "note", "--tag", ""}, secretTags, ""},
+		{[]string{"secret", "store", "-n", "x", ""}, noNames, ""},
+		{[]string{"task", ""}, taskIDs, ""},
+		{[]string{"task", "done", "ab,"}, taskIDs, "ab,"},
+		{[]string{"task", "add", "-p", "h", "--tags", "a"}, taskTags, "a"},
+		{[]string{"task", "edit", "ab12", ""}, noNames, ""},
+		{[]string{"otp", "g"}, secretNames, "g"},
+		{[]string{"run", "secret", "get", ""}, noNames, ""},
+		{[]string{"secret", ""}, noNames, ""},
+	}
+	root := rootCommand("test")
+	for _, tt := range tests {
+		t.Run(strings.Join(tt.words, " "), func(t *testing.T) {
+			kind, prefix := completeWords(root, tt.words)
+			if kind != tt.kind || prefix != tt.prefix {
+				t.Errorf("got %v %q, want %v %q", kind, prefix, tt.kind, tt.prefix)
+			}
+		})
+	}
+}
+
+func TestNameCompletions(t *testing.T) {
+	names := vault.Names{
+		Secrets: []vault.SecretName{
+			{ID: "a1b2c3d4", Name: "github", Type: secret.TypePassword, Tags: []string{"work", "git"}},
+			{ID: "a1ffffff", Name: "gitlab", Type: secret.TypeAPIKey, Tags: []string{"work"}},
+			{ID: "99999999", Name: "home wifi", Type: secret.TypePassword},
+		},
+		Tasks: []vault.TaskName{
+			{ID: "11111111", Title: "renew\tcert", Tags: []string{"ops"}},
+			{ID: "12222222", Title: "water plants", Done: true},
+		},
+	}
+	tests := []struct {
+		kind   nameKind
+		prefix string
+		want   []string
+	}{
+		{secretNames, "", []string{"github\tpassword", "gitlab\tapikey", "home wifi\tpassword"}},
+		{secretNames, "git", []string{"github\tpassword", "gitlab\tapikey"}},
+		{secretNames, "a1", []string{"a1b2c3d4\tgithub", "a1ffffff\tgitlab"}},
+		{taskIDs, "1", []string{"11111111\trenew cert", "12222222\twater plants (done)"}},
+		{taskIDs, "11111111,1", []string{"11111111,12222222\twater plants (done)"}},
+		{secretTags, "", []string{"git", "work"}},
+		{secretTags, "git,w", []string{"git,work"}},
+		{taskTags, "", []string{"ops"}},
+	}
+	for _, tt := range tests {
+		got := nameCompletions(names, tt.kind, tt.prefix)
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("nameCompletions(%v, %q) = %q, want %q", tt.kind, tt.prefix, got, tt.want)
+		}
+	}
+}
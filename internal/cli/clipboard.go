@@ -0,0 +1,102 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/zarlcorp/zvault/internal/clipboard"
+)
+
+// clipboardClearDelay is how long a copied value stays on the clipboard
+// by default.
+const clipboardClearDelay = 20 * time.Second
+
+// clipboardClearCommand is the hidden command copyToClipboard starts in
+// the background. It waits, then clears the clipboard if it still holds
+// the value whose hash it reads from stdin.
+func clipboardClearCommand() *command {
+	return &command{
+		name:    "__clipboard-clear",
+		summary: "clear the clipboard if it still holds a copied value",
+		hidden:  true,
+		flags: []*flag{
+			{name: "after", kind: durationFlag, usage: "wait this long first"},
+		},
+		run: runClipboardClear,
+	}
+}
+
+// copyToClipboard copies val and starts a detached zvault that clears it
+// after the delay, since this process exits right away. A delay of 0
+// leaves the value on the clipboard.
+func copyToClipboard(val string, after time.Duration) error {
+	if err := clipboard.Copy(val); err != nil {
+		return err
+	}
+	if after <= 0 {
+		return nil
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("find zvault executable: %w", err)
+	}
+
+	// the hash goes through a pipe rather than the command line, where
+	// other users could see it
+	r, w, err := os.Pipe()
+	if err != nil {
+		return fmt.Errorf("start clipboard clear: %w", err)
+	}
+	defer r.Close()
+	_, err = io.WriteString(w, clipboard.Sum(val))
+	w.Close()
+	if err != nil {
+		return fmt.Errorf("start clipboard clear: %w", err)
+	}
+
+	cmd := exec.Command(exe, "__clipboard-clear", "--after", after.String())
+	cmd.Stdin = r
+	cmd.SysProcAttr = detached()
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("start clipboard clear: %w", err)
+	}
+	return cmd.Process.Release()
+}
+
+func runClipboardClear(in *invocation) {
+	b, err := io.ReadAll(io.LimitReader(os.Stdin, 128))
+	if err != nil {
+		exit(1)
+	}
+	sum := strings.TrimSpace(string(b))
+	if sum == "" {
+		exit(1)
+	}
+
+	time.Sleep(in.duration("after"))
+	if _, err := clipboard.ClearIf(sum); err != nil {
+		exit(1)
+	}
+}
+
+// clearAfterFlag is the --clear-after flag of commands that copy.
+func clearAfterFlag() *flag {
+	return &flag{
+		name:  "clear-after",
+		kind:  durationFlag,
+		usage: "clear the clipboard after this long, if it still holds the value; 0 keeps it (default 20s)",
+	}
+}
+
+// clearAfter returns the --clear-after delay, or the default.
+func clearAfter(in *invocation) time.Duration {
+	if in.given("clear-after") {
+		return in.duration("clear-after")
+	}
+	return clipboardClearDelay
+}
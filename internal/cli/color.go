@@ -35,7 +35,20 @@ func boldMauve(s string) string  { return boldMauveStyle.Render(s) }
 func boldRed(s string) string    { return boldRedStyle.Render(s) }
 func boldYellow(s string) string { return boldYellowStyle.Render(s) }
 
-// errf prints a formatted error message to stderr.
+// errf prints a formatted error message to stderr, as a JSON object with
+// --json. The object's code comes from the typed errors among args.
 func errf(format string, args ...any) {
+	errfCode("", format, args...)
+}
+
+// errfCode is errf with an explicit --json error code.
+func errfCode(code, format string, args ...any) {
+	if jsonOutput {
+		if code == "" {
+			code = errorCode(args)
+		}
+		writeJSONError(code, fmt.Sprintf(format, args...))
+		return
+	}
 	fmt.Fprintf(os.Stderr, red("error: ")+format+"\n", args...)
 }
@@ -0,0 +1,459 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// command is a node of the command tree. Parsing, --help output and shell
+// completions are all generated from these definitions.
+type command struct {
+	name    string
+	aliases []string
+	summary string // one line, shown in command lists
+	args    string // positional arguments for the usage line, e.g. "<id-or-name>"
+	help    string // shown under the usage line by --help
+	flags   []*flag
+	subs    []*command
+
+	// minArgs and maxArgs bound the positional arguments; a negative
+	// maxArgs means no limit.
+	minArgs, maxArgs int
+
+	// passthrough ends flag parsing at the first positional argument,
+	// which starts another command line (zvault run).
+	passthrough bool
+
+	// hidden commands are left out of help and completions; zvault runs
+	// them itself.
+	hidden bool
+
+	// argChoices, argFiles and argNames say how the first positional
+	// argument completes.
+	argChoices []string
+	argFiles   bool
+	argNames   nameKind
+
+	// secretArgs marks positional arguments that carry secret values, so
+	// zvault shell keeps them out of its history.
+	secretArgs bool
+
+	// run is called with the parsed command line. A command with
+	// subcommands may also have one, for a bare positional argument
+	// (zvault task <id>).
+	run func(in *invocation)
+
+	parent *command
+}
+
+// unlimited is a maxArgs for commands taking any number of arguments.
+const unlimited = -1
+
+type flagKind int
+
+const (
+	stringFlag flagKind = iota
+	boolFlag
+	stringsFlag // repeatable; every value is kept
+	intFlag
+	durationFlag
+)
+
+// flag is an option of a command. Flags of the root command are global:
+// every command accepts them.
+type flag struct {
+	name  string // long name, without dashes
+	short byte   // single-letter name, or 0
+	kind  flagKind
+	value string // placeholder in help, e.g. "<name>"
+	usage string
+
+	// choices are the only accepted values; suggest are completed but
+	// not enforced.
+	choices []string
+	suggest []string
+	files   bool     // the value is a path, for completion
+	names   nameKind // the value is a name from the vault, for completion
+	secret  bool     // the value may be a secret, kept out of shell history
+}
+
+// nameKind says which names from the vault an argument or flag value
+// completes to. zvault __complete reads them from the vault's name index,
+// so completion never needs the password.
+type nameKind int
+
+const (
+	noNames     nameKind = iota
+	secretNames          // secret names and id prefixes
+	taskIDs              // task ids, described by their titles
+	secretTags
+	taskTags
+)
+
+// usageError reports a command line that doesn't fit the definitions.
+type usageError struct {
+	msg string
+}
+
+func (e *usageError) Error() string { return e.msg }
+
+func usagef(format string, args ...any) error {
+	return &usageError{msg: fmt.Sprintf(format, args...)}
+}
+
+// link sets the parent of every command below c.
+func (c *command) link() *command {
+	for _, sub := range c.subs {
+		sub.parent = c
+		sub.link()
+	}
+	return c
+}
+
+// path is the command line that selects c, e.g. "zvault secret store".
+func (c *command) path() string {
+	if c.parent == nil {
+		return c.name
+	}
+	return c.parent.path() + " " + c.name
+}
+
+func (c *command) sub(name string) *command {
+	for _, sub := range c.subs {
+		if sub.name == name || slices.Contains(sub.aliases, name) {
+			return sub
+		}
+	}
+	return nil
+}
+
+// visibleSubs returns the subcommands of c that aren't hidden.
+func (c *command) visibleSubs() []*command {
+	var out []*command
+	for _, sub := range c.subs {
+		if !sub.hidden {
+			out = append(out, sub)
+		}
+	}
+	return out
+}
+
+// allFlags returns c's flags followed by the global ones.
+func (c *command) allFlags() []*flag {
+	var out []*flag
+	for p := c; p != nil; p = p.parent {
+		out = append(out, p.flags...)
+	}
+	return out
+}
+
+func (c *command) longFlag(name string) *flag {
+	for _, f := range c.allFlags() {
+		if f.name == name {
+			return f
+		}
+	}
+	return nil
+}
+
+func (c *command) shortFlag(ch byte) *flag {
+	for _, f := range c.allFlags() {
+		if f.short != 0 && f.short == ch {
+			return f
+		}
+	}
+	return nil
+}
+
+// invocation is a parsed command line.
+type invocation struct {
+	cmd  *command
+	args []string
+	vals map[*flag][]string
+}
+
+// parse walks args down the command tree from c. Flags may come anywhere
+// among the arguments, as --name value, --name=value, -n value, -nvalue or
+// combined single-letter flags like -ab. Everything after "--" is
+// positional. The invocation is returned even on error, with whatever was
+// parsed so far.
+func (c *command) parse(args []string) (*invocation, error) {
+	in := &invocation{cmd: c, vals: make(map[*flag][]string)}
+
+	for i := 0; i < len(args); i++ {
+		a := args[i]
+		switch {
+		case a == "--":
+			in.args = append(in.args, args[i+1:]...)
+			i = len(args)
+
+		case strings.HasPrefix(a, "--"):
+			name, val, hasVal := strings.Cut(a[2:], "=")
+			f := in.cmd.longFlag(name)
+			if f == nil {
+				return in, usagef("unknown flag --%s", name)
+			}
+			if f.kind != boolFlag && !hasVal {
+				if i+1 >= len(args) {
+					return in, usagef("flag --%s needs a value", name)
+				}
+				i++
+				val = args[i]
+			}
+			if err := in.set(f, val, hasVal); err != nil {
+				return in, err
+			}
+
+		case len(a) > 1 && a[0] == '-':
+			for j := 1; j < len(a); j++ {
+				f := in.cmd.shortFlag(a[j])
+				if f == nil {
+					return in, usagef("unknown flag -%c", a[j])
+				}
+				if f.kind == boolFlag {
+					if err := in.set(f, "", false); err != nil {
+						return in, err
+					}
+					continue
+				}
+				val := a[j+1:]
+				if val == "" {
+					if i+1 >= len(args) {
+						return in, usagef("flag -%c needs a value", a[j])
+					}
+					i++
+					val = args[i]
+				}
+				if err := in.set(f, val, true); err != nil {
+					return in, err
+				}
+				break
+			}
+
+		default:
+			if len(in.args) == 0 && len(in.cmd.subs) > 0 {
+				if sub := in.cmd.sub(a); sub != nil {
+					in.cmd = sub
+					continue
+				}
+				if a == "help" {
+					in.vals[in.cmd.longFlag("help")] = []string{"true"}
+					continue
+				}
+				if in.cmd.run == nil {
+					if in.cmd.parent == nil {
+						return in, usagef("unknown command %q", a)
+					}
+					return in, usagef("unknown %s command %q", strings.TrimPrefix(in.cmd.path(), "zvault "), a)
+				}
+			}
+			in.args = append(in.args, a)
+			if in.cmd.passthrough {
+				in.args = append(in.args, args[i+1:]...)
+				i = len(args)
+			}
+		}
+	}
+
+	if in.has("help") || (len(in.cmd.subs) > 0 && len(in.args) == 0) {
+		return in, nil
+	}
+	if len(in.args) < in.cmd.minArgs {
+		return in, usagef("%s: %s required", in.cmd.path(), in.cmd.args)
+	}
+	if in.cmd.maxArgs >= 0 && len(in.args) > in.cmd.maxArgs {
+		return in, usagef("%s: unexpected argument %q", in.cmd.path(), in.args[in.cmd.maxArgs])
+	}
+	return in, nil
+}
+
+// set records a flag value, checking it against the flag's type.
+func (in *invocation) set(f *flag, val string, hasVal bool) error {
+	name := "--" + f.name
+	switch f.kind {
+	case boolFlag:
+		if !hasVal {
+			val = "true"
+		}
+		b, err := strconv.ParseBool(val)
+		if err != nil {
+			return usagef("invalid value %q for %s (want true or false)", val, name)
+		}
+		val = strconv.FormatBool(b)
+	case intFlag:
+		if _, err := strconv.Atoi(val); err != nil {
+			return usagef("invalid value %q for %s (want a number)", val, name)
+		}
+	case durationFlag:
+		if _, err := time.ParseDuration(val); err != nil {
+			return usagef("invalid value %q for %s (want a duration like 30m or 8h)", val, name)
+		}
+	}
+	if len(f.choices) > 0 && !slices.Contains(f.choices, val) {
+		return usagef("invalid value %q for %s (use %s)", val, name, strings.Join(f.choices, ", "))
+	}
+
+	if f.kind == stringsFlag {
+		in.vals[f] = append(in.vals[f], val)
+	} else {
+		in.vals[f] = []string{val}
+	}
+	return nil
+}
+
+func (in *invocation) flag(name string) *flag {
+	f := in.cmd.longFlag(name)
+	if f == nil {
+		panic(fmt.Sprintf("cli: %s has no flag --%s", in.cmd.path(), name))
+	}
+	return f
+}
+
+// has reports whether a bool flag is set.
+func (in *invocation) has(name string) bool {
+	vals := in.vals[in.flag(name)]
+	return len(vals) > 0 && vals[len(vals)-1] == "true"
+}
+
+// given reports whether a flag appeared on the command line at all.
+func (in *invocation) given(name string) bool {
+	return len(in.vals[in.flag(name)]) > 0
+}
+
+// value returns a flag's value, or "" when it wasn't given.
+func (in *invocation) value(name string) string {
+	vals := in.vals[in.flag(name)]
+	if len(vals) == 0 {
+		return ""
+	}
+	return vals[len(vals)-1]
+}
+
+// values returns every value of a repeatable flag.
+func (in *invocation) values(name string) []string {
+	return in.vals[in.flag(name)]
+}
+
+// int returns a number flag's value, or 0 when it wasn't given.
+func (in *invocation) int(name string) int {
+	n, _ := strconv.Atoi(in.value(name))
+	return n
+}
+
+// duration returns a duration flag's value, or 0 when it wasn't given.
+func (in *invocation) duration(name string) time.Duration {
+	d, _ := time.ParseDuration(in.value(name))
+	return d
+}
+
+// synopsis is a flag as listed in help, e.g. "-n, --name <name>".
+func (f *flag) synopsis() string {
+	s := "    --" + f.name
+	if f.short != 0 {
+		s = "-" + string(f.short) + ", --" + f.name
+	}
+	if f.kind != boolFlag {
+		s += " " + f.placeholder()
+	}
+	return s
+}
+
+func (f *flag) placeholder() string {
+	switch {
+	case f.value != "":
+		return f.value
+	case f.kind == intFlag:
+		return "<n>"
+	case f.kind == durationFlag:
+		return "<duration>"
+	}
+	return "<" + f.name + ">"
+}
+
+func (f *flag) description() string {
+	d := f.usage
+	if len(f.choices) > 0 {
+		d += " (" + strings.Join(f.choices, ", ") + ")"
+	}
+	if f.kind == stringsFlag {
+		d += ", repeatable"
+	}
+	return d
+}
+
+// writeHelp prints c's usage line, description, subcommands and flags.
+func (c *command) writeHelp(w io.Writer) {
+	path := c.path()
+	var usage []string
+	if len(c.subs) > 0 {
+		usage = append(usage, path+" <command> [flags]")
+	}
+	if c.run != nil && (len(c.subs) == 0 || c.args != "") {
+		line := path + " [flags]"
+		if c.args != "" {
+			line += " " + c.args
+		}
+		usage = append(usage, line)
+	}
+	for i, u := range usage {
+		if i == 0 {
+			fmt.Fprintf(w, "Usage: %s\n", u)
+		} else {
+			fmt.Fprintf(w, "       %s\n", u)
+		}
+	}
+	if c.help != "" {
+		fmt.Fprintf(w, "\n%s\n", strings.TrimSpace(c.help))
+	}
+
+	if len(c.subs) > 0 {
+		var rows [][2]string
+		for _, sub := range c.visibleSubs() {
+			summary := sub.summary
+			if len(sub.aliases) > 0 {
+				summary += " (alias: " + strings.Join(sub.aliases, ", ") + ")"
+			}
+			rows = append(rows, [2]string{sub.name, summary})
+		}
+		fmt.Fprint(w, "\nCommands:\n")
+		writeRows(w, rows)
+	}
+
+	if c.parent != nil && len(c.flags) > 0 {
+		fmt.Fprint(w, "\nFlags:\n")
+		writeRows(w, flagRows(c.flags))
+	}
+	root := c
+	for root.parent != nil {
+		root = root.parent
+	}
+	fmt.Fprint(w, "\nGlobal flags:\n")
+	writeRows(w, flagRows(root.flags))
+
+	if len(c.subs) > 0 {
+		fmt.Fprintf(w, "\nRun '%s <command> --help' for command-specific help.\n", path)
+	}
+}
+
+func flagRows(flags []*flag) [][2]string {
+	rows := make([][2]string, len(flags))
+	for i, f := range flags {
+		rows[i] = [2]string{f.synopsis(), f.description()}
+	}
+	return rows
+}
+
+// writeRows prints two aligned columns.
+func writeRows(w io.Writer, rows [][2]string) {
+	width := 0
+	for _, r := range rows {
+		width = max(width, len(r[0]))
+	}
+	for _, r := range rows {
+		fmt.Fprintf(w, "  %-*s   %s\n", width, r[0], r[1])
+	}
+}
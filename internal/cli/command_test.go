@@ -0,0 +1,315 @@
+package cli
+
+import (
+	"bytes"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func parseArgs(t *testing.T, args ...string) *invocation {
+	t.Helper()
+	in, err := rootCommand("test").parse(args)
+	if err != nil {
+		t.Fatalf("parse(%q): %v", args, err)
+	}
+	return in
+}
+
+func TestParseFlagForms(t *testing.T) {
+	tests := []struct {
+		args []string
+		name string
+		typ  string
+	}{
+		{[]string{"secret", "store", "-t", "password", "-n", "github"}, "github", "password"},
+		{[]string{"secret", "store", "--type", "password", "--name", "github"}, "github", "password"},
+		{[]string{"secret", "store", "--type=password", "--name=github"}, "github", "password"},
+		{[]string{"secret", "store", "-tpassword", "-ngithub"}, "github", "password"},
+		{[]string{"secret", "store", "-t", "note", "-t", "password", "-n", "github"}, "github", "password"},
+	}
+	for _, tt := range tests {
+		t.Run(strings.Join(tt.args, " "), func(t *testing.T) {
+			in := parseArgs(t, tt.args...)
+			if in.cmd.path() != "zvault secret store" {
+				t.Fatalf("command = %q", in.cmd.path())
+			}
+			if got := in.value("name"); got != tt.name {
+				t.Errorf("name = %q, want %q", got, tt.name)
+			}
+			if got := in.value("type"); got != tt.typ {
+				t.Errorf("type = %q, want %q", got, tt.typ)
+			}
+		})
+	}
+}
+
+func TestParseValueLooksLikeFlag(t *testing.T) {
+	// a value is taken as is, even when it spells another flag
+	in := parseArgs(t, "secret", "store", "-n", "--type", "-t", "note")
+	if got := in.value("name"); got != "--type" {
+		t.Errorf("name = %q, want --type", got)
+	}
+	if got := in.value("type"); got != "note" {
+		t.Errorf("type = %q, want note", got)
+	}
+
+	in = parseArgs(t, "task", "add", "-p", "h", "--", "-p", "is", "a", "flag")
+	if got := strings.Join(in.args, " "); got != "-p is a flag" {
+		t.Errorf("args = %q", got)
+	}
+	if got := in.value("priority"); got != "h" {
+		t.Errorf("priority = %q, want h", got)
+	}
+}
+
+func TestParseInterspersed(t *testing.T) {
+	in := parseArgs(t, "task", "add", "buy", "-p", "h", "milk", "--tags", "home")
+	if got := strings.Join(in.args, " "); got != "buy milk" {
+		t.Errorf("args = %q, want %q", got, "buy milk")
+	}
+	if in.value("tags") != "home" {
+		t.Errorf("tags = %q", in.value("tags"))
+	}
+}
+
+func TestParseCombinedShortFlags(t *testing.T) {
+	root := (&command{
+		name:  "zvault",
+		flags: []*flag{{name: "help", kind: boolFlag, short: 'h'}},
+		subs: []*command{{
+			name:    "x",
+			maxArgs: unlimited,
+			flags: []*flag{
+				{name: "all", kind: boolFlag, short: 'a'},
+				{name: "brief", kind: boolFlag, short: 'b'},
+				{name: "count", short: 'c', kind: intFlag},
+			},
+			run: func(*invocation) {},
+		}},
+	}).link()
+
+	for _, args := range [][]string{
+		{"x", "-abc", "3"},
+		{"x", "-abc3"},
+		{"x", "-a", "-b", "-c", "3"},
+	} {
+		in, err := root.parse(args)
+		if err != nil {
+			t.Fatalf("parse(%q): %v", args, err)
+		}
+		if !in.has("all") || !in.has("brief") || in.int("count") != 3 {
+			t.Errorf("parse(%q): all=%v brief=%v count=%d", args, in.has("all"), in.has("brief"), in.int("count"))
+		}
+	}
+}
+
+func TestParseTypedValues(t *testing.T) {
+	in := parseArgs(t, "ssh", "keygen", "-n", "k", "--bits", "4096")
+	if in.int("bits") != 4096 || !in.given("bits") {
+		t.Errorf("bits = %d", in.int("bits"))
+	}
+
+	in = parseArgs(t, "ssh-agent", "--lifetime", "8h", "--confirm=false")
+	if in.duration("lifetime") != 8*time.Hour {
+		t.Errorf("lifetime = %v", in.duration("lifetime"))
+	}
+	if in.has("confirm") || !in.given("confirm") {
+		t.Error("--confirm=false should be given but off")
+	}
+
+	in = parseArgs(t, "k8s", "secret", "app", "--from", "a", "--from=b", "--from", "c")
+	if got := in.values("from"); !slices.Equal(got, []string{"a", "b", "c"}) {
+		t.Errorf("from = %q", got)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"secret", "get", "--bogus", "x"}, "unknown flag --bogus"},
+		{[]string{"secret", "get", "-x", "github"}, "unknown flag -x"},
+		{[]string{"secret", "store", "-n"}, "flag -n needs a value"},
+		{[]string{"secret", "store", "--name"}, "flag --name needs a value"},
+		{[]string{"secret", "store", "-t", "pasword"}, `invalid value "pasword" for --type`},
+		{[]string{"ssh", "keygen", "--bits", "lots"}, `invalid value "lots" for --bits`},
+		{[]string{"ssh-agent", "--lifetime", "soon"}, `invalid value "soon" for --lifetime`},
+		{[]string{"secret", "get", "--show=maybe", "x"}, `invalid value "maybe" for --show`},
+		{[]string{"secret", "get"}, "zvault secret get: <id-or-name> required"},
+		{[]string{"secret", "get", "a", "b"}, `zvault secret get: unexpected argument "b"`},
+		{[]string{"secret", "frobnicate"}, `unknown secret command "frobnicate"`},
+		{[]string{"frobnicate"}, `unknown command "frobnicate"`},
+		{[]string{"secret", "list", "--show"}, "unknown flag --show"},
+	}
+	for _, tt := range tests {
+		t.Run(strings.Join(tt.args, " "), func(t *testing.T) {
+			_, err := rootCommand("test").parse(tt.args)
+			if err == nil {
+				t.Fatal("expected error")
+			}
+			var ue *usageError
+			if !errors.As(err, &ue) {
+				t.Errorf("error %T is not a usageError", err)
+			}
+			if !strings.HasPrefix(err.Error(), tt.want) {
+				t.Errorf("error = %q, want prefix %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseGlobalFlags(t *testing.T) {
+	tests := []struct {
+		args     []string
+		cmd      string
+		wantArgs string
+		json     bool
+	}{
+		{[]string{"secret", "list"}, "zvault secret list", "", false},
+		{[]string{"--json", "secret", "list"}, "zvault secret list", "", true},
+		{[]string{"secret", "list", "--json"}, "zvault secret list", "", true},
+		{[]string{"task", "add", "--", "--json"}, "zvault task add", "--json", false},
+		{[]string{"run", "--", "aws", "--json"}, "zvault run", "aws --json", false},
+		{[]string{"run", "aws", "--json"}, "zvault run", "aws --json", false},
+		{[]string{"--json", "run", "aws"}, "zvault run", "aws", true},
+		{[]string{"run", "--json", "aws"}, "zvault run", "aws", true},
+	}
+	for _, tt := range tests {
+		t.Run(strings.Join(tt.args, " "), func(t *testing.T) {
+			in := parseArgs(t, tt.args...)
+			if in.cmd.path() != tt.cmd {
+				t.Errorf("command = %q, want %q", in.cmd.path(), tt.cmd)
+			}
+			if got := strings.Join(in.args, " "); got != tt.wantArgs {
+				t.Errorf("args = %q, want %q", got, tt.wantArgs)
+			}
+			if in.has("json") != tt.json {
+				t.Errorf("json = %v, want %v", in.has("json"), tt.json)
+			}
+		})
+	}
+}
+
+func TestParsePassthrough(t *testing.T) {
+	tests := []struct {
+		args []string
+		env  []string
+		cmd  []string
+	}{
+		{[]string{"run", "--env", "A=b", "--", "env", "-0"}, []string{"A=b"}, []string{"env", "-0"}},
+		{[]string{"run", "--env-file", "x.env", "make", "--", "deploy"}, nil, []string{"make", "--", "deploy"}},
+		{[]string{"run", "--env=A=b", "ls", "--env", "x"}, []string{"A=b"}, []string{"ls", "--env", "x"}},
+	}
+	for _, tt := range tests {
+		in := parseArgs(t, tt.args...)
+		if !slices.Equal(in.values("env"), tt.env) || !slices.Equal(in.args, tt.cmd) {
+			t.Errorf("parse(%q): env %q, command %q; want %q, %q", tt.args, in.values("env"), in.args, tt.env, tt.cmd)
+		}
+	}
+
+	if _, err := rootCommand("test").parse([]string{"run", "--env", "A=b"}); err == nil {
+		t.Error("expected error for run without a command")
+	}
+}
+
+func TestParseSubcommandFallback(t *testing.T) {
+	in := parseArgs(t, "task", "abc123")
+	if in.cmd.path() != "zvault task" || !slices.Equal(in.args, []string{"abc123"}) {
+		t.Errorf("task <id>: command %q, args %q", in.cmd.path(), in.args)
+	}
+
+	in = parseArgs(t, "secret", "ls")
+	if in.cmd.path() != "zvault secret list" {
+		t.Errorf("alias: command = %q", in.cmd.path())
+	}
+
+	// git ignores helper operations it doesn't know
+	in = parseArgs(t, "git-credential", "capability")
+	if in.cmd.path() != "zvault git-credential" {
+		t.Errorf("git-credential: command = %q", in.cmd.path())
+	}
+
+	for _, args := range [][]string{{"secret", "help"}, {"secret", "--help"}, {"secret", "get", "-h"}} {
+		if in := parseArgs(t, args...); !in.has("help") {
+			t.Errorf("parse(%q): help not set", args)
+		}
+	}
+}
+
+func TestHelpFromDefinitions(t *testing.T) {
+	var buf bytes.Buffer
+	root := rootCommand("test")
+	root.sub("secret").sub("store").writeHelp(&buf)
+	out := buf.String()
+
+	for _, want := range []string{
+		"Usage: zvault secret store [flags]",
+		"-t, --type <type>",
+		"secret type (password, apikey, sshkey, note)",
+		"    --tags <a,b>",
+		"Global flags:",
+		"--json",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("help missing %q:\n%s", want, out)
+		}
+	}
+
+	buf.Reset()
+	root.writeHelp(&buf)
+	root.walk(func(c *command) {
+		if c.parent == root && !strings.Contains(buf.String(), "  "+c.name+" ") {
+			t.Errorf("top-level help missing %q", c.name)
+		}
+	})
+}
+
+func TestDefinitionsConsistent(t *testing.T) {
+	rootCommand("test").walk(func(c *command) {
+		if c.parent != nil && c.summary == "" {
+			t.Errorf("%s: no summary", c.path())
+		}
+		if c.run == nil && len(c.subs) == 0 {
+			t.Errorf("%s: nothing to run", c.path())
+		}
+		if c.minArgs > 0 && c.args == "" {
+			t.Errorf("%s: takes arguments but has no args synopsis", c.path())
+		}
+		seen := make(map[string]bool)
+		for _, f := range c.allFlags() {
+			for _, n := range f.flagNames() {
+				if seen[n] {
+					t.Errorf("%s: flag %s defined twice", c.path(), n)
+				}
+				seen[n] = true
+			}
+		}
+	})
+}
+
+func TestHiddenCommands(t *testing.T) {
+	root := rootCommand("test")
+	if root.sub("__clipboard-clear") == nil {
+		t.Fatal("hidden command not found")
+	}
+	in := parseArgs(t, "__clipboard-clear", "--after", "20s")
+	if in.duration("after") != 20*time.Second {
+		t.Errorf("after = %v", in.duration("after"))
+	}
+
+	var help bytes.Buffer
+	root.writeHelp(&help)
+	out := []string{help.String()}
+	for _, shell := range []func(*command) string{bashCompletion, zshCompletion, fishCompletion} {
+		out = append(out, shell(root))
+	}
+	for _, s := range out {
+		if strings.Contains(s, "__clipboard-clear") {
+			t.Errorf("hidden command listed:\n%s", s)
+		}
+	}
+}
@@ -3,234 +3,732 @@ package cli
 import (
 	"fmt"
 	"os"
+	"slices"
+	"strings"
+
+	"github.com/zarlcorp/zvault/internal/vault"
 )
 
-func runCompletion(args []string) {
-	if len(args) == 0 {
-		errf("shell type required (bash, zsh, fish)")
-		os.Exit(1)
+func completionCommand() *command {
+	return &command{
+		name:       "completion",
+		summary:    "generate shell completions",
+		args:       "<shell>",
+		minArgs:    1,
+		maxArgs:    1,
+		argChoices: []string{"bash", "zsh", "fish"},
+		help: `
+Print a completion script for bash, zsh or fish. Scripts are generated
+from the same definitions as --help, so they always match this binary.
+
+  eval "$(zvault completion bash)"
+  eval "$(zvault completion zsh)"
+  zvault completion fish | source
+
+Secret names, task ids and tags complete too once 'zvault completion
+names' has written the name index they come from.`,
+		run: runCompletion,
+		subs: []*command{
+			{
+				name:    "names",
+				summary: "complete secret names, task ids and tags",
+				help: `
+Write names.json next to the vault: a plain-text index of secret names,
+types and tags and of task titles, which completion reads without the
+password. It holds no values and only you can read it, but the names
+themselves are no longer encrypted. Once written, zvault keeps it up to
+date; --off deletes it.`,
+				flags: []*flag{
+					{name: "off", kind: boolFlag, usage: "delete the index and stop keeping it"},
+				},
+				run: runCompletionNames,
+			},
+		},
+	}
+}
+
+func runCompletionNames(in *invocation) {
+	if in.has("off") {
+		if err := vault.RemoveNames(vault.DefaultDir()); err != nil {
+			errf("remove name index: %v", err)
+			exit(1)
+		}
+		fmt.Fprintln(os.Stderr, muted("name index removed"))
+		return
 	}
 
-	switch args[0] {
+	v := openVault()
+	defer closeVault(v)
+	if err := v.IndexNames(); err != nil {
+		errf("%v", err)
+		exit(1)
+	}
+	fmt.Fprintln(os.Stderr, muted("names indexed for completion; zvault completion names --off removes them"))
+}
+
+func runCompletion(in *invocation) {
+	root := in.cmd.parent
+	switch in.args[0] {
 	case "bash":
-		fmt.Print(bashCompletion)
+		fmt.Print(bashCompletion(root))
 	case "zsh":
-		fmt.Print(zshCompletion)
+		fmt.Print(zshCompletion(root))
 	case "fish":
-		fmt.Print(fishCompletion)
+		fmt.Print(fishCompletion(root))
 	default:
-		errf("unsupported shell %q (use bash, zsh, or fish)", args[0])
-		os.Exit(1)
+		errfCode(codeUsage, "unsupported shell %q (use bash, zsh, or fish)", in.args[0])
+		exit(1)
 	}
 }
 
-const bashCompletion = `# zvault bash completion
-_zvault() {
-    local cur prev words cword
-    _init_completion || return
-
-    local commands="secret task export completion version help"
-    local secret_cmds="store get list delete search"
-    local task_cmds="add list ls done edit rm clear"
-    local secret_types="password apikey sshkey note"
-    local shells="bash zsh fish"
-    local priorities="h m l"
-
-    case "${cword}" in
-        1)
-            COMPREPLY=($(compgen -W "${commands}" -- "${cur}"))
-            return
-            ;;
-        2)
-            case "${prev}" in
-                secret)
-                    COMPREPLY=($(compgen -W "${secret_cmds}" -- "${cur}"))
-                    return
-                    ;;
-                task)
-                    COMPREPLY=($(compgen -W "${task_cmds}" -- "${cur}"))
-                    return
-                    ;;
-                completion)
-                    COMPREPLY=($(compgen -W "${shells}" -- "${cur}"))
-                    return
-                    ;;
-            esac
-            ;;
-        *)
-            case "${words[1]}" in
-                secret)
-                    case "${prev}" in
-                        -t) COMPREPLY=($(compgen -W "${secret_types}" -- "${cur}")) ;;
-                    esac
-                    ;;
-                task)
-                    case "${prev}" in
-                        -p) COMPREPLY=($(compgen -W "${priorities}" -- "${cur}")) ;;
-                    esac
-                    ;;
-            esac
-            ;;
-    esac
+// walk calls fn for c and every visible command below it, parents first.
+func (c *command) walk(fn func(*command)) {
+	fn(c)
+	for _, sub := range c.visibleSubs() {
+		sub.walk(fn)
+	}
 }
 
-complete -F _zvault zvault
-`
+// names returns the name and aliases of c.
+func (c *command) names() []string {
+	return append([]string{c.name}, c.aliases...)
+}
+
+// flagNames returns the spellings of f, e.g. "-n" and "--name".
+func (f *flag) flagNames() []string {
+	if f.short != 0 {
+		return []string{"-" + string(f.short), "--" + f.name}
+	}
+	return []string{"--" + f.name}
+}
+
+// words are the values a flag's argument completes to.
+func (f *flag) words() []string {
+	if len(f.choices) > 0 {
+		return f.choices
+	}
+	return f.suggest
+}
+
+// casePattern is a shell case pattern matching "<path>:<word>" for each
+// word.
+func casePattern(path string, words []string) string {
+	parts := make([]string, len(words))
+	for i, w := range words {
+		parts[i] = fmt.Sprintf("%q", path+":"+w)
+	}
+	return strings.Join(parts, "|")
+}
+
+// bashCompletion walks the words before the cursor to find the command
+// being completed, skipping flag values, then completes a flag value,
+// a flag or an argument of that command.
+func bashCompletion(root *command) string {
+	var b strings.Builder
+	b.WriteString(`# zvault bash completion
 
-const zshCompletion = `#compdef zvault
+# _zvault_names adds names from the vault, which zvault reads from its
+# name index without the password.
+_zvault_names() {
+    local IFS=$'\n' c
+    for c in $(zvault __complete -- "${words[@]:1:cword}" 2>/dev/null); do
+        COMPREPLY+=("$(printf '%q' "${c%%$'\t'*}")")
+    done
+}
 
 _zvault() {
-    local -a commands secret_cmds task_cmds
-
-    commands=(
-        'secret:manage secrets'
-        'task:manage tasks'
-        'export:export vault data'
-        'completion:generate shell completions'
-        'version:print version'
-        'help:show help'
-    )
-
-    secret_cmds=(
-        'store:create a new secret'
-        'get:retrieve a secret'
-        'list:list secrets'
-        'delete:delete a secret'
-        'search:search secrets'
-    )
-
-    task_cmds=(
-        'add:add a new task'
-        'list:list tasks'
-        'ls:list tasks'
-        'done:mark task(s) complete'
-        'edit:rename a task'
-        'rm:delete task(s)'
-        'clear:delete all completed tasks'
-    )
-
-    if (( CURRENT == 2 )); then
-        _describe 'command' commands
+    local cur prev words cword split
+    _init_completion -s || return
+
+    local cmd="zvault" skip= args= dashdash= i w
+    for ((i = 1; i < cword; i++)); do
+        w=${words[i]}
+        if [[ -n $skip ]]; then
+            skip=
+            continue
+        fi
+        if [[ $w == -- ]]; then
+            case "$cmd" in
+`)
+	root.walk(func(c *command) {
+		if c.passthrough {
+			fmt.Fprintf(&b, "                %q) _command_offset $((i + 1)); return ;;\n", c.path())
+		}
+	})
+	b.WriteString(`            esac
+            args=1 dashdash=1
+            break
+        fi
+        if [[ $w == -* ]]; then
+            case "$cmd:$w" in
+`)
+	root.walk(func(c *command) {
+		var names []string
+		for _, f := range c.allFlags() {
+			if f.kind != boolFlag {
+				names = append(names, f.flagNames()...)
+			}
+		}
+		if len(names) > 0 {
+			fmt.Fprintf(&b, "                %s) skip=1 ;;\n", casePattern(c.path(), names))
+		}
+	})
+	b.WriteString(`            esac
+            continue
+        fi
+        [[ -n $args ]] && continue
+        case "$cmd:$w" in
+`)
+	root.walk(func(c *command) {
+		for _, sub := range c.visibleSubs() {
+			fmt.Fprintf(&b, "            %s) cmd=%q ;;\n", casePattern(c.path(), sub.names()), sub.path())
+		}
+	})
+	b.WriteString(`            *)
+                case "$cmd" in
+`)
+	root.walk(func(c *command) {
+		if c.passthrough {
+			fmt.Fprintf(&b, "                    %q) _command_offset $i; return ;;\n", c.path())
+		}
+	})
+	b.WriteString(`                esac
+                args=1
+                ;;
+        esac
+    done
+
+    if [[ -n $skip || $split == true ]]; then
+        case "$cmd:$prev" in
+`)
+	root.walk(func(c *command) {
+		for _, f := range c.flags {
+			switch {
+			case len(f.words()) > 0:
+				fmt.Fprintf(&b, "            %s) COMPREPLY=($(compgen -W %q -- \"$cur\")) ;;\n",
+					casePattern(c.path(), f.flagNames()), strings.Join(f.words(), " "))
+			case f.names != noNames:
+				fmt.Fprintf(&b, "            %s) _zvault_names ;;\n", casePattern(c.path(), f.flagNames()))
+			case f.files:
+				fmt.Fprintf(&b, "            %s) _filedir ;;\n", casePattern(c.path(), f.flagNames()))
+			}
+		}
+	})
+	b.WriteString(`        esac
+        return
+    fi
+
+    if [[ $cur == -* && -z $dashdash ]]; then
+        case "$cmd" in
+`)
+	root.walk(func(c *command) {
+		var names []string
+		for _, f := range c.allFlags() {
+			names = append(names, f.flagNames()...)
+		}
+		fmt.Fprintf(&b, "            %q) COMPREPLY=($(compgen -W %q -- \"$cur\")) ;;\n", c.path(), strings.Join(names, " "))
+	})
+	b.WriteString(`        esac
         return
     fi
 
-    case "${words[2]}" in
-        secret)
-            if (( CURRENT == 3 )); then
-                _describe 'secret command' secret_cmds
-                return
-            fi
-            case "${words[3]}" in
-                store)
-                    _arguments \
-                        '-t[secret type]:type:(password apikey sshkey note)' \
-                        '-n[secret name]:name:' \
-                        '--tags[tags]:tags:'
-                    ;;
-                get)
-                    _arguments '--show[reveal sensitive values]'
-                    ;;
-                list)
-                    _arguments \
-                        '-t[filter by type]:type:(password apikey sshkey note)' \
-                        '--tag[filter by tag]:tag:'
-                    ;;
-            esac
-            ;;
-        task)
-            if (( CURRENT == 3 )); then
-                _describe 'task command' task_cmds
-                return
-            fi
-            case "${words[3]}" in
-                add)
-                    _arguments \
-                        '-p[priority]:priority:(h m l)' \
-                        '-d[due date]:date:' \
-                        '--tags[tags]:tags:'
-                    ;;
-                list)
-                    _arguments \
-                        '--pending[show only pending]' \
-                        '--done[show only completed]' \
-                        '-p[filter by priority]:priority:(h m l)' \
-                        '--tag[filter by tag]:tag:'
-                    ;;
-            esac
-            ;;
-        completion)
-            if (( CURRENT == 3 )); then
-                _values 'shell' bash zsh fish
-            fi
-            ;;
-        export)
-            _arguments \
-                '--tasks[export tasks]' \
-                '--secrets[export secrets]' \
-                '--pending[pending tasks only]' \
-                '--done[completed tasks only]'
-            ;;
-    esac
-}
-
-_zvault "$@"
-`
-
-const fishCompletion = `# zvault fish completion
-
-# top-level commands
-complete -c zvault -n '__fish_use_subcommand' -a 'secret' -d 'manage secrets'
-complete -c zvault -n '__fish_use_subcommand' -a 'task' -d 'manage tasks'
-complete -c zvault -n '__fish_use_subcommand' -a 'export' -d 'export vault data'
-complete -c zvault -n '__fish_use_subcommand' -a 'completion' -d 'generate shell completions'
-complete -c zvault -n '__fish_use_subcommand' -a 'version' -d 'print version'
-complete -c zvault -n '__fish_use_subcommand' -a 'help' -d 'show help'
-
-# secret subcommands
-complete -c zvault -n '__fish_seen_subcommand_from secret; and not __fish_seen_subcommand_from store get list delete search' -a 'store' -d 'create a new secret'
-complete -c zvault -n '__fish_seen_subcommand_from secret; and not __fish_seen_subcommand_from store get list delete search' -a 'get' -d 'retrieve a secret'
-complete -c zvault -n '__fish_seen_subcommand_from secret; and not __fish_seen_subcommand_from store get list delete search' -a 'list' -d 'list secrets'
-complete -c zvault -n '__fish_seen_subcommand_from secret; and not __fish_seen_subcommand_from store get list delete search' -a 'delete' -d 'delete a secret'
-complete -c zvault -n '__fish_seen_subcommand_from secret; and not __fish_seen_subcommand_from store get list delete search' -a 'search' -d 'search secrets'
-
-# secret store flags
-complete -c zvault -n '__fish_seen_subcommand_from store' -s t -d 'secret type' -xa 'password apikey sshkey note'
-complete -c zvault -n '__fish_seen_subcommand_from store' -s n -d 'secret name'
-complete -c zvault -n '__fish_seen_subcommand_from store' -l tags -d 'comma-separated tags'
-
-# secret get flags
-complete -c zvault -n '__fish_seen_subcommand_from get' -l show -d 'reveal sensitive values'
-
-# secret list flags
-complete -c zvault -n '__fish_seen_subcommand_from secret; and __fish_seen_subcommand_from list' -s t -d 'filter by type' -xa 'password apikey sshkey note'
-complete -c zvault -n '__fish_seen_subcommand_from secret; and __fish_seen_subcommand_from list' -l tag -d 'filter by tag'
-
-# task subcommands
-complete -c zvault -n '__fish_seen_subcommand_from task; and not __fish_seen_subcommand_from add list ls done edit rm clear' -a 'add' -d 'add a new task'
-complete -c zvault -n '__fish_seen_subcommand_from task; and not __fish_seen_subcommand_from add list ls done edit rm clear' -a 'list' -d 'list tasks'
-complete -c zvault -n '__fish_seen_subcommand_from task; and not __fish_seen_subcommand_from add list ls done edit rm clear' -a 'ls' -d 'list tasks'
-complete -c zvault -n '__fish_seen_subcommand_from task; and not __fish_seen_subcommand_from add list ls done edit rm clear' -a 'done' -d 'mark task(s) complete'
-complete -c zvault -n '__fish_seen_subcommand_from task; and not __fish_seen_subcommand_from add list ls done edit rm clear' -a 'edit' -d 'rename a task'
-complete -c zvault -n '__fish_seen_subcommand_from task; and not __fish_seen_subcommand_from add list ls done edit rm clear' -a 'rm' -d 'delete task(s)'
-complete -c zvault -n '__fish_seen_subcommand_from task; and not __fish_seen_subcommand_from add list ls done edit rm clear' -a 'clear' -d 'delete all completed tasks'
-
-# task add flags
-complete -c zvault -n '__fish_seen_subcommand_from add' -s p -d 'priority' -xa 'h m l'
-complete -c zvault -n '__fish_seen_subcommand_from add' -s d -d 'due date'
-complete -c zvault -n '__fish_seen_subcommand_from add' -l tags -d 'comma-separated tags'
-
-# task list flags
-complete -c zvault -n '__fish_seen_subcommand_from task; and __fish_seen_subcommand_from list' -l pending -d 'show only pending'
-complete -c zvault -n '__fish_seen_subcommand_from task; and __fish_seen_subcommand_from list' -l done -d 'show only completed'
-complete -c zvault -n '__fish_seen_subcommand_from task; and __fish_seen_subcommand_from list' -s p -d 'filter by priority' -xa 'h m l'
-complete -c zvault -n '__fish_seen_subcommand_from task; and __fish_seen_subcommand_from list' -l tag -d 'filter by tag'
-
-# completion subcommands
-complete -c zvault -n '__fish_seen_subcommand_from completion' -a 'bash zsh fish' -d 'shell type'
-
-# export flags
-complete -c zvault -n '__fish_seen_subcommand_from export' -l tasks -d 'export tasks'
-complete -c zvault -n '__fish_seen_subcommand_from export' -l secrets -d 'export secrets'
-complete -c zvault -n '__fish_seen_subcommand_from export' -l pending -d 'pending tasks only'
-complete -c zvault -n '__fish_seen_subcommand_from export' -l done -d 'completed tasks only'
-`
+    case "$cmd" in
+`)
+	root.walk(func(c *command) {
+		switch {
+		case c.passthrough:
+			fmt.Fprintf(&b, "        %q) _command_offset $cword ;;\n", c.path())
+		case len(c.subs) > 0:
+			var names []string
+			for _, sub := range c.visibleSubs() {
+				names = append(names, sub.names()...)
+			}
+			if c.argNames != noNames {
+				fmt.Fprintf(&b, "        %q) [[ -z $args ]] && COMPREPLY=($(compgen -W %q -- \"$cur\")) && _zvault_names ;;\n", c.path(), strings.Join(names, " "))
+			} else {
+				fmt.Fprintf(&b, "        %q) [[ -z $args ]] && COMPREPLY=($(compgen -W %q -- \"$cur\")) ;;\n", c.path(), strings.Join(names, " "))
+			}
+		case c.argNames != noNames:
+			fmt.Fprintf(&b, "        %q) [[ -z $args ]] && _zvault_names ;;\n", c.path())
+		case len(c.argChoices) > 0:
+			fmt.Fprintf(&b, "        %q) [[ -z $args ]] && COMPREPLY=($(compgen -W %q -- \"$cur\")) ;;\n", c.path(), strings.Join(c.argChoices, " "))
+		case c.argFiles:
+			fmt.Fprintf(&b, "        %q) _filedir ;;\n", c.path())
+		}
+	})
+	b.WriteString(`    esac
+}
+
+complete -F _zvault zvault
+`)
+	return b.String()
+}
+
+// zshFunc names the completion function of c, e.g. _zvault_secret_store.
+func zshFunc(c *command) string {
+	return "_" + strings.NewReplacer(" ", "_", "-", "_").Replace(c.path())
+}
+
+// zshQuote escapes s for use inside a single-quoted _arguments spec.
+func zshQuote(s string) string {
+	return strings.NewReplacer("'", `'\''`, "[", `\[`, "]", `\]`).Replace(s)
+}
+
+// zshFlagSpec is an _arguments spec for f, e.g.
+// '(-n --name)'{-n,--name}'[secret name]:name: '.
+func zshFlagSpec(f *flag) string {
+	var spec string
+	switch {
+	case f.short != 0 && f.kind == stringsFlag:
+		spec = fmt.Sprintf("'*'{-%c,--%s}'", f.short, f.name)
+	case f.short != 0:
+		spec = fmt.Sprintf("'(-%c --%s)'{-%c,--%s}'", f.short, f.name, f.short, f.name)
+	case f.kind == stringsFlag:
+		spec = "'*--" + f.name
+	default:
+		spec = "'--" + f.name
+	}
+	spec += "[" + zshQuote(f.usage) + "]"
+
+	if f.kind != boolFlag {
+		msg := strings.NewReplacer("<", "", ">", "", ":", "").Replace(f.placeholder())
+		action := " "
+		switch {
+		case len(f.words()) > 0:
+			action = "(" + strings.Join(f.words(), " ") + ")"
+		case f.names != noNames:
+			action = "__zvault_names"
+		case f.files:
+			action = "_files"
+		}
+		spec += ":" + zshQuote(msg) + ":" + action
+	}
+	return spec + "'"
+}
+
+// zshCompletion defines a function per command. Each one completes its
+// flags with _arguments, then hands the words after a subcommand to that
+// subcommand's function.
+func zshCompletion(root *command) string {
+	var b strings.Builder
+	b.WriteString(`#compdef zvault
+
+# __zvault_names offers names from the vault, which zvault reads from its
+# name index without the password.
+__zvault_names() {
+    local -a line candidates
+    local c
+    line=(${(Q)${(z)LBUFFER}})
+    [[ $LBUFFER == *[[:space:]] ]] && line+=('')
+    for c in ${(f)"$(zvault __complete -- "${(@)line[2,-1]}" 2>/dev/null)"}; do
+        if [[ $c == *$'\t'* ]]; then
+            candidates+=("${${c%%$'\t'*}//:/\\:}:${c#*$'\t'}")
+        else
+            candidates+=("${c//:/\\:}")
+        fi
+    done
+    _describe -t names 'name' candidates
+}
+`)
+
+	root.walk(func(c *command) {
+		fmt.Fprintf(&b, "\n%s() {\n", zshFunc(c))
+
+		var specs []string
+		for _, f := range c.allFlags() {
+			specs = append(specs, zshFlagSpec(f))
+		}
+
+		if len(c.subs) > 0 {
+			b.WriteString("    local curcontext=\"$curcontext\" state line\n")
+			b.WriteString("    local -a commands\n    commands=(\n")
+			for _, sub := range c.visibleSubs() {
+				for _, name := range sub.names() {
+					fmt.Fprintf(&b, "        '%s:%s'\n", name, zshQuote(sub.summary))
+				}
+			}
+			b.WriteString("    )\n")
+			specs = append(specs, "'1: :->command'", "'*:: :->args'")
+		} else {
+			switch {
+			case c.passthrough:
+				specs = append(specs, "'*:: :_normal'")
+			case c.argNames != noNames:
+				specs = append(specs, "'1: :__zvault_names'")
+			case len(c.argChoices) > 0:
+				specs = append(specs, "'1: :("+strings.Join(c.argChoices, " ")+")'")
+			case c.argFiles:
+				specs = append(specs, "'*: :_files'")
+			}
+		}
+
+		flags := "-s"
+		if len(c.subs) > 0 {
+			flags = "-C -s"
+		}
+		fmt.Fprintf(&b, "    _arguments %s \\\n        %s\n", flags, strings.Join(specs, " \\\n        "))
+
+		if len(c.subs) > 0 {
+			b.WriteString("\n    case $state in\n")
+			b.WriteString("        command)\n            _describe -t commands 'command' commands\n")
+			if c.argNames != noNames {
+				b.WriteString("            __zvault_names\n")
+			}
+			b.WriteString("            ;;\n")
+			b.WriteString("        args)\n            case $words[1] in\n")
+			for _, sub := range c.visibleSubs() {
+				fmt.Fprintf(&b, "                %s) %s ;;\n", strings.Join(sub.names(), "|"), zshFunc(sub))
+			}
+			b.WriteString("            esac\n            ;;\n    esac\n")
+		}
+		b.WriteString("}\n")
+	})
+
+	b.WriteString(`
+if [[ $zsh_eval_context[-1] == loadautofunc ]]; then
+    _zvault "$@"
+else
+    compdef _zvault zvault
+fi
+`)
+	return b.String()
+}
+
+// fishQuote single-quotes s for fish.
+func fishQuote(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, "'", `\'`).Replace(s) + "'"
+}
+
+// fishCompletion defines __zvault_cmd, which finds the command being
+// completed the same way the bash script does, and a complete line per
+// subcommand and flag, conditioned on it.
+func fishCompletion(root *command) string {
+	var b strings.Builder
+	b.WriteString(`# zvault fish completion
+
+# __zvault_cmd prints the command being completed, like "zvault secret
+# store". For commands that run another command line, it prints the path
+# followed by "--" and keeps that command line in __zvault_rest.
+function __zvault_cmd
+    set -l tokens (commandline -opc)
+    set -e tokens[1]
+    set -l cmd zvault
+    set -l skip
+    set -l args
+    set -l rest
+    set -g __zvault_rest
+    for w in $tokens
+        if set -q rest[1]
+            set -a __zvault_rest $w
+            continue
+        end
+        if set -q skip[1]
+            set -e skip
+            continue
+        end
+        if test "$w" = --
+            switch $cmd
+`)
+	root.walk(func(c *command) {
+		if c.passthrough {
+			fmt.Fprintf(&b, "                case %s\n                    set rest 1\n                    continue\n", fishQuote(c.path()))
+		}
+	})
+	b.WriteString(`            end
+            break
+        end
+        switch "$cmd:$w"
+`)
+	root.walk(func(c *command) {
+		var pats []string
+		for _, f := range c.allFlags() {
+			if f.kind != boolFlag {
+				for _, n := range f.flagNames() {
+					pats = append(pats, fishQuote(c.path()+":"+n))
+				}
+			}
+		}
+		if len(pats) > 0 {
+			fmt.Fprintf(&b, "            case %s\n                set skip 1\n                continue\n", strings.Join(pats, " "))
+		}
+	})
+	b.WriteString(`        end
+        string match -q -- '-*' $w; and continue
+        set -q args[1]; and continue
+        switch "$cmd:$w"
+`)
+	root.walk(func(c *command) {
+		for _, sub := range c.visibleSubs() {
+			var pats []string
+			for _, n := range sub.names() {
+				pats = append(pats, fishQuote(c.path()+":"+n))
+			}
+			fmt.Fprintf(&b, "            case %s\n                set cmd %s\n", strings.Join(pats, " "), fishQuote(sub.path()))
+		}
+	})
+	b.WriteString(`            case '*'
+                switch $cmd
+`)
+	root.walk(func(c *command) {
+		if c.passthrough {
+			fmt.Fprintf(&b, "                    case %s\n                        set rest 1\n                        set -a __zvault_rest $w\n                        continue\n", fishQuote(c.path()))
+		}
+	})
+	b.WriteString(`                end
+                set args 1
+        end
+    end
+    if set -q rest[1]
+        echo "$cmd --"
+    else
+        echo $cmd
+    end
+end
+
+function __zvault_using
+    set -l cmd (__zvault_cmd)
+    test "$cmd" = "$argv[1]"
+end
+
+# names from the vault, which zvault reads from its name index without
+# the password
+function __zvault_names
+    zvault __complete -- (commandline -opc)[2..-1] (commandline -ct) 2>/dev/null
+end
+
+# completes the command line run by zvault run
+function __zvault_complete_rest
+    complete -C (string join ' ' -- (string escape -- $__zvault_rest) (commandline -ct))
+end
+
+complete -c zvault -f
+`)
+
+	root.walk(func(c *command) {
+		cond := "-n " + fishQuote("__zvault_using "+fishQuote(c.path()))
+		fmt.Fprintf(&b, "\n# %s\n", c.path())
+		for _, sub := range c.visibleSubs() {
+			for _, name := range sub.names() {
+				fmt.Fprintf(&b, "complete -c zvault %s -a %s -d %s\n", cond, name, fishQuote(sub.summary))
+			}
+		}
+		for _, f := range c.flags {
+			line := "complete -c zvault"
+			if c.parent != nil {
+				line += " " + cond
+			}
+			if f.short != 0 {
+				line += fmt.Sprintf(" -s %c", f.short)
+			}
+			line += " -l " + f.name + " -d " + fishQuote(f.usage)
+			switch {
+			case len(f.words()) > 0:
+				line += " -xa " + fishQuote(strings.Join(f.words(), " "))
+			case f.names != noNames:
+				line += " -xa '(__zvault_names)'"
+			case f.files:
+				line += " -rF"
+			case f.kind != boolFlag:
+				line += " -x"
+			}
+			fmt.Fprintln(&b, line)
+		}
+		switch {
+		case c.passthrough:
+			fmt.Fprintf(&b, "complete -c zvault %s -xa '(__zvault_complete_rest)'\n", cond)
+			fmt.Fprintf(&b, "complete -c zvault -n %s -xa '(__zvault_complete_rest)'\n", fishQuote("__zvault_using "+fishQuote(c.path()+" --")))
+		case c.argNames != noNames:
+			fmt.Fprintf(&b, "complete -c zvault %s -a '(__zvault_names)'\n", cond)
+		case len(c.argChoices) > 0:
+			fmt.Fprintf(&b, "complete -c zvault %s -xa %s\n", cond, fishQuote(strings.Join(c.argChoices, " ")))
+		case c.argFiles:
+			fmt.Fprintf(&b, "complete -c zvault %s -F\n", cond)
+		}
+	})
+	return b.String()
+}
+
+// completeCommand is the hidden command the completion scripts call for
+// names from the vault. Its arguments are the words after "zvault", the
+// last being the one under the cursor; it prints a candidate per line,
+// with a tab and a description when there is one.
+func completeCommand() *command {
+	return &command{
+		name:        "__complete",
+		summary:     "print completions for names in the vault",
+		hidden:      true,
+		maxArgs:     unlimited,
+		passthrough: true,
+		run:         runComplete,
+	}
+}
+
+// runComplete reads only the vault's name index: completion must never
+// prompt for the password, so with no index it prints nothing.
+func runComplete(in *invocation) {
+	kind, prefix := completeWords(in.cmd.parent, in.args)
+	if kind == noNames {
+		return
+	}
+	names, err := vault.ReadNames(vault.DefaultDir())
+	if err != nil {
+		return
+	}
+	for _, c := range nameCompletions(names, kind, prefix) {
+		fmt.Println(c)
+	}
+}
+
+// completeWords finds which names the last of words completes to, and
+// the prefix typed so far.
+func completeWords(root *command, words []string) (nameKind, string) {
+	at, ok := completionAt(root, words)
+	if !ok {
+		return noNames, ""
+	}
+	switch {
+	case at.flag != nil:
+		return at.flag.names, at.word
+	case at.flagName:
+		return noNames, ""
+	case at.positional == 0:
+		return at.cmd.argNames, at.word
+	}
+	return noNames, ""
+}
+
+// completion is where the word under the cursor sits in a command line.
+type completion struct {
+	cmd        *command
+	flag       *flag // the word is a value of this flag
+	flagName   bool  // the word is a flag
+	positional int   // positional arguments before the word
+	word       string
+}
+
+// completionAt walks the words before the last like parse does, to find
+// what the last one is. It reports false inside a command line run by
+// another command (zvault run), which zvault can't complete.
+func completionAt(root *command, words []string) (completion, bool) {
+	at := completion{cmd: root}
+	if len(words) == 0 {
+		return at, true
+	}
+	cur := words[len(words)-1]
+
+	var value *flag // flag whose value is the next word
+	dashdash := false
+	for _, w := range words[:len(words)-1] {
+		switch {
+		case value != nil:
+			value = nil
+		case dashdash:
+			at.positional++
+		case w == "--":
+			dashdash = true
+		case strings.HasPrefix(w, "--"):
+			name, _, hasVal := strings.Cut(w[2:], "=")
+			if f := at.cmd.longFlag(name); f != nil && f.kind != boolFlag && !hasVal {
+				value = f
+			}
+		case len(w) > 1 && w[0] == '-':
+			for j := 1; j < len(w); j++ {
+				f := at.cmd.shortFlag(w[j])
+				if f == nil || f.kind == boolFlag {
+					continue
+				}
+				if j == len(w)-1 {
+					value = f
+				}
+				break
+			}
+		default:
+			if at.positional == 0 {
+				if sub := at.cmd.sub(w); sub != nil {
+					at.cmd = sub
+					continue
+				}
+			}
+			if at.cmd.passthrough {
+				return at, false
+			}
+			at.positional++
+		}
+	}
+
+	at.word = cur
+	switch {
+	case value != nil:
+		at.flag = value
+	case !dashdash && strings.HasPrefix(cur, "--") && strings.Contains(cur, "="):
+		name, val, _ := strings.Cut(cur[2:], "=")
+		at.flag = at.cmd.longFlag(name)
+		at.flagName = at.flag == nil
+		at.word = val
+	case !dashdash && strings.HasPrefix(cur, "-"):
+		at.flagName = true
+	}
+	return at, true
+}
+
+// nameCompletions lists the names of kind starting with prefix. Task ids
+// and tags may be comma-separated lists, so only the last item of one is
+// completed.
+func nameCompletions(names vault.Names, kind nameKind, prefix string) []string {
+	var lead string
+	if kind != secretNames {
+		if i := strings.LastIndex(prefix, ","); i >= 0 {
+			lead, prefix = prefix[:i+1], prefix[i+1:]
+		}
+	}
+
+	listed := strings.Split(lead, ",")
+	var out []string
+	add := func(word, desc string) {
+		if !strings.HasPrefix(word, prefix) || slices.Contains(listed, word) {
+			return
+		}
+		word = lead + word
+		if desc != "" {
+			word += "\t" + strings.Join(strings.Fields(desc), " ")
+		}
+		out = append(out, word)
+	}
+
+	switch kind {
+	case secretNames:
+		for _, s := range names.Secrets {
+			add(s.Name, string(s.Type))
+		}
+		// ids only once one is being typed, as they'd swamp the names
+		if prefix != "" {
+			for _, s := range names.Secrets {
+				add(s.ID, s.Name)
+			}
+		}
+	case taskIDs:
+		for _, t := range names.Tasks {
+			if t.Done {
+				add(t.ID, t.Title+" (done)")
+			} else {
+				add(t.ID, t.Title)
+			}
+		}
+	case secretTags, taskTags:
+		var tags []string
+		if kind == secretTags {
+			for _, s := range names.Secrets {
+				tags = append(tags, s.Tags...)
+			}
+		} else {
+			for _, t := range names.Tasks {
+				tags = append(tags, t.Tags...)
+			}
+		}
+		slices.Sort(tags)
+		for _, tag := range slices.Compact(tags) {
+			add(tag, "")
+		}
+	}
+	return out
+}
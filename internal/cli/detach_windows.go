@@ -0,0 +1,12 @@
+package cli
+
+import "syscall"
+
+// detachedProcess is DETACHED_PROCESS: no console is inherited.
+const detachedProcess = 0x00000008
+
+// detached starts a process without zvault's console, so it outlives
+// zvault and the terminal.
+func detached() *syscall.SysProcAttr {
+	return &syscall.SysProcAttr{CreationFlags: detachedProcess | syscall.CREATE_NEW_PROCESS_GROUP}
+}
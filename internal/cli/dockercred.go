@@ -0,0 +1,225 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/zarlcorp/zvault/internal/secret"
+)
+
+// dockerTag marks registry logins managed by the docker credential helper.
+const dockerTag = "docker"
+
+// errDockerNotFound is the exact message docker expects for a missing login.
+const errDockerNotFound = "credentials not found in native keychain"
+
+// dockerCredential is the JSON document of docker's credential protocol.
+type dockerCredential struct {
+	ServerURL string `json:"ServerURL"`
+	Username  string `json:"Username"`
+	Secret    string `json:"Secret"`
+}
+
+func dockerCredentialCommand() *command {
+	return &command{
+		name:    "docker-credential",
+		summary: "docker credential helper (get, store, erase, list)",
+		help: `
+Docker credential helper. Registry logins are kept as password secrets
+tagged "docker", with the server URL in the url field.
+
+Setup: put a docker-credential-zvault symlink to zvault on PATH, then
+set in ~/.docker/config.json:
+
+  { "credsStore": "zvault" }
+
+The vault password is read from the sources listed by 'zvault --help',
+or prompted on the terminal.`,
+		subs: []*command{
+			{name: "get", summary: "print the login for a registry", run: runDockerCredentialGet},
+			{name: "store", summary: "save a registry login", run: runDockerCredentialStore},
+			{name: "erase", summary: "delete a registry login", run: runDockerCredentialErase},
+			{name: "list", summary: "list registry logins", run: runDockerCredentialList},
+			{
+				name:    "version",
+				summary: "print the helper name",
+				run: func(*invocation) {
+					fmt.Println("docker-credential-zvault")
+				},
+			},
+		},
+	}
+}
+
+// normalizeServerURL reduces a registry address to host[:port][/path] so
+// "https://ghcr.io/", "ghcr.io" and "ghcr.io/" are the same login.
+func normalizeServerURL(s string) string {
+	s = strings.TrimSpace(s)
+	raw := s
+	if !strings.Contains(raw, "://") {
+		raw = "//" + raw
+	}
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return strings.ToLower(strings.TrimSuffix(s, "/"))
+	}
+	return strings.ToLower(u.Host) + strings.TrimSuffix(u.Path, "/")
+}
+
+// dockerSecrets returns the docker-tagged password secrets.
+func dockerSecrets(all []secret.Secret) []secret.Secret {
+	var out []secret.Secret
+	for _, sec := range all {
+		if sec.Type == secret.TypePassword && containsTag(sec.Tags, dockerTag) {
+			out = append(out, sec)
+		}
+	}
+	return out
+}
+
+func findDockerCredential(all []secret.Secret, serverURL string) (secret.Secret, bool) {
+	want := normalizeServerURL(serverURL)
+	for _, sec := range dockerSecrets(all) {
+		if normalizeServerURL(sec.URL()) == want {
+			return sec, true
+		}
+	}
+	return secret.Secret{}, false
+}
+
+// dockerCredentialList maps each stored server URL to its username.
+func dockerCredentialList(all []secret.Secret) map[string]string {
+	out := make(map[string]string)
+	for _, sec := range dockerSecrets(all) {
+		out[sec.URL()] = sec.Username()
+	}
+	return out
+}
+
+// readServerURL reads the bare server URL docker writes for get and erase.
+func readServerURL() string {
+	b, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		errf("read stdin: %v", err)
+		exit(1)
+	}
+	s := strings.TrimSpace(string(b))
+	if s == "" {
+		errfCode(codeUsage, "server url required on stdin")
+		exit(1)
+	}
+	return s
+}
+
+func listDockerSecrets() []secret.Secret {
+	v := openVault()
+	defer closeVault(v)
+
+	all, err := v.Secrets().List()
+	if err != nil {
+		errf("list secrets: %v", err)
+		exit(1)
+	}
+	return all
+}
+
+func runDockerCredentialGet(*invocation) {
+	serverURL := readServerURL()
+
+	sec, ok := findDockerCredential(listDockerSecrets(), serverURL)
+	if !ok {
+		// docker recognises this message on stdout as "not logged in"
+		fmt.Println(errDockerNotFound)
+		exit(1)
+	}
+
+	out, err := json.Marshal(dockerCredential{
+		ServerURL: serverURL,
+		Username:  sec.Username(),
+		Secret:    sec.Password(),
+	})
+	if err != nil {
+		errf("encode credentials: %v", err)
+		exit(1)
+	}
+	fmt.Println(string(out))
+}
+
+func runDockerCredentialStore(*invocation) {
+	var c dockerCredential
+	if err := json.NewDecoder(os.Stdin).Decode(&c); err != nil {
+		errf("decode credentials: %v", err)
+		exit(1)
+	}
+	if c.ServerURL == "" {
+		errfCode(codeUsage, "server url required")
+		exit(1)
+	}
+
+	v := openVault()
+	defer closeVault(v)
+
+	all, err := v.Secrets().List()
+	if err != nil {
+		errf("list secrets: %v", err)
+		exit(1)
+	}
+
+	if sec, ok := findDockerCredential(all, c.ServerURL); ok {
+		sec.Fields["username"] = c.Username
+		sec.Fields["password"] = c.Secret
+		if err := v.Secrets().Update(sec); err != nil {
+			errf("update secret: %v", err)
+			exit(1)
+		}
+		return
+	}
+
+	sec, err := secret.NewPassword(normalizeServerURL(c.ServerURL), c.ServerURL, c.Username, c.Secret)
+	if err != nil {
+		errf("create secret: %v", err)
+		exit(1)
+	}
+	sec.Tags = []string{dockerTag}
+
+	if err := v.Secrets().Add(sec); err != nil {
+		errf("store secret: %v", err)
+		exit(1)
+	}
+}
+
+func runDockerCredentialErase(*invocation) {
+	serverURL := readServerURL()
+
+	v := openVault()
+	defer closeVault(v)
+
+	all, err := v.Secrets().List()
+	if err != nil {
+		errf("list secrets: %v", err)
+		exit(1)
+	}
+
+	sec, ok := findDockerCredential(all, serverURL)
+	if !ok {
+		fmt.Println(errDockerNotFound)
+		exit(1)
+	}
+	if err := v.Secrets().Delete(sec.ID); err != nil {
+		errf("delete secret: %v", err)
+		exit(1)
+	}
+}
+
+func runDockerCredentialList(*invocation) {
+	out, err := json.Marshal(dockerCredentialList(listDockerSecrets()))
+	if err != nil {
+		errf("encode credentials: %v", err)
+		exit(1)
+	}
+	fmt.Println(string(out))
+}
@@ -0,0 +1,55 @@
+package cli
+
+import (
+	"maps"
+	"testing"
+
+	"github.com/zarlcorp/zvault/internal/secret"
+)
+
+func TestNormalizeServerURL(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"https://index.docker.io/v1/", "index.docker.io/v1"},
+		{"ghcr.io", "ghcr.io"},
+		{"https://GHCR.io/", "ghcr.io"},
+		{"registry.example.com:5000", "registry.example.com:5000"},
+		{"http://registry.example.com:5000/", "registry.example.com:5000"},
+	}
+
+	for _, tt := range tests {
+		if got := normalizeServerURL(tt.in); got != tt.want {
+			t.Errorf("normalizeServerURL(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestFindDockerCredential(t *testing.T) {
+	hub := mustPasswordSecret(t, "index.docker.io/v1", "https://index.docker.io/v1/", "alice", "hub")
+	hub.Tags = []string{dockerTag}
+	ghcr := mustPasswordSecret(t, "ghcr.io", "ghcr.io", "alice", "gh")
+	ghcr.Tags = []string{dockerTag}
+	untagged := mustPasswordSecret(t, "quay", "https://quay.io", "bob", "q")
+	all := []secret.Secret{hub, ghcr, untagged}
+
+	sec, ok := findDockerCredential(all, "https://ghcr.io")
+	if !ok || sec.Password() != "gh" {
+		t.Fatalf("ghcr.io: got %q, %v", sec.Password(), ok)
+	}
+	sec, ok = findDockerCredential(all, "https://index.docker.io/v1/")
+	if !ok || sec.Password() != "hub" {
+		t.Fatalf("docker hub: got %q, %v", sec.Password(), ok)
+	}
+	if _, ok := findDockerCredential(all, "quay.io"); ok {
+		t.Fatal("secrets without the docker tag should be ignored")
+	}
+
+	want := map[string]string{
+		"https://index.docker.io/v1/": "alice",
+		"ghcr.io":                     "alice",
+	}
+	if got := dockerCredentialList(all); !maps.Equal(got, want) {
+		t.Errorf("list = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,300 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/zarlcorp/zvault/internal/dotenv"
+	"github.com/zarlcorp/zvault/internal/secret"
+)
+
+// envTag marks note secrets holding a .env file, one field per variable.
+const envTag = "env"
+
+// envLegacyField is where env secrets imported before variables became
+// fields keep the whole file. No variable may take its name.
+const envLegacyField = "content"
+
+func envCommand() *command {
+	return &command{
+		name:    "env",
+		summary: "import and export .env files",
+		help: `
+Single variables can be referenced as zvault://<name>/<KEY> in zvault run
+and zvault inject.`,
+		subs: []*command{
+			{
+				name:     "import",
+				summary:  "store a .env file as one secret",
+				args:     "<file>",
+				minArgs:  1,
+				maxArgs:  1,
+				argFiles: true,
+				help: `
+Store the variables as a note tagged "env", one field per variable, named
+by --prefix (default: the directory the file is in). Variables can then
+be read and changed one at a time with secret get and secret edit
+--field. Importing again updates the variables in the file and keeps the
+others, unless --replace is given. Use - to read from stdin.`,
+				flags: []*flag{
+					{name: "prefix", value: "<name>", usage: "secret name"},
+					{name: "tags", value: "<a,b>", usage: "tags for the secret", names: secretTags},
+					{name: "replace", kind: boolFlag, usage: "drop variables that aren't in the file"},
+				},
+				run: runEnvImport,
+			},
+			{
+				name:     "export",
+				summary:  "print variables back out",
+				args:     "[<name>]",
+				argNames: secretNames,
+				maxArgs:  1,
+				help: `
+Print an env secret's variables. With --tag, the variables of every env
+secret with that tag are merged, in name order. The shell and fish
+formats skip, with a warning, keys such as app.name that a shell can't
+export.`,
+				flags: []*flag{
+					{name: "tag", usage: "export every env secret with this tag", names: secretTags},
+					{name: "format", usage: "output format, default dotenv", choices: dotenv.Formats},
+				},
+				run: runEnvExport,
+			},
+		},
+	}
+}
+
+func runEnvImport(in *invocation) {
+	prefix := in.value("prefix")
+	tags := parseTags(in.value("tags"))
+	path := in.args[0]
+
+	var r io.Reader = os.Stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			errf("%v", err)
+			exit(1)
+		}
+		defer f.Close()
+		r = f
+	}
+
+	entries, err := dotenv.Parse(r)
+	if err != nil {
+		errf("%s: %v", path, err)
+		exit(1)
+	}
+	if len(entries) == 0 {
+		errf("%s: no variables found", path)
+		exit(1)
+	}
+
+	if prefix == "" {
+		prefix = defaultEnvName(path)
+	}
+	if prefix == "" {
+		errfCode(codeUsage, "secret name required (--prefix <name>)")
+		exit(1)
+	}
+
+	entries = slices.DeleteFunc(entries, func(e dotenv.Entry) bool {
+		if e.Key != envLegacyField {
+			return false
+		}
+		fmt.Fprintf(os.Stderr, "%s skipping %s: the name is reserved\n", yellow("warn"), e.Key)
+		return true
+	})
+
+	v := openVault()
+	defer closeVault(v)
+
+	all, err := v.Secrets().List()
+	if err != nil {
+		errf("list secrets: %v", err)
+		exit(1)
+	}
+
+	for _, sec := range all {
+		if !strings.EqualFold(sec.Name, prefix) {
+			continue
+		}
+		if sec.Type != secret.TypeNote || !containsTag(sec.Tags, envTag) {
+			errf("secret %q already exists and is not an env secret", sec.Name)
+			exit(1)
+		}
+		if !in.has("replace") {
+			old, err := envEntries(sec)
+			if err != nil {
+				errf("%v", err)
+				exit(1)
+			}
+			entries = mergeEnvEntries(old, entries)
+		}
+		sec.Fields = envFields(entries)
+		for _, t := range tags {
+			if !containsTag(sec.Tags, t) {
+				sec.Tags = append(sec.Tags, t)
+			}
+		}
+		if err := v.Secrets().Update(sec); err != nil {
+			errf("update secret: %v", err)
+			exit(1)
+		}
+		fmt.Printf("%s %s updated (%d variables)\n", green(sec.ID), bold(sec.Name), len(entries))
+		return
+	}
+
+	sec, err := secret.NewNote(prefix, "")
+	if err != nil {
+		errf("create secret: %v", err)
+		exit(1)
+	}
+	sec.Fields = envFields(entries)
+	sec.Tags = append([]string{envTag}, slices.DeleteFunc(tags, func(t string) bool { return t == envTag })...)
+
+	if err := v.Secrets().Add(sec); err != nil {
+		errf("store secret: %v", err)
+		exit(1)
+	}
+	fmt.Printf("%s %s stored (%d variables)\n", green(sec.ID), bold(sec.Name), len(entries))
+}
+
+// defaultEnvName names an imported file after its directory, which for a
+// service repo's .env is the service name.
+func defaultEnvName(path string) string {
+	if path == "-" {
+		return ""
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return ""
+	}
+	dir := filepath.Base(filepath.Dir(abs))
+	if dir == "/" || dir == "." {
+		return ""
+	}
+	return dir
+}
+
+func runEnvExport(in *invocation) {
+	tag := in.value("tag")
+	format := in.value("format")
+
+	if tag == "" && len(in.args) == 0 {
+		errfCode(codeUsage, "secret name or --tag required")
+		exit(1)
+	}
+
+	v := openVault()
+	defer closeVault(v)
+
+	var sources []secret.Secret
+	if tag != "" {
+		all, err := v.Secrets().List()
+		if err != nil {
+			errf("list secrets: %v", err)
+			exit(1)
+		}
+		sources = envSecretsWithTag(all, tag)
+		if len(sources) == 0 {
+			errf("no env secrets tagged %q", tag)
+			exit(1)
+		}
+	} else {
+		sec, err := resolveSecret(v, in.args[0])
+		if err != nil {
+			errf("%v", err)
+			exit(1)
+		}
+		if sec.Type != secret.TypeNote {
+			errf("secret %q is a %s, not an env note", sec.Name, sec.Type)
+			exit(1)
+		}
+		sources = []secret.Secret{sec}
+	}
+
+	var entries []dotenv.Entry
+	for _, sec := range sources {
+		e, err := envEntries(sec)
+		if err != nil {
+			errf("%v", err)
+			exit(1)
+		}
+		entries = mergeEnvEntries(entries, e)
+	}
+
+	if format == dotenv.FormatShell || format == dotenv.FormatFish {
+		for _, e := range entries {
+			if !dotenv.ShellName(e.Key) {
+				fmt.Fprintf(os.Stderr, "%s skipping %s: not a valid %s variable name\n", yellow("warn"), e.Key, format)
+			}
+		}
+	}
+	if err := dotenv.Format(os.Stdout, entries, format); err != nil {
+		errf("%v", err)
+		exit(1)
+	}
+}
+
+// envSecretsWithTag returns env notes carrying tag, sorted by name.
+func envSecretsWithTag(all []secret.Secret, tag string) []secret.Secret {
+	var out []secret.Secret
+	for _, sec := range all {
+		if sec.Type == secret.TypeNote && containsTag(sec.Tags, envTag) && containsTag(sec.Tags, tag) {
+			out = append(out, sec)
+		}
+	}
+	slices.SortFunc(out, func(a, b secret.Secret) int {
+		return strings.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name))
+	})
+	return out
+}
+
+// envEntries returns the variables of an env secret, sorted by name. An
+// env secret from an older zvault holds a .env file in its content; its
+// variables come first.
+func envEntries(sec secret.Secret) ([]dotenv.Entry, error) {
+	var entries []dotenv.Entry
+	if content := sec.Fields[envLegacyField]; content != "" {
+		var err error
+		entries, err = dotenv.Parse(strings.NewReader(content))
+		if err != nil {
+			return nil, fmt.Errorf("secret %q: %w", sec.Name, err)
+		}
+	}
+	var fields []dotenv.Entry
+	for _, k := range slices.Sorted(maps.Keys(sec.Fields)) {
+		if k != envLegacyField {
+			fields = append(fields, dotenv.Entry{Key: k, Value: sec.Fields[k]})
+		}
+	}
+	return mergeEnvEntries(entries, fields), nil
+}
+
+// envFields stores variables as the fields of an env secret.
+func envFields(entries []dotenv.Entry) map[string]string {
+	fields := make(map[string]string, len(entries))
+	for _, e := range entries {
+		fields[e.Key] = e.Value
+	}
+	return fields
+}
+
+// mergeEnvEntries adds entries to base, later values winning.
+func mergeEnvEntries(base, entries []dotenv.Entry) []dotenv.Entry {
+	for _, e := range entries {
+		i := slices.IndexFunc(base, func(b dotenv.Entry) bool { return b.Key == e.Key })
+		if i >= 0 {
+			base[i].Value = e.Value
+			continue
+		}
+		base = append(base, e)
+	}
+	return base
+}
@@ -0,0 +1,132 @@
+package cli
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/zarlcorp/zvault/internal/dotenv"
+	"github.com/zarlcorp/zvault/internal/secret"
+)
+
+// mustEnvNote builds an env secret the way env import stores one.
+func mustEnvNote(t *testing.T, name, env string, tags ...string) secret.Secret {
+	t.Helper()
+	entries, err := dotenv.Parse(strings.NewReader(env))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sec, err := secret.NewNote(name, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sec.Fields = envFields(entries)
+	sec.Tags = append([]string{envTag}, tags...)
+	return sec
+}
+
+func TestEnvSecretsWithTag(t *testing.T) {
+	api := mustEnvNote(t, "api", "A=1\n", "prod")
+	web := mustEnvNote(t, "Web", "B=2\n", "prod")
+	dev := mustEnvNote(t, "dev", "C=3\n", "dev")
+	plain, err := secret.NewNote("notes", "prod things")
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain.Tags = []string{"prod"}
+
+	got := envSecretsWithTag([]secret.Secret{web, dev, plain, api}, "prod")
+	var names []string
+	for _, s := range got {
+		names = append(names, s.Name)
+	}
+	if !slices.Equal(names, []string{"api", "Web"}) {
+		t.Errorf("names = %q, want [api Web]", names)
+	}
+}
+
+func TestMergeEnvEntries(t *testing.T) {
+	got := mergeEnvEntries(
+		[]dotenv.Entry{{Key: "A", Value: "1"}, {Key: "B", Value: "2"}},
+		[]dotenv.Entry{{Key: "B", Value: "override"}, {Key: "C", Value: "3"}},
+	)
+	want := []dotenv.Entry{{Key: "A", Value: "1"}, {Key: "B", Value: "override"}, {Key: "C", Value: "3"}}
+	if !slices.Equal(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestEnvEntries(t *testing.T) {
+	sec := mustEnvNote(t, "api", "PORT=8080\nEMPTY=\nAPP_NAME=api\n")
+	got, err := envEntries(sec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []dotenv.Entry{{Key: "APP_NAME", Value: "api"}, {Key: "EMPTY"}, {Key: "PORT", Value: "8080"}}
+	if !slices.Equal(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	// a secret from an older zvault holds the file in its content; fields
+	// set since then win
+	legacy, err := secret.NewNote("old", "PORT=80\nHOST=db\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	legacy.Tags = []string{envTag}
+	legacy.Fields["PORT"] = "8080"
+	got, err = envEntries(legacy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = []dotenv.Entry{{Key: "PORT", Value: "8080"}, {Key: "HOST", Value: "db"}}
+	if !slices.Equal(got, want) {
+		t.Errorf("legacy: got %q, want %q", got, want)
+	}
+
+	// re-importing merges, and the stored secret loses its content
+	got = mergeEnvEntries(got, []dotenv.Entry{{Key: "HOST", Value: "db2"}, {Key: "DEBUG", Value: "1"}})
+	fields := envFields(got)
+	if _, ok := fields[envLegacyField]; ok || len(fields) != 3 || fields["PORT"] != "8080" || fields["HOST"] != "db2" {
+		t.Errorf("fields = %v", fields)
+	}
+}
+
+func TestDefaultEnvName(t *testing.T) {
+	if got := defaultEnvName("/srv/billing-api/.env"); got != "billing-api" {
+		t.Errorf("got %q, want billing-api", got)
+	}
+	if got := defaultEnvName("-"); got != "" {
+		t.Errorf("stdin should have no default name, got %q", got)
+	}
+}
+
+func TestSecretFieldValueEnvNote(t *testing.T) {
+	sec := mustEnvNote(t, "billing", "DATABASE_URL=\"postgres://db/app\"\nPORT=8080\n")
+
+	got, err := secretFieldValue(sec, "DATABASE_URL")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "postgres://db/app" {
+		t.Errorf("DATABASE_URL = %q", got)
+	}
+	if _, err := secretFieldValue(sec, "MISSING"); err == nil {
+		t.Error("expected error for a missing variable")
+	}
+
+	legacy, err := secret.NewNote("billing", "PORT=8080\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	legacy.Tags = []string{envTag}
+	if got, err := secretFieldValue(legacy, "PORT"); err != nil || got != "8080" {
+		t.Errorf("legacy PORT = %q, %v", got, err)
+	}
+
+	// notes without the env tag are not parsed
+	legacy.Tags = nil
+	if _, err := secretFieldValue(legacy, "PORT"); err == nil {
+		t.Error("expected error for a plain note")
+	}
+}
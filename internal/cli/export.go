@@ -1,57 +1,135 @@
 package cli
 
 import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
 	"fmt"
+	"io"
 	"os"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/zarlcorp/zvault/internal/archive"
+	"github.com/zarlcorp/zvault/internal/secret"
 	"github.com/zarlcorp/zvault/internal/task"
+	"golang.org/x/term"
 )
 
-func runExport(args []string) {
-	exportTasks := hasFlag(args, "--tasks")
-	exportSecrets := hasFlag(args, "--secrets")
-	pending := hasFlag(args, "--pending")
-	done := hasFlag(args, "--done")
+// exportFormats lists the formats accepted by export --format.
+var exportFormats = []string{"markdown", "json", "csv"}
 
-	// default: export everything
-	if !exportTasks && !exportSecrets {
-		exportTasks = true
-		exportSecrets = true
+func exportCommand() *command {
+	return &command{
+		name:    "export",
+		summary: "export vault data as markdown, json or csv",
+		help: `
+Export vault data to stdout. Without --tasks or --secrets, exports
+everything (csv exports secrets unless --tasks is given).
+
+Formats:
+  markdown   a readable summary (default)
+  json       every field and timestamp of every secret and task; import
+             it elsewhere with zvault import --from zvault-json
+  csv        one row per secret, or per task with --tasks
+
+Secret values are left out unless --include-values is given, which asks
+for confirmation on the terminal. --encrypt implies it, since a sealed
+backup is meant to be restored.`,
+		flags: []*flag{
+			{name: "format", usage: "output format", choices: exportFormats},
+			{name: "tasks", kind: boolFlag, usage: "export tasks"},
+			{name: "secrets", kind: boolFlag, usage: "export secrets"},
+			{name: "pending", kind: boolFlag, usage: "pending tasks only"},
+			{name: "done", kind: boolFlag, usage: "completed tasks only"},
+			{name: "include-values", kind: boolFlag, usage: "include passwords, keys, notes and other values"},
+			{name: "encrypt", kind: boolFlag, usage: "seal a json export, with values, under a passphrase"},
+		},
+		run: runExport,
+	}
+}
+
+func runExport(in *invocation) {
+	exportTasks := in.has("tasks")
+	exportSecrets := in.has("secrets")
+	pending := in.has("pending")
+	done := in.has("done")
+	encrypt := in.has("encrypt")
+	// import refuses archives without values, so a sealed backup has them
+	includeValues := in.has("include-values") || encrypt
+
+	format := in.value("format")
+	if jsonOutput {
+		if format != "" && format != "json" {
+			errfCode(codeUsage, "--json conflicts with --format %s", format)
+			exit(1)
+		}
+		format = "json"
+	}
+	if format == "" {
+		format = "markdown"
+		if encrypt {
+			format = "json"
+		}
+	}
+
+	switch format {
+	case "markdown", "json":
+		// default: export everything
+		if !exportTasks && !exportSecrets {
+			exportTasks = true
+			exportSecrets = true
+		}
+	case "csv":
+		// a csv file holds one table, so it defaults to secrets
+		if exportTasks && exportSecrets {
+			errfCode(codeUsage, "csv holds one table; use --secrets or --tasks")
+			exit(1)
+		}
+		if !exportTasks {
+			exportSecrets = true
+		}
+	}
+	if encrypt && format != "json" {
+		errfCode(codeUsage, "--encrypt writes a json export; drop --format %s", format)
+		exit(1)
+	}
+
+	if includeValues {
+		if !confirmOnTerminal("export secret values (passwords, keys, notes)?") {
+			errf("aborted")
+			exit(1)
+		}
+	}
+	var passphrase string
+	if encrypt {
+		passphrase = promptPassword("export passphrase: ")
+		if passphrase == "" {
+			errf("passphrase cannot be empty")
+			exit(1)
+		}
+		if promptPassword("confirm passphrase: ") != passphrase {
+			errf("passphrases do not match")
+			exit(1)
+		}
 	}
 
 	v := openVault()
-	defer v.Close()
+	defer closeVault(v)
 
+	var secrets []secret.Secret
 	if exportSecrets {
-		secrets, err := v.Secrets().List()
+		var err error
+		secrets, err = v.Secrets().List()
 		if err != nil {
 			errf("list secrets: %v", err)
-			os.Exit(1)
+			exit(1)
 		}
-
-		fmt.Println("# Secrets")
-		fmt.Println()
-		if len(secrets) == 0 {
-			fmt.Println("No secrets stored.")
-		} else {
-			fmt.Println("| ID | Type | Name | Tags |")
-			fmt.Println("|---|---|---|---|")
-			for _, sec := range secrets {
-				tags := ""
-				if len(sec.Tags) > 0 {
-					var parts []string
-					for _, t := range sec.Tags {
-						parts = append(parts, "#"+t)
-					}
-					tags = strings.Join(parts, " ")
-				}
-				fmt.Printf("| %s | %s | %s | %s |\n", sec.ID, sec.Type, sec.Name, tags)
-			}
-		}
-		fmt.Println()
 	}
 
+	var tasks []task.Task
 	if exportTasks {
 		var f task.Filter
 		if pending {
@@ -60,16 +138,118 @@ func runExport(args []string) {
 			f.Status = task.FilterDone
 		}
 
-		tasks, err := v.Tasks().List(f)
+		var err error
+		tasks, err = v.Tasks().List(f)
 		if err != nil {
 			errf("list tasks: %v", err)
-			os.Exit(1)
+			exit(1)
+		}
+	}
+
+	var buf bytes.Buffer
+	switch format {
+	case "markdown":
+		writeMarkdownExport(&buf, secrets, tasks, exportSecrets, exportTasks, includeValues)
+	case "json":
+		if !includeValues {
+			secrets = redactSecrets(secrets)
+		}
+		b, err := archive.Marshal(archive.Archive{
+			ExportedAt:     time.Now().UTC(),
+			IncludesValues: includeValues,
+			Secrets:        secrets,
+			Tasks:          tasks,
+		})
+		if err != nil {
+			errf("%v", err)
+			exit(1)
+		}
+		buf.Write(b)
+	case "csv":
+		var err error
+		if exportSecrets {
+			if !includeValues {
+				secrets = redactSecrets(secrets)
+			}
+			err = writeSecretsCSV(&buf, secrets)
+		} else {
+			err = writeTasksCSV(&buf, tasks)
+		}
+		if err != nil {
+			errf("write csv: %v", err)
+			exit(1)
+		}
+	}
+
+	out := buf.Bytes()
+	if encrypt {
+		var err error
+		out, err = archive.Seal(out, passphrase)
+		if err != nil {
+			errf("%v", err)
+			exit(1)
+		}
+	}
+	os.Stdout.Write(out)
+}
+
+// confirmOnTerminal asks a y/n question on the terminal, even when stdin
+// is piped, so a script can't answer it.
+func confirmOnTerminal(prompt string) bool {
+	in := os.Stdin
+	if !term.IsTerminal(int(in.Fd())) {
+		tty, err := os.Open("/dev/tty")
+		if err != nil {
+			errf("no terminal to confirm on")
+			exit(1)
 		}
+		defer tty.Close()
+		in = tty
+	}
+
+	fmt.Fprint(os.Stderr, yellow(prompt)+" [y/N] ")
+	scanner := bufio.NewScanner(in)
+	if scanner.Scan() {
+		ans := strings.TrimSpace(strings.ToLower(scanner.Text()))
+		return ans == "y" || ans == "yes"
+	}
+	return false
+}
 
-		fmt.Println("# Tasks")
-		fmt.Println()
+func redactSecrets(secrets []secret.Secret) []secret.Secret {
+	out := make([]secret.Secret, len(secrets))
+	for i, sec := range secrets {
+		out[i] = sec.Redacted()
+	}
+	return out
+}
+
+func writeMarkdownExport(w io.Writer, secrets []secret.Secret, tasks []task.Task, exportSecrets, exportTasks, includeValues bool) {
+	if exportSecrets {
+		fmt.Fprintln(w, "# Secrets")
+		fmt.Fprintln(w)
+		if len(secrets) == 0 {
+			fmt.Fprintln(w, "No secrets stored.")
+		} else {
+			fmt.Fprintln(w, "| ID | Type | Name | Tags |")
+			fmt.Fprintln(w, "|---|---|---|---|")
+			for _, sec := range secrets {
+				fmt.Fprintf(w, "| %s | %s | %s | %s |\n", sec.ID, sec.Type, sec.Name, hashTags(sec.Tags))
+			}
+			if includeValues {
+				for _, sec := range secrets {
+					writeMarkdownFields(w, sec)
+				}
+			}
+		}
+		fmt.Fprintln(w)
+	}
+
+	if exportTasks {
+		fmt.Fprintln(w, "# Tasks")
+		fmt.Fprintln(w)
 		if len(tasks) == 0 {
-			fmt.Println("No tasks found.")
+			fmt.Fprintln(w, "No tasks found.")
 		} else {
 			for _, tk := range tasks {
 				check := "[ ]"
@@ -82,11 +262,7 @@ func runExport(args []string) {
 					extra += "  due: " + tk.DueDate.Format("2006-01-02")
 				}
 				if len(tk.Tags) > 0 {
-					var parts []string
-					for _, t := range tk.Tags {
-						parts = append(parts, "#"+t)
-					}
-					extra += "  " + strings.Join(parts, " ")
+					extra += "  " + hashTags(tk.Tags)
 				}
 
 				pri := ""
@@ -97,9 +273,104 @@ func runExport(args []string) {
 					pri = "! "
 				}
 
-				fmt.Printf("- %s %s%s%s\n", check, pri, tk.Title, extra)
+				fmt.Fprintf(w, "- %s %s%s%s\n", check, pri, tk.Title, extra)
 			}
 		}
-		fmt.Println()
+		fmt.Fprintln(w)
+	}
+}
+
+// writeMarkdownFields writes a section listing a secret's fields; values
+// spanning several lines go in a code block.
+func writeMarkdownFields(w io.Writer, sec secret.Secret) {
+	fmt.Fprintf(w, "\n## %s\n\n", sec.Name)
+	for _, k := range sortedFieldKeys(sec.Fields) {
+		v := sec.Fields[k]
+		if v == "" {
+			continue
+		}
+		if strings.Contains(v, "\n") {
+			fmt.Fprintf(w, "- %s:\n\n```\n%s\n```\n\n", k, strings.TrimRight(v, "\n"))
+			continue
+		}
+		fmt.Fprintf(w, "- %s: `%s`\n", k, v)
+	}
+}
+
+func hashTags(tags []string) string {
+	parts := make([]string, len(tags))
+	for i, t := range tags {
+		parts[i] = "#" + t
+	}
+	return strings.Join(parts, " ")
+}
+
+func sortedFieldKeys(fields map[string]string) []string {
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// writeSecretsCSV writes one row per secret, with a column for every field
+// any secret has.
+func writeSecretsCSV(w io.Writer, secrets []secret.Secret) error {
+	seen := make(map[string]bool)
+	var fields []string
+	for _, sec := range secrets {
+		for k := range sec.Fields {
+			if !seen[k] {
+				seen[k] = true
+				fields = append(fields, k)
+			}
+		}
+	}
+	sort.Strings(fields)
+
+	cw := csv.NewWriter(w)
+	cw.Write(append([]string{"id", "type", "name", "tags", "created_at", "updated_at"}, fields...))
+	for _, sec := range secrets {
+		row := []string{
+			sec.ID,
+			string(sec.Type),
+			sec.Name,
+			strings.Join(sec.Tags, ","),
+			sec.CreatedAt.Format(time.RFC3339),
+			sec.UpdatedAt.Format(time.RFC3339),
+		}
+		for _, k := range fields {
+			row = append(row, sec.Fields[k])
+		}
+		cw.Write(row)
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func writeTasksCSV(w io.Writer, tasks []task.Task) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"id", "title", "done", "priority", "due_date", "tags", "created_at", "completed_at"})
+	for _, tk := range tasks {
+		var due, completed string
+		if tk.DueDate != nil {
+			due = tk.DueDate.Format(time.RFC3339)
+		}
+		if tk.CompletedAt != nil {
+			completed = tk.CompletedAt.Format(time.RFC3339)
+		}
+		cw.Write([]string{
+			tk.ID,
+			tk.Title,
+			strconv.FormatBool(tk.Done),
+			string(tk.Priority),
+			due,
+			strings.Join(tk.Tags, ","),
+			tk.CreatedAt.Format(time.RFC3339),
+			completed,
+		})
 	}
+	cw.Flush()
+	return cw.Error()
 }
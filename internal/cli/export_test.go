@@ -0,0 +1,103 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/csv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zarlcorp/zvault/internal/secret"
+	"github.com/zarlcorp/zvault/internal/task"
+)
+
+func TestWriteSecretsCSV(t *testing.T) {
+	gh := mustPasswordSecret(t, "github", "github.com", "alice", "pw")
+	gh.Tags = []string{"work", "dev"}
+	gh.CreatedAt = time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	gh.UpdatedAt = gh.CreatedAt
+	note, err := secret.NewNote("wifi", "line one\nline two")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := writeSecretsCSV(&buf, []secret.Secret{gh, note}); err != nil {
+		t.Fatal(err)
+	}
+	rows, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantHeader := "id,type,name,tags,created_at,updated_at,content,password,url,username"
+	if got := strings.Join(rows[0], ","); got != wantHeader {
+		t.Fatalf("header = %q, want %q", got, wantHeader)
+	}
+	if len(rows) != 3 {
+		t.Fatalf("got %d rows, want 3", len(rows))
+	}
+	want := []string{gh.ID, "password", "github", "work,dev", "2024-01-02T03:04:05Z", "2024-01-02T03:04:05Z", "", "pw", "github.com", "alice"}
+	if got := strings.Join(rows[1], "|"); got != strings.Join(want, "|") {
+		t.Errorf("row = %q, want %q", got, strings.Join(want, "|"))
+	}
+	if rows[2][6] != "line one\nline two" {
+		t.Errorf("multi-line content = %q", rows[2][6])
+	}
+}
+
+func TestWriteTasksCSV(t *testing.T) {
+	created := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	due := created.AddDate(0, 0, 7)
+	tasks := []task.Task{
+		{ID: "aaaa1111", Title: "ship it", Priority: task.PriorityHigh, DueDate: &due, Tags: []string{"work"}, CreatedAt: created},
+		{ID: "bbbb2222", Title: "done, really", Done: true, CreatedAt: created, CompletedAt: &created},
+	}
+
+	var buf bytes.Buffer
+	if err := writeTasksCSV(&buf, tasks); err != nil {
+		t.Fatal(err)
+	}
+	want := `id,title,done,priority,due_date,tags,created_at,completed_at
+aaaa1111,ship it,false,high,2024-01-09T00:00:00Z,work,2024-01-02T00:00:00Z,
+bbbb2222,"done, really",true,,,,2024-01-02T00:00:00Z,2024-01-02T00:00:00Z
+`
+	if got := buf.String(); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestWriteMarkdownExportValues(t *testing.T) {
+	gh := mustPasswordSecret(t, "github", "github.com", "alice", "hunter2")
+	note, err := secret.NewNote("wifi", "ssid: home\npsk: secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	secrets := []secret.Secret{gh, note}
+
+	var buf bytes.Buffer
+	writeMarkdownExport(&buf, secrets, nil, true, false, false)
+	if strings.Contains(buf.String(), "hunter2") {
+		t.Fatalf("values exported without --include-values:\n%s", buf.String())
+	}
+
+	buf.Reset()
+	writeMarkdownExport(&buf, secrets, nil, true, false, true)
+	out := buf.String()
+	for _, want := range []string{"## github", "- password: `hunter2`", "## wifi", "```\nssid: home\npsk: secret\n```"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestRedactSecrets(t *testing.T) {
+	gh := mustPasswordSecret(t, "github", "github.com", "alice", "hunter2")
+	got := redactSecrets([]secret.Secret{gh})
+	if got[0].Password() != "" || got[0].Username() != "alice" {
+		t.Errorf("redacted fields = %v", got[0].Fields)
+	}
+	if gh.Password() != "hunter2" {
+		t.Error("redactSecrets modified its input")
+	}
+}
@@ -0,0 +1,10 @@
+//go:build !windows
+
+package cli
+
+import "syscall"
+
+// makeFifo creates a named pipe only the owner can use.
+func makeFifo(path string) error {
+	return syscall.Mkfifo(path, 0o600)
+}
@@ -0,0 +1,9 @@
+package cli
+
+import "errors"
+
+// makeFifo is unsupported: Windows named pipes do not live in the file
+// system where netrc readers look.
+func makeFifo(path string) error {
+	return errors.New("named pipes are not supported on windows")
+}
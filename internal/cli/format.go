@@ -0,0 +1,290 @@
+package cli
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"text/template"
+	"time"
+
+	"github.com/zarlcorp/zvault/internal/secret"
+	"github.com/zarlcorp/zvault/internal/task"
+)
+
+// secretSorts and taskSorts are the keys list commands sort by.
+var (
+	secretSorts = []string{"name", "updated", "created", "type"}
+	taskSorts   = []string{"name", "created", "due", "priority"}
+)
+
+// secretColumns and taskColumns are what --columns selects, as templates
+// over secretOutput and taskOutput. Any other secret column is a field.
+var (
+	secretColumns = map[string]string{
+		"id":      "{{.ID}}",
+		"name":    "{{.Name}}",
+		"type":    "{{.Type}}",
+		"tags":    `{{join " " .Tags}}`,
+		"created": "{{relative .CreatedAt}}",
+		"updated": "{{relative .UpdatedAt}}",
+	}
+	taskColumns = map[string]string{
+		"id":        "{{.ID}}",
+		"status":    `{{if .Done}}done{{else}}pending{{end}}`,
+		"priority":  "{{.Priority}}",
+		"title":     "{{.Title}}",
+		"due":       "{{due .DueDate}}",
+		"tags":      `{{join " " .Tags}}`,
+		"created":   "{{relative .CreatedAt}}",
+		"completed": "{{relative .CompletedAt}}",
+	}
+)
+
+// listFlags are the output flags of secret list, secret search and task
+// list.
+func listFlags(sorts []string, columns map[string]string) []*flag {
+	names := slices.Sorted(maps.Keys(columns))
+	return []*flag{
+		{name: "format", value: "<template>", usage: "print each item with a Go template, e.g. '{{.Name}}'"},
+		{name: "columns", value: "<a,b>", usage: "print only these columns, aligned", suggest: names},
+		{name: "sort", value: "<key>", usage: "sort by", choices: sorts},
+		{name: "reverse", kind: boolFlag, usage: "reverse the order"},
+		{name: "limit", kind: intFlag, usage: "print at most n items; 0 prints all"},
+	}
+}
+
+// listFormatHelp documents listFlags for a command's --help.
+const listFormatHelp = `
+--format prints each item with a Go template over the fields of the
+--json output (.ID, .Name, .Tags, ...), which never hold secret values.
+Functions:
+  due         a due date relative to today: "in 3d", "overdue 2d"
+  relative    a time relative to now: "5m ago", "in 2h"
+  join        join a list: {{join "," .Tags}}
+  mask        hide a value behind asterisks
+  color       color text: {{color "red" .Name}} (red, green, yellow,
+              peach, blue, muted, bold); NO_COLOR is respected
+
+--columns picks columns of a tab-aligned table instead.
+
+--sort orders names and types A to Z, dates newest first, due dates
+soonest first and priorities highest first; --reverse flips it.`
+
+// listFormat prints the items of a list command with --format or
+// --columns.
+type listFormat struct {
+	tmpl  *template.Template
+	table bool // align tab-separated cells
+}
+
+// newListFormat checks the output flags of a list command, before the
+// vault is opened. It returns nil without --format or --columns.
+func newListFormat(in *invocation, columns map[string]string, fieldColumns bool) (*listFormat, error) {
+	if in.int("limit") < 0 {
+		return nil, usagef("invalid value %q for --limit (want 0 or more)", in.value("limit"))
+	}
+	format, cols := in.value("format"), in.value("columns")
+	switch {
+	case format != "" && cols != "":
+		return nil, usagef("--format and --columns can't be combined")
+	case (format != "" || cols != "") && jsonOutput:
+		return nil, usagef("--json can't be combined with --format or --columns")
+	case format != "":
+		t, err := parseListTemplate(format)
+		if err != nil {
+			return nil, usagef("invalid --format: %v", err)
+		}
+		return &listFormat{tmpl: t}, nil
+	case cols != "":
+		var cells []string
+		for _, c := range parseTags(cols) {
+			switch cell, ok := columns[c]; {
+			case ok:
+				cells = append(cells, cell)
+			case fieldColumns:
+				cells = append(cells, fmt.Sprintf("{{index .Fields %q}}", c))
+			default:
+				return nil, usagef("invalid column %q (use %s)", c, strings.Join(slices.Sorted(maps.Keys(columns)), ", "))
+			}
+		}
+		t, err := parseListTemplate(strings.Join(cells, "\t"))
+		if err != nil {
+			return nil, err
+		}
+		return &listFormat{tmpl: t, table: true}, nil
+	}
+	return nil, nil
+}
+
+func parseListTemplate(text string) (*template.Template, error) {
+	return template.New("format").Option("missingkey=zero").Funcs(listTemplateFuncs).Parse(text)
+}
+
+// printList prints items to w with f, one per line.
+func printList[T any](w io.Writer, f *listFormat, items []T) error {
+	var buf bytes.Buffer
+	for _, item := range items {
+		if err := f.tmpl.Execute(&buf, item); err != nil {
+			return err
+		}
+		buf.WriteByte('\n')
+	}
+	if !f.table {
+		_, err := w.Write(buf.Bytes())
+		return err
+	}
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	tw.Write(buf.Bytes())
+	return tw.Flush()
+}
+
+var listTemplateFuncs = template.FuncMap{
+	"due":      dueText,
+	"relative": relativeTime,
+	"join": func(sep string, items []string) string {
+		return strings.Join(items, sep)
+	},
+	"mask": func(s string) string {
+		if s == "" {
+			return ""
+		}
+		return "********"
+	},
+	"color": func(name, s string) (string, error) {
+		fn, ok := colorFuncs[name]
+		if !ok {
+			return "", fmt.Errorf("unknown color %q", name)
+		}
+		return fn(s), nil
+	},
+}
+
+// colorFuncs are the colors of the color template function.
+var colorFuncs = map[string]func(string) string{
+	"red":    red,
+	"green":  green,
+	"yellow": yellow,
+	"peach":  peach,
+	"blue":   blue,
+	"muted":  muted,
+	"bold":   bold,
+}
+
+// relativeTime describes t relative to now, e.g. "5m ago" or "in 2d".
+// Beyond a month it's the date. It takes a time.Time or *time.Time and
+// returns "" for nil or zero.
+func relativeTime(v any) (string, error) {
+	var t time.Time
+	switch v := v.(type) {
+	case time.Time:
+		t = v
+	case *time.Time:
+		if v != nil {
+			t = *v
+		}
+	default:
+		return "", fmt.Errorf("relative: want a time, got %T", v)
+	}
+	if t.IsZero() {
+		return "", nil
+	}
+
+	d := time.Since(t)
+	suffix := " ago"
+	if d < 0 {
+		d, suffix = -d, ""
+	}
+	var s string
+	switch {
+	case d < time.Minute:
+		return "just now", nil
+	case d < time.Hour:
+		s = fmt.Sprintf("%dm", int(d.Minutes()))
+	case d < 24*time.Hour:
+		s = fmt.Sprintf("%dh", int(d.Hours()))
+	case d < 30*24*time.Hour:
+		s = fmt.Sprintf("%dd", int(d.Hours()/24))
+	default:
+		return t.Format("2006-01-02"), nil
+	}
+	if suffix == "" {
+		return "in " + s, nil
+	}
+	return s + suffix, nil
+}
+
+// arrange applies --reverse and --limit to sorted items.
+func arrange[T any](in *invocation, items []T) []T {
+	if in.has("reverse") {
+		slices.Reverse(items)
+	}
+	if n := in.int("limit"); n > 0 && len(items) > n {
+		items = items[:n]
+	}
+	return items
+}
+
+// sortSecrets orders secrets by one of secretSorts, ties by name.
+func sortSecrets(secrets []secret.Secret, by string) {
+	byName := func(a, b secret.Secret) int {
+		return strings.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name))
+	}
+	slices.SortStableFunc(secrets, func(a, b secret.Secret) int {
+		var c int
+		switch by {
+		case "updated":
+			c = b.UpdatedAt.Compare(a.UpdatedAt)
+		case "created":
+			c = b.CreatedAt.Compare(a.CreatedAt)
+		case "type":
+			c = strings.Compare(string(a.Type), string(b.Type))
+		}
+		if c != 0 {
+			return c
+		}
+		return byName(a, b)
+	})
+}
+
+// priorityRank orders priorities highest first.
+var priorityRank = map[task.Priority]int{
+	task.PriorityHigh:   0,
+	task.PriorityMedium: 1,
+	task.PriorityLow:    2,
+	task.PriorityNone:   3,
+}
+
+// sortTasks orders tasks by one of taskSorts, ties by title. Tasks
+// without a due date sort after those with one.
+func sortTasks(tasks []task.Task, by string) {
+	byTitle := func(a, b task.Task) int {
+		return strings.Compare(strings.ToLower(a.Title), strings.ToLower(b.Title))
+	}
+	slices.SortStableFunc(tasks, func(a, b task.Task) int {
+		var c int
+		switch by {
+		case "created":
+			c = b.CreatedAt.Compare(a.CreatedAt)
+		case "due":
+			switch {
+			case a.DueDate == nil && b.DueDate == nil:
+			case a.DueDate == nil:
+				c = 1
+			case b.DueDate == nil:
+				c = -1
+			default:
+				c = a.DueDate.Compare(*b.DueDate)
+			}
+		case "priority":
+			c = priorityRank[a.Priority] - priorityRank[b.Priority]
+		}
+		if c != 0 {
+			return c
+		}
+		return byTitle(a, b)
+	})
+}
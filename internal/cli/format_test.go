@@ -0,0 +1,167 @@
+package cli
+
+import (
+	"bytes"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/zarlcorp/zvault/internal/secret"
+	"github.com/zarlcorp/zvault/internal/task"
+)
+
+func TestRelativeTime(t *testing.T) {
+	now := time.Now()
+	old := time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC)
+	tests := []struct {
+		name string
+		in   any
+		want string
+	}{
+		{"just now", now, "just now"},
+		{"minutes", now.Add(-5*time.Minute - time.Second), "5m ago"},
+		{"hours", now.Add(-3*time.Hour - time.Second), "3h ago"},
+		{"days", now.Add(-49 * time.Hour), "2d ago"},
+		{"future", now.Add(2*time.Hour + time.Minute), "in 2h"},
+		{"old", old, "2021-03-04"},
+		{"pointer", &old, "2021-03-04"},
+		{"nil", (*time.Time)(nil), ""},
+		{"zero", time.Time{}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := relativeTime(tt.in)
+			if err != nil || got != tt.want {
+				t.Errorf("relativeTime = %q, %v, want %q", got, err, tt.want)
+			}
+		})
+	}
+	if _, err := relativeTime("yesterday"); err == nil {
+		t.Error("relativeTime(string): want error")
+	}
+}
+
+func TestListFormat(t *testing.T) {
+	t.Setenv("NO_COLOR", "1")
+	created := time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC)
+	secrets := secretOutputs([]secret.Secret{
+		{ID: "a1b2c3d4", Name: "github", Type: secret.TypePassword, Tags: []string{"dev", "git"},
+			Fields: map[string]string{"username": "me", "password": "hunter2"}, CreatedAt: created, UpdatedAt: created},
+	})
+
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{"fields", []string{"--format", "{{.Name}} {{.Type}}"}, "github password\n"},
+		{"join", []string{"--format", `{{join "," .Tags}}`}, "dev,git\n"},
+		{"mask", []string{"--format", "{{mask .Fields.username}}"}, "********\n"},
+		{"no values", []string{"--format", "[{{.Fields.password}}]"}, "[]\n"},
+		{"relative", []string{"--format", "{{relative .CreatedAt}}"}, "2021-03-04\n"},
+		{"columns", []string{"--columns", "id,name,username,tags"}, "a1b2c3d4  github  me  dev git\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			in := parseArgs(t, append([]string{"secret", "list"}, tt.args...)...)
+			lf, err := newListFormat(in, secretColumns, true)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var buf bytes.Buffer
+			if err := printList(&buf, lf, secrets); err != nil {
+				t.Fatal(err)
+			}
+			if buf.String() != tt.want {
+				t.Errorf("got %q, want %q", buf.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestListFormatErrors(t *testing.T) {
+	tests := [][]string{
+		{"--format", "{{.Name"},
+		{"--format", "{{.Name}}", "--columns", "name"},
+		{"--columns", "name,bogus"},
+		{"--limit", "-1"},
+	}
+	for _, args := range tests {
+		in := parseArgs(t, append([]string{"task", "list"}, args...)...)
+		if _, err := newListFormat(in, taskColumns, false); err == nil {
+			t.Errorf("%q: want error", args)
+		}
+	}
+
+	in := parseArgs(t, "task", "list")
+	if lf, err := newListFormat(in, taskColumns, false); lf != nil || err != nil {
+		t.Errorf("no flags: got %v, %v", lf, err)
+	}
+}
+
+func TestSortTasks(t *testing.T) {
+	day := func(n int) *time.Time {
+		d := time.Date(2026, 1, n, 0, 0, 0, 0, time.UTC)
+		return &d
+	}
+	tasks := []task.Task{
+		{Title: "b", Priority: task.PriorityLow, DueDate: day(3), CreatedAt: *day(1)},
+		{Title: "A", Priority: task.PriorityNone, CreatedAt: *day(3)},
+		{Title: "c", Priority: task.PriorityHigh, DueDate: day(2), CreatedAt: *day(2)},
+		{Title: "d", Priority: task.PriorityHigh, CreatedAt: *day(1)},
+	}
+	tests := []struct {
+		by   string
+		want []string
+	}{
+		{"name", []string{"A", "b", "c", "d"}},
+		{"created", []string{"A", "c", "b", "d"}},
+		{"due", []string{"c", "b", "A", "d"}},
+		{"priority", []string{"c", "d", "b", "A"}},
+	}
+	for _, tt := range tests {
+		sortTasks(tasks, tt.by)
+		var got []string
+		for _, tk := range tasks {
+			got = append(got, tk.Title)
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("sort by %s = %q, want %q", tt.by, got, tt.want)
+		}
+	}
+}
+
+func TestSortSecrets(t *testing.T) {
+	day := func(n int) time.Time { return time.Date(2026, 1, n, 0, 0, 0, 0, time.UTC) }
+	secrets := []secret.Secret{
+		{Name: "mail", Type: secret.TypePassword, CreatedAt: day(1), UpdatedAt: day(5)},
+		{Name: "Aws", Type: secret.TypeAPIKey, CreatedAt: day(2), UpdatedAt: day(2)},
+		{Name: "deploy", Type: secret.TypeSSHKey, CreatedAt: day(3), UpdatedAt: day(3)},
+	}
+	tests := []struct {
+		by   string
+		want []string
+	}{
+		{"name", []string{"Aws", "deploy", "mail"}},
+		{"updated", []string{"mail", "deploy", "Aws"}},
+		{"created", []string{"deploy", "Aws", "mail"}},
+		{"type", []string{"Aws", "mail", "deploy"}},
+	}
+	for _, tt := range tests {
+		sortSecrets(secrets, tt.by)
+		var got []string
+		for _, sec := range secrets {
+			got = append(got, sec.Name)
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("sort by %s = %q, want %q", tt.by, got, tt.want)
+		}
+	}
+}
+
+func TestArrange(t *testing.T) {
+	in := parseArgs(t, "task", "list", "--reverse", "--limit", "2")
+	if got := arrange(in, []int{1, 2, 3}); !slices.Equal(got, []int{3, 2}) {
+		t.Errorf("arrange = %v, want [3 2]", got)
+	}
+}
@@ -0,0 +1,286 @@
+package cli
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/zarlcorp/zvault/internal/secret"
+)
+
+// gitTag marks secrets created by the git credential helper. Only these
+// are updated or erased; hand-made secrets are only ever read.
+const gitTag = "git"
+
+func gitCredentialCommand() *command {
+	return &command{
+		name:    "git-credential",
+		summary: "git credential helper (get, store, erase)",
+		help: `
+Git credential helper. Credentials are read from the url, username and
+password fields of password secrets; logins saved by git are stored as
+password secrets tagged "git".
+
+Setup:
+  git config --global credential.helper "!zvault git-credential"
+
+or, with a git-credential-zvault symlink to zvault on PATH:
+  git config --global credential.helper "zvault git-credential"
+
+The vault password is read from the sources listed by 'zvault --help',
+or prompted on the terminal. Set credential.useHttpPath to keep separate logins per
+repository on the same host.`,
+		// git may add operations in future; helpers must ignore them
+		maxArgs: unlimited,
+		run:     func(*invocation) {},
+		subs: []*command{
+			{name: "get", summary: "print a stored login", run: runGitCredentialGet},
+			{name: "store", summary: "save a login", run: runGitCredentialStore},
+			{name: "erase", summary: "delete a saved login", run: runGitCredentialErase},
+		},
+	}
+}
+
+// gitCredential is a request or response in git's credential protocol.
+type gitCredential struct {
+	Protocol string
+	Host     string
+	Path     string
+	Username string
+	Password string
+}
+
+// parseGitCredential reads key=value lines up to a blank line or EOF.
+func parseGitCredential(r io.Reader) (gitCredential, error) {
+	var c gitCredential
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		line := sc.Text()
+		if line == "" {
+			break
+		}
+		k, v, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		switch k {
+		case "protocol":
+			c.Protocol = v
+		case "host":
+			c.Host = v
+		case "path":
+			c.Path = v
+		case "username":
+			c.Username = v
+		case "password":
+			c.Password = v
+		case "url":
+			u, err := url.Parse(v)
+			if err != nil {
+				return c, fmt.Errorf("invalid url %q: %w", v, err)
+			}
+			c.Protocol, c.Host, c.Path = u.Scheme, u.Host, strings.TrimPrefix(u.Path, "/")
+			if u.User != nil {
+				c.Username = u.User.Username()
+			}
+		}
+	}
+	return c, sc.Err()
+}
+
+// URL returns the credential's location as a url, without userinfo.
+func (c gitCredential) URL() string {
+	u := url.URL{Scheme: c.Protocol, Host: c.Host}
+	if c.Path != "" {
+		u.Path = "/" + c.Path
+	}
+	return u.String()
+}
+
+// matchScore rates how well a secret's url matches the request: 0 means
+// no match, 1 a host match, 2 a host and path match. A secret url without
+// a scheme matches any protocol.
+func (c gitCredential) matchScore(sec secret.Secret) int {
+	if sec.Type != secret.TypePassword || sec.URL() == "" || sec.Password() == "" {
+		return 0
+	}
+
+	raw := sec.URL()
+	if !strings.Contains(raw, "://") {
+		raw = "//" + raw
+	}
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return 0
+	}
+
+	if u.Scheme != "" && !strings.EqualFold(u.Scheme, c.Protocol) {
+		return 0
+	}
+	if !strings.EqualFold(u.Host, c.Host) {
+		return 0
+	}
+	if c.Username != "" && sec.Username() != "" && sec.Username() != c.Username {
+		return 0
+	}
+
+	secPath := cleanGitPath(u.Path)
+	if secPath == "" {
+		return 1
+	}
+	if c.Path == "" || secPath != cleanGitPath(c.Path) {
+		return 0
+	}
+	return 2
+}
+
+func cleanGitPath(p string) string {
+	return strings.TrimSuffix(strings.Trim(p, "/"), ".git")
+}
+
+// findGitCredential returns the best matching secret. Secrets scoped to the
+// request's path win over host-wide ones.
+func findGitCredential(secrets []secret.Secret, c gitCredential) (secret.Secret, bool) {
+	var best secret.Secret
+	bestScore := 0
+	for _, sec := range secrets {
+		if score := c.matchScore(sec); score > bestScore {
+			best, bestScore = sec, score
+		}
+	}
+	return best, bestScore > 0
+}
+
+// findStoredGitCredential returns the git-tagged secret for exactly this
+// location and username.
+func findStoredGitCredential(secrets []secret.Secret, c gitCredential) (secret.Secret, bool) {
+	for _, sec := range secrets {
+		if !containsTag(sec.Tags, gitTag) || sec.Type != secret.TypePassword {
+			continue
+		}
+		if sec.URL() == c.URL() && sec.Username() == c.Username {
+			return sec, true
+		}
+	}
+	return secret.Secret{}, false
+}
+
+func readGitCredential() gitCredential {
+	c, err := parseGitCredential(os.Stdin)
+	if err != nil {
+		errf("%v", err)
+		exit(1)
+	}
+	return c
+}
+
+func runGitCredentialGet(*invocation) {
+	c := readGitCredential()
+	if c.Host == "" {
+		return
+	}
+
+	v := openVault()
+	defer closeVault(v)
+
+	all, err := v.Secrets().List()
+	if err != nil {
+		errf("list secrets: %v", err)
+		exit(1)
+	}
+
+	sec, ok := findGitCredential(all, c)
+	if !ok {
+		return // git falls through to the next helper or prompts
+	}
+
+	username := sec.Username()
+	if username == "" {
+		username = c.Username
+	}
+	if username != "" {
+		fmt.Printf("username=%s\n", username)
+	}
+	fmt.Printf("password=%s\n", sec.Password())
+}
+
+func runGitCredentialStore(*invocation) {
+	c := readGitCredential()
+	if c.Host == "" || c.Password == "" {
+		return
+	}
+
+	v := openVault()
+	defer closeVault(v)
+
+	all, err := v.Secrets().List()
+	if err != nil {
+		errf("list secrets: %v", err)
+		exit(1)
+	}
+
+	if sec, ok := findStoredGitCredential(all, c); ok {
+		if sec.Password() == c.Password {
+			return
+		}
+		sec.Fields["password"] = c.Password
+		if err := v.Secrets().Update(sec); err != nil {
+			errf("update secret: %v", err)
+			exit(1)
+		}
+		return
+	}
+
+	// a matching secret made by hand already answers get
+	if sec, ok := findGitCredential(all, c); ok && sec.Password() == c.Password {
+		return
+	}
+
+	name := c.Host
+	if c.Path != "" {
+		name += "/" + cleanGitPath(c.Path)
+	}
+	sec, err := secret.NewPassword(name, c.URL(), c.Username, c.Password)
+	if err != nil {
+		errf("create secret: %v", err)
+		exit(1)
+	}
+	sec.Tags = []string{gitTag}
+
+	if err := v.Secrets().Add(sec); err != nil {
+		errf("store secret: %v", err)
+		exit(1)
+	}
+}
+
+func runGitCredentialErase(*invocation) {
+	c := readGitCredential()
+	if c.Host == "" {
+		return
+	}
+
+	v := openVault()
+	defer closeVault(v)
+
+	all, err := v.Secrets().List()
+	if err != nil {
+		errf("list secrets: %v", err)
+		exit(1)
+	}
+
+	sec, ok := findStoredGitCredential(all, c)
+	if !ok {
+		return
+	}
+	// git passes the rejected password; keep a login that was changed since
+	if c.Password != "" && sec.Password() != c.Password {
+		return
+	}
+	if err := v.Secrets().Delete(sec.ID); err != nil {
+		errf("delete secret: %v", err)
+		exit(1)
+	}
+}
@@ -0,0 +1,122 @@
+package cli
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/zarlcorp/zvault/internal/secret"
+)
+
+func TestParseGitCredential(t *testing.T) {
+	in := "protocol=https\nhost=github.com\npath=org/repo.git\nusername=alice\npassword=tok=en\n\nignored=1\n"
+	c, err := parseGitCredential(strings.NewReader(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := gitCredential{Protocol: "https", Host: "github.com", Path: "org/repo.git", Username: "alice", Password: "tok=en"}
+	if c != want {
+		t.Errorf("got %+v, want %+v", c, want)
+	}
+	if c.URL() != "https://github.com/org/repo.git" {
+		t.Errorf("URL = %q", c.URL())
+	}
+}
+
+func TestParseGitCredentialURL(t *testing.T) {
+	c, err := parseGitCredential(strings.NewReader("url=https://bob@git.example.com:8443/x\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := gitCredential{Protocol: "https", Host: "git.example.com:8443", Path: "x", Username: "bob"}
+	if c != want {
+		t.Errorf("got %+v, want %+v", c, want)
+	}
+}
+
+func mustPasswordSecret(t *testing.T, name, url, user, pass string) secret.Secret {
+	t.Helper()
+	sec, err := secret.NewPassword(name, url, user, pass)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sec
+}
+
+func TestFindGitCredential(t *testing.T) {
+	host := mustPasswordSecret(t, "github", "https://github.com", "alice", "host-token")
+	repo := mustPasswordSecret(t, "repo", "https://github.com/org/repo", "alice", "repo-token")
+	bare := mustPasswordSecret(t, "gitlab", "gitlab.com", "carol", "gl-token")
+	http := mustPasswordSecret(t, "internal", "http://git.internal", "dave", "int-token")
+	secrets := []secret.Secret{host, repo, bare, http}
+
+	tests := []struct {
+		name string
+		req  gitCredential
+		want string // password, empty for no match
+	}{
+		{"host only", gitCredential{Protocol: "https", Host: "github.com"}, "host-token"},
+		{"path wins", gitCredential{Protocol: "https", Host: "github.com", Path: "org/repo.git"}, "repo-token"},
+		{"other path falls back to host", gitCredential{Protocol: "https", Host: "github.com", Path: "org/other"}, "host-token"},
+		{"host is case-insensitive", gitCredential{Protocol: "https", Host: "GitHub.com"}, "host-token"},
+		{"no scheme matches any protocol", gitCredential{Protocol: "https", Host: "gitlab.com"}, "gl-token"},
+		{"protocol must match", gitCredential{Protocol: "https", Host: "git.internal"}, ""},
+		{"username must match", gitCredential{Protocol: "https", Host: "gitlab.com", Username: "eve"}, ""},
+		{"unknown host", gitCredential{Protocol: "https", Host: "example.com"}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sec, ok := findGitCredential(secrets, tt.req)
+			if tt.want == "" {
+				if ok {
+					t.Fatalf("expected no match, got %q", sec.Name)
+				}
+				return
+			}
+			if !ok {
+				t.Fatal("expected a match")
+			}
+			if sec.Password() != tt.want {
+				t.Errorf("password = %q, want %q", sec.Password(), tt.want)
+			}
+		})
+	}
+}
+
+func TestFindStoredGitCredential(t *testing.T) {
+	req := gitCredential{Protocol: "https", Host: "github.com", Username: "alice"}
+
+	manual := mustPasswordSecret(t, "github", "https://github.com", "alice", "a")
+	stored := mustPasswordSecret(t, "github.com", req.URL(), "alice", "b")
+	stored.Tags = []string{gitTag}
+
+	sec, ok := findStoredGitCredential([]secret.Secret{manual, stored}, req)
+	if !ok || sec.ID != stored.ID {
+		t.Fatal("should find only the git-tagged secret")
+	}
+	if _, ok := findStoredGitCredential([]secret.Secret{manual}, req); ok {
+		t.Fatal("untagged secrets must not be updated or erased")
+	}
+}
+
+func TestMultiCall(t *testing.T) {
+	tests := []struct {
+		argv0 string
+		args  []string
+		want  []string
+		ok    bool
+	}{
+		{"git-credential-zvault", []string{"get"}, []string{"git-credential", "get"}, true},
+		{"git-credential-zvault", []string{"git-credential", "store"}, []string{"git-credential", "store"}, true},
+		{"docker-credential-zvault", []string{"list"}, []string{"docker-credential", "list"}, true},
+		{"zvault", []string{"secret", "list"}, nil, false},
+	}
+
+	for _, tt := range tests {
+		got, ok := MultiCall(tt.argv0, tt.args)
+		if ok != tt.ok || !slices.Equal(got, tt.want) {
+			t.Errorf("MultiCall(%q, %v) = %v, %v; want %v, %v", tt.argv0, tt.args, got, ok, tt.want, tt.ok)
+		}
+	}
+}
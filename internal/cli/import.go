@@ -0,0 +1,303 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/zarlcorp/zvault/internal/archive"
+	"github.com/zarlcorp/zvault/internal/importer"
+	"github.com/zarlcorp/zvault/internal/secret"
+	"github.com/zarlcorp/zvault/internal/sshkey"
+	"github.com/zarlcorp/zvault/internal/task"
+)
+
+// archiveFormat reads zvault's own json export, sealed or not.
+const archiveFormat = "zvault-json"
+
+func importCommand() *command {
+	return &command{
+		name:     "import",
+		summary:  "import from other password managers",
+		args:     "<file>",
+		minArgs:  1,
+		maxArgs:  1,
+		argFiles: true,
+		help: `
+Import secrets from another password manager's export. Use - to read
+from stdin.
+
+Formats:
+  zvault-json      zvault export --format json, encrypted or not
+  bitwarden-json   Bitwarden unencrypted .json export
+  keepass-xml      KeePass 2 / KeePassXC .xml export
+  chrome-csv       Chrome, Edge or Brave passwords .csv
+  firefox-csv      Firefox logins .csv
+  generic-csv      any .csv with a header row (Bitwarden, LastPass,
+                   1Password and similar exports)
+
+Logins become password secrets and secure notes become notes; cards and
+identities become notes tagged "card" or "identity". TOTP seeds, custom
+fields, folders (as tags) and timestamps are kept. Entries with the same
+name and url as an existing secret are skipped.
+
+A zvault-json export brings secrets and tasks across with their ids and
+timestamps; anything whose id is already in the vault is skipped. An
+encrypted export asks for its passphrase.`,
+		flags: []*flag{
+			{name: "from", value: "<format>", usage: "export format (required)", choices: importFormats()},
+			{name: "tags", value: "<a,b>", usage: "add tags to every imported secret", names: secretTags},
+			{name: "dry-run", kind: boolFlag, usage: "show what would be imported without storing anything"},
+		},
+		run: runImport,
+	}
+}
+
+// importFormats lists the formats accepted by import --from.
+func importFormats() []string {
+	return append([]string{archiveFormat}, importer.Formats...)
+}
+
+func runImport(in *invocation) {
+	format := in.value("from")
+	tags := parseTags(in.value("tags"))
+	dryRun := in.has("dry-run")
+	path := in.args[0]
+
+	if format == "" {
+		errfCode(codeUsage, "--from required (%s)", strings.Join(importFormats(), ", "))
+		exit(1)
+	}
+
+	var r io.Reader = os.Stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			errf("%v", err)
+			exit(1)
+		}
+		defer f.Close()
+		r = f
+	}
+
+	if format == archiveFormat {
+		importArchive(r, tags, dryRun)
+		return
+	}
+
+	res, err := importer.Parse(format, r)
+	if err != nil {
+		errf("%v", err)
+		exit(1)
+	}
+
+	v := openVault()
+	defer closeVault(v)
+
+	existing, err := v.Secrets().List()
+	if err != nil {
+		errf("list secrets: %v", err)
+		exit(1)
+	}
+
+	var sum importSummary
+	for _, s := range res.Skipped {
+		fmt.Fprintf(os.Stderr, "%s %s\n", yellow("skip"), s)
+		sum.unsupported++
+	}
+	for _, w := range res.Warnings {
+		fmt.Fprintf(os.Stderr, "%s %s\n", yellow("warn"), w)
+	}
+
+	for _, sec := range res.Secrets {
+		if sec.Type == secret.TypeSSHKey {
+			if err := sshkey.Validate(&sec); err != nil {
+				fmt.Fprintf(os.Stderr, "%s %s (invalid ssh key: %v)\n", yellow("skip"), sec.Name, err)
+				sum.unsupported++
+				continue
+			}
+		}
+		if _, dup := importer.Duplicate(existing, sec); dup {
+			fmt.Fprintf(os.Stderr, "%s %s (already in vault)\n", muted("skip"), sec.Name)
+			sum.duplicates++
+			continue
+		}
+
+		for _, t := range tags {
+			if !containsTag(sec.Tags, t) {
+				sec.Tags = append(sec.Tags, t)
+			}
+		}
+
+		if !dryRun {
+			if err := v.Secrets().Add(sec); err != nil {
+				errf("store secret: %v", err)
+				exit(1)
+			}
+		}
+		existing = append(existing, sec)
+		sum.add(sec)
+
+		fmt.Printf("%s %-10s %s\n", green(sec.ID[:8]), peach(importKind(sec)), bold(sec.Name))
+	}
+
+	fmt.Fprintln(os.Stderr, sum.String(dryRun))
+}
+
+// importArchive imports a zvault json export, keeping ids and timestamps.
+func importArchive(r io.Reader, tags []string, dryRun bool) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		errf("read export: %v", err)
+		exit(1)
+	}
+	if archive.Sealed(data) {
+		data, err = archive.Open(data, promptPassword("export passphrase: "))
+		if err != nil {
+			errf("%v", err)
+			exit(1)
+		}
+	}
+	a, err := archive.Unmarshal(data)
+	if err != nil {
+		errf("%v", err)
+		exit(1)
+	}
+	if !a.IncludesValues && len(a.Secrets) > 0 {
+		errf("export has no secret values; re-export with --include-values")
+		exit(1)
+	}
+
+	v := openVault()
+	defer closeVault(v)
+
+	existing, err := v.Secrets().List()
+	if err != nil {
+		errf("list secrets: %v", err)
+		exit(1)
+	}
+	existingTasks, err := v.Tasks().List(task.Filter{})
+	if err != nil {
+		errf("list tasks: %v", err)
+		exit(1)
+	}
+	ids := make(map[string]bool)
+	for _, sec := range existing {
+		ids[sec.ID] = true
+	}
+	for _, tk := range existingTasks {
+		ids[tk.ID] = true
+	}
+
+	var sum importSummary
+	for _, sec := range a.Secrets {
+		if _, dup := importer.Duplicate(existing, sec); dup || ids[sec.ID] {
+			fmt.Fprintf(os.Stderr, "%s %s (already in vault)\n", muted("skip"), sec.Name)
+			sum.duplicates++
+			continue
+		}
+		if sec.Fields == nil {
+			sec.Fields = make(map[string]string)
+		}
+		for _, t := range tags {
+			if !containsTag(sec.Tags, t) {
+				sec.Tags = append(sec.Tags, t)
+			}
+		}
+
+		if !dryRun {
+			if err := v.Secrets().Add(sec); err != nil {
+				errf("store secret: %v", err)
+				exit(1)
+			}
+		}
+		existing = append(existing, sec)
+		ids[sec.ID] = true
+		sum.add(sec)
+
+		fmt.Printf("%s %-10s %s\n", green(sec.ID[:min(8, len(sec.ID))]), peach(importKind(sec)), bold(sec.Name))
+	}
+
+	for _, tk := range a.Tasks {
+		if ids[tk.ID] {
+			fmt.Fprintf(os.Stderr, "%s %s (already in vault)\n", muted("skip"), tk.Title)
+			sum.duplicates++
+			continue
+		}
+		if !dryRun {
+			if err := v.Tasks().Add(tk); err != nil {
+				errf("store task: %v", err)
+				exit(1)
+			}
+		}
+		ids[tk.ID] = true
+		sum.tasks++
+
+		fmt.Printf("%s %-10s %s\n", green(tk.ID[:min(8, len(tk.ID))]), peach("task"), bold(tk.Title))
+	}
+
+	fmt.Fprintln(os.Stderr, sum.String(dryRun))
+}
+
+// importKind labels a secret in the import listing, calling out the notes
+// that hold cards and identities.
+func importKind(sec secret.Secret) string {
+	if sec.Type == secret.TypeNote {
+		for _, t := range []string{"card", "identity"} {
+			if containsTag(sec.Tags, t) {
+				return t
+			}
+		}
+	}
+	return string(sec.Type)
+}
+
+type importSummary struct {
+	kinds       []string
+	counts      map[string]int
+	totp        int
+	tasks       int
+	duplicates  int
+	unsupported int
+}
+
+func (s *importSummary) add(sec secret.Secret) {
+	if s.counts == nil {
+		s.counts = make(map[string]int)
+	}
+	k := importKind(sec)
+	if s.counts[k] == 0 {
+		s.kinds = append(s.kinds, k)
+	}
+	s.counts[k]++
+	if sec.TOTPSecret() != "" {
+		s.totp++
+	}
+}
+
+func (s importSummary) String(dryRun bool) string {
+	total := 0
+	var parts []string
+	for _, k := range s.kinds {
+		total += s.counts[k]
+		parts = append(parts, fmt.Sprintf("%d %s", s.counts[k], k))
+	}
+
+	verb := "imported"
+	if dryRun {
+		verb = "would import"
+	}
+	out := fmt.Sprintf("%s %d", verb, total)
+	if len(parts) > 0 {
+		out += " (" + strings.Join(parts, ", ") + ")"
+	}
+	if s.totp > 0 {
+		out += fmt.Sprintf(", %d with totp", s.totp)
+	}
+	if s.tasks > 0 {
+		out += fmt.Sprintf(", %d tasks", s.tasks)
+	}
+	out += fmt.Sprintf(", skipped %d duplicates, %d unsupported", s.duplicates, s.unsupported)
+	return out
+}
@@ -0,0 +1,77 @@
+package cli
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/zarlcorp/zvault/internal/importer"
+	"github.com/zarlcorp/zvault/internal/secret"
+)
+
+func TestImportSummary(t *testing.T) {
+	var s importSummary
+	gh := mustPasswordSecret(t, "github", "github.com", "alice", "pw")
+	gh.Fields["totp_secret"] = "JBSWY3DPEHPK3PXP"
+	s.add(gh)
+	s.add(mustPasswordSecret(t, "gitlab", "gitlab.com", "alice", "pw"))
+
+	card, err := secret.NewNote("visa", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	card.Tags = []string{"card"}
+	s.add(card)
+	s.duplicates = 2
+	s.unsupported = 1
+
+	want := "imported 3 (2 password, 1 card), 1 with totp, skipped 2 duplicates, 1 unsupported"
+	if got := s.String(false); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	s.tasks = 4
+	want = "imported 3 (2 password, 1 card), 1 with totp, 4 tasks, skipped 2 duplicates, 1 unsupported"
+	if got := s.String(false); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	var empty importSummary
+	want = "would import 0, skipped 0 duplicates, 0 unsupported"
+	if got := empty.String(true); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestImportedCardDetail(t *testing.T) {
+	t.Setenv("NO_COLOR", "1")
+	in := `{"encrypted": false, "items": [{"id": "i1", "type": 3, "name": "Visa",
+		"card": {"cardholderName": "Alice A", "number": "4111111111111111", "expYear": "2030", "code": "123"}}]}`
+	res, err := importer.Parse(importer.BitwardenJSON, strings.NewReader(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Secrets) != 1 {
+		t.Fatalf("got %d secrets", len(res.Secrets))
+	}
+	card := res.Secrets[0]
+
+	var buf bytes.Buffer
+	printSecretDetail(&buf, card, false)
+	for _, want := range []string{"number: ********", "code: ********", "exp year: ********"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("masked detail lacks %q:\n%s", want, buf.String())
+		}
+	}
+	if strings.Contains(buf.String(), "4111111111111111") {
+		t.Errorf("masked detail shows the card number:\n%s", buf.String())
+	}
+
+	buf.Reset()
+	printSecretDetail(&buf, card, true)
+	for _, want := range []string{"number: 4111111111111111", "code: 123", "cardholder: Alice A"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("detail with --show lacks %q:\n%s", want, buf.String())
+		}
+	}
+}
@@ -0,0 +1,233 @@
+package cli
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/template"
+
+	"github.com/zarlcorp/zvault/internal/secret"
+	"github.com/zarlcorp/zvault/internal/vault"
+)
+
+func injectCommand() *command {
+	return &command{
+		name:    "inject",
+		summary: "render a template with values from the vault",
+		help: `
+Render a Go text/template with values from the vault. Without -i the
+template is read from stdin; without -o it is written to stdout. Output
+files are created with 0600 permissions and replaced atomically.
+
+Functions:
+  {{ secret "name" }}            main value (password, key, private_key, content)
+  {{ secret "name" "field" }}    any field, e.g. "username" or "url"
+  {{ totp "name" }}              current TOTP code
+  {{ tag "prod" }}               secrets with a tag, for range:
+                                 {{ range tag "prod" }}{{ .Name }}={{ .Password }}{{ end }}
+
+--watch-stdin keeps the vault open and renders stdin to stdout as it
+arrives, one line at a time; blocks such as range may span lines.
+
+Example:
+  zvault inject -i npmrc.tmpl -o ~/.npmrc`,
+		flags: []*flag{
+			{name: "input", short: 'i', value: "<template>", usage: "template file (default stdin)", files: true},
+			{name: "output", short: 'o', value: "<file>", usage: "output file (default stdout)", files: true},
+			{name: "watch-stdin", kind: boolFlag, usage: "render stdin to stdout as it arrives"},
+		},
+		run: runInject,
+	}
+}
+
+func runInject(inv *invocation) {
+	in := inv.value("input")
+	out := inv.value("output")
+	watch := inv.has("watch-stdin")
+
+	if watch && (in != "" || out != "") {
+		errfCode(codeUsage, "--watch-stdin reads stdin and writes stdout; drop -i and -o")
+		exit(1)
+	}
+
+	if watch {
+		v := openVault()
+		defer closeVault(v)
+
+		if err := streamTemplates(os.Stdin, os.Stdout, func() template.FuncMap { return vaultTemplateFuncs(v) }); err != nil {
+			errf("%v", err)
+			exit(1)
+		}
+		return
+	}
+
+	var src []byte
+	var err error
+	if in == "" || in == "-" {
+		src, err = io.ReadAll(os.Stdin)
+	} else {
+		src, err = os.ReadFile(in)
+	}
+	if err != nil {
+		errf("read template: %v", err)
+		exit(1)
+	}
+
+	name := in
+	if name == "" {
+		name = "stdin"
+	}
+	// parse before asking for the vault password so typos fail fast
+	if _, err := parseTemplate(name, string(src), templateFuncs(nil, nil)); err != nil {
+		errf("%v", err)
+		exit(1)
+	}
+
+	v := openVault()
+	rendered, err := renderTemplate(name, string(src), vaultTemplateFuncs(v))
+	closeVault(v)
+	if err != nil {
+		errf("%v", err)
+		exit(1)
+	}
+
+	if out == "" || out == "-" {
+		os.Stdout.Write(rendered)
+		return
+	}
+	if err := writePrivateFile(out, rendered); err != nil {
+		errf("write %s: %v", out, err)
+		exit(1)
+	}
+	fmt.Fprintf(os.Stderr, "%s %s\n", green("wrote"), out)
+}
+
+// templateFuncs builds the template functions. With a nil resolver they
+// exist only so templates can be parsed.
+func templateFuncs(r *refResolver, list func() ([]secret.Secret, error)) template.FuncMap {
+	return template.FuncMap{
+		"secret": func(name string, field ...string) (string, error) {
+			if len(field) > 1 {
+				return "", fmt.Errorf("secret takes a name and at most one field")
+			}
+			f := ""
+			if len(field) == 1 {
+				f = field[0]
+			}
+			return r.Field(name, f)
+		},
+		"totp": func(name string) (string, error) {
+			return r.Field(name, "totp")
+		},
+		"tag": func(tag string) ([]secret.Secret, error) {
+			all, err := list()
+			if err != nil {
+				return nil, err
+			}
+			var tagged []secret.Secret
+			for _, sec := range all {
+				if containsTag(sec.Tags, tag) {
+					tagged = append(tagged, sec)
+				}
+			}
+			slices.SortFunc(tagged, func(a, b secret.Secret) int {
+				return strings.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name))
+			})
+			return tagged, nil
+		},
+	}
+}
+
+// vaultTemplateFuncs backs the template functions with the vault's
+// secret store. Each call gets a fresh lookup cache.
+func vaultTemplateFuncs(v *vault.Vault) template.FuncMap {
+	r := newRefResolver(func(name string) (secret.Secret, error) {
+		return resolveSecret(v, name)
+	})
+	return templateFuncs(r, v.Secrets().List)
+}
+
+func parseTemplate(name, text string, funcs template.FuncMap) (*template.Template, error) {
+	return template.New(filepath.Base(name)).Funcs(funcs).Option("missingkey=error").Parse(text)
+}
+
+func renderTemplate(name, text string, funcs template.FuncMap) ([]byte, error) {
+	t, err := parseTemplate(name, text, funcs)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, nil); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// streamTemplates renders r to w line by line. Lines are accumulated while
+// the template is incomplete (an open action or block), so a range over
+// several lines is rendered once its end arrives.
+func streamTemplates(r io.Reader, w io.Writer, funcs func() template.FuncMap) error {
+	br := bufio.NewReader(r)
+	var pending strings.Builder
+	for {
+		line, readErr := br.ReadString('\n')
+		pending.WriteString(line)
+
+		if pending.Len() > 0 {
+			out, err := renderTemplate("stdin", pending.String(), funcs())
+			switch {
+			case err == nil:
+				if _, err := w.Write(out); err != nil {
+					return err
+				}
+				pending.Reset()
+			case readErr == nil && incompleteTemplate(err):
+				// wait for more input
+			default:
+				return err
+			}
+		}
+
+		if readErr == io.EOF {
+			return nil
+		}
+		if readErr != nil {
+			return readErr
+		}
+	}
+}
+
+func incompleteTemplate(err error) bool {
+	msg := err.Error()
+	return strings.Contains(msg, "unexpected EOF") || strings.Contains(msg, "unclosed action")
+}
+
+// writePrivateFile replaces path with data, readable only by the owner.
+// The data goes to a temporary file in the same directory first, so a
+// failed write never leaves a half-written config behind.
+func writePrivateFile(path string, data []byte) error {
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	defer os.Remove(tmp) // no-op after a successful rename
+
+	if err := f.Chmod(0o600); err != nil {
+		f.Close()
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
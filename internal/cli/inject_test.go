@@ -0,0 +1,122 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"text/template"
+
+	"github.com/zarlcorp/zvault/internal/secret"
+)
+
+func testTemplateFuncs(t *testing.T) func() template.FuncMap {
+	t.Helper()
+
+	npm := mustPasswordSecret(t, "npm", "https://registry.npmjs.org", "alice", "npm_token")
+	npm.Fields["totp_secret"] = "JBSWY3DPEHPK3PXP"
+	db := mustPasswordSecret(t, "db", "postgres://db:5432", "app", "pg_pass")
+	db.Tags = []string{"prod"}
+	api, err := secret.NewAPIKey("stripe", "stripe", "sk_live")
+	if err != nil {
+		t.Fatal(err)
+	}
+	api.Tags = []string{"prod"}
+	all := []secret.Secret{npm, db, api}
+
+	return func() template.FuncMap {
+		r := newRefResolver(func(name string) (secret.Secret, error) {
+			for _, s := range all {
+				if s.Name == name {
+					return s, nil
+				}
+			}
+			return secret.Secret{}, fmt.Errorf("secret %q not found", name)
+		})
+		return templateFuncs(r, func() ([]secret.Secret, error) { return all, nil })
+	}
+}
+
+func TestRenderTemplate(t *testing.T) {
+	funcs := testTemplateFuncs(t)
+
+	tests := []struct {
+		name, tmpl, want string
+	}{
+		{"main value", `//registry.npmjs.org/:_authToken={{ secret "npm" }}`, "//registry.npmjs.org/:_authToken=npm_token"},
+		{"field", `user: {{ secret "db" "username" }}`, "user: app"},
+		{"tag", `{{ range tag "prod" }}{{ .Name }}={{ secret .Name }};{{ end }}`, "db=pg_pass;stripe=sk_live;"},
+		{"methods", `{{ range tag "prod" }}{{ .Password }}{{ end }}`, "pg_pass"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := renderTemplate("test", tt.tmpl, funcs())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	code, err := renderTemplate("test", `{{ totp "npm" }}`, funcs())
+	if err != nil || len(code) != 6 {
+		t.Errorf("totp = %q, %v", code, err)
+	}
+
+	for _, tmpl := range []string{`{{ secret "missing" }}`, `{{ secret "db" "nope" }}`, `{{ secret "db" "a" "b" }}`, `{{ totp "db" }}`} {
+		if _, err := renderTemplate("test", tmpl, funcs()); err == nil {
+			t.Errorf("%s: expected error", tmpl)
+		}
+	}
+}
+
+func TestStreamTemplates(t *testing.T) {
+	in := "host: db\n{{ range tag \"prod\" }}\n- {{ .Name }}\n{{ end }}\npass: {{ secret \"db\" }}\n"
+	var out strings.Builder
+	if err := streamTemplates(strings.NewReader(in), &out, testTemplateFuncs(t)); err != nil {
+		t.Fatal(err)
+	}
+	want := "host: db\n\n- db\n\n- stripe\n\npass: pg_pass\n"
+	if out.String() != want {
+		t.Errorf("got %q, want %q", out.String(), want)
+	}
+
+	if err := streamTemplates(strings.NewReader("{{ range tag \"prod\" }}\n"), &out, testTemplateFuncs(t)); err == nil {
+		t.Error("expected error for a block left open at EOF")
+	}
+}
+
+func TestWritePrivateFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("old"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := writePrivateFile(path, []byte("new")); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "new" {
+		t.Errorf("content = %q, want %q", data, "new")
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0o600 {
+		t.Errorf("mode = %o, want 600", perm)
+	}
+
+	entries, _ := os.ReadDir(filepath.Dir(path))
+	if len(entries) != 1 {
+		t.Errorf("temporary file left behind: %d entries", len(entries))
+	}
+}
@@ -0,0 +1,288 @@
+package cli
+
+import (
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/zarlcorp/zvault/internal/secret"
+)
+
+// Kubernetes Secret types zvault can build.
+const (
+	k8sOpaque           = "Opaque"
+	k8sDockerConfigJSON = "kubernetes.io/dockerconfigjson"
+	k8sTLS              = "kubernetes.io/tls"
+)
+
+var (
+	// k8sNamePattern is a DNS subdomain name, as required for object names.
+	k8sNamePattern = regexp.MustCompile(`^[a-z0-9]([-a-z0-9.]*[a-z0-9])?$`)
+	// k8sLabelPattern is a DNS label, as required for namespaces.
+	k8sLabelPattern = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`)
+	// k8sKeyPattern is a valid key of a Secret's data.
+	k8sKeyPattern = regexp.MustCompile(`^[-._a-zA-Z0-9]+$`)
+)
+
+func k8sCommand() *command {
+	return &command{
+		name:    "k8s",
+		summary: "print Kubernetes Secret manifests",
+		subs: []*command{
+			{
+				name:    "secret",
+				summary: "print a Secret built from the vault",
+				args:    "<k8s-name>",
+				minArgs: 1,
+				maxArgs: 1,
+				help: `
+Print a Kubernetes Secret manifest built from the vault, ready for
+kubectl apply -f -.
+
+Sources:
+  KEY=zvault://name/field   one key from a field
+  KEY=name                  one key from the secret's main value
+  zvault://name/field       key named after the field
+  name                      every field of the secret (every variable of
+                            an env secret)
+
+For dockerconfigjson, each source is a password secret whose url field
+is the registry. For tls, the sources must provide tls.crt and tls.key.
+
+Example:
+  zvault k8s secret api-db --from DATABASE_URL=zvault://db/url \
+    --namespace prod | kubectl apply -f -`,
+				flags: []*flag{
+					{name: "from", kind: stringsFlag, value: "<source>", usage: "where values come from"},
+					{name: "namespace", value: "<ns>", usage: "metadata.namespace"},
+					{name: "type", usage: "Opaque (default), kubernetes.io/dockerconfigjson or kubernetes.io/tls",
+						suggest: []string{k8sOpaque, k8sDockerConfigJSON, k8sTLS}},
+				},
+				run: runK8sSecret,
+			},
+		},
+	}
+}
+
+func runK8sSecret(in *invocation) {
+	namespace := in.value("namespace")
+	typ := in.value("type")
+	froms := in.values("from")
+	name := in.args[0]
+	if !k8sNamePattern.MatchString(name) || len(name) > 253 {
+		errfCode(codeUsage, "invalid kubernetes name %q (lowercase letters, digits, - and .)", name)
+		exit(1)
+	}
+	if namespace != "" && (!k8sLabelPattern.MatchString(namespace) || len(namespace) > 63) {
+		errfCode(codeUsage, "invalid namespace %q (lowercase letters, digits and -, at most 63)", namespace)
+		exit(1)
+	}
+
+	typ, err := parseK8sType(typ)
+	if err != nil {
+		errf("%v", err)
+		exit(1)
+	}
+	if len(froms) == 0 {
+		errfCode(codeUsage, "at least one --from is required")
+		exit(1)
+	}
+
+	v := openVault()
+	r := newRefResolver(func(name string) (secret.Secret, error) {
+		return resolveSecret(v, name)
+	})
+	data, err := k8sSecretData(typ, froms, r)
+	closeVault(v)
+	if err != nil {
+		errf("%v", err)
+		exit(1)
+	}
+
+	fmt.Print(formatK8sSecret(name, namespace, typ, data))
+}
+
+func parseK8sType(s string) (string, error) {
+	switch s {
+	case "", k8sOpaque, "opaque":
+		return k8sOpaque, nil
+	case k8sDockerConfigJSON, "dockerconfigjson":
+		return k8sDockerConfigJSON, nil
+	case k8sTLS, "tls":
+		return k8sTLS, nil
+	}
+	return "", fmt.Errorf("unknown secret type %q (use %s, %s or %s)", s, k8sOpaque, k8sDockerConfigJSON, k8sTLS)
+}
+
+// k8sSecretData resolves the --from sources into the Secret's data.
+func k8sSecretData(typ string, froms []string, r *refResolver) (map[string][]byte, error) {
+	if typ == k8sDockerConfigJSON {
+		return k8sDockerConfig(froms, r)
+	}
+
+	data := make(map[string][]byte)
+	for _, from := range froms {
+		vals, err := k8sSourceValues(from, r)
+		if err != nil {
+			return nil, err
+		}
+		for k, val := range vals {
+			if !k8sKeyPattern.MatchString(k) {
+				return nil, fmt.Errorf("--from %s: %q is not a valid key (letters, digits, -, _ and .)", from, k)
+			}
+			data[k] = []byte(val)
+		}
+	}
+
+	if typ == k8sTLS {
+		crt, key := data["tls.crt"], data["tls.key"]
+		if crt == nil || key == nil {
+			return nil, fmt.Errorf("tls secrets need tls.crt and tls.key (--from tls.crt=zvault://site/cert --from tls.key=zvault://site/key)")
+		}
+		if _, err := tls.X509KeyPair(crt, key); err != nil {
+			return nil, fmt.Errorf("tls.crt and tls.key: %w", err)
+		}
+	}
+	return data, nil
+}
+
+// k8sSourceValues resolves one --from source into keys and values.
+func k8sSourceValues(from string, r *refResolver) (map[string]string, error) {
+	key, target, explicit := strings.Cut(from, "=")
+	if !explicit || isSecretRef(key) {
+		key, target = "", from
+	}
+
+	name, field := target, ""
+	if isSecretRef(target) {
+		ref, err := parseSecretRef(target)
+		if err != nil {
+			return nil, err
+		}
+		name, field = ref.Name, ref.Field
+	}
+
+	if key != "" {
+		val, err := r.Field(name, field)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]string{key: val}, nil
+	}
+
+	if field != "" {
+		val, err := r.Field(name, field)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]string{field: val}, nil
+	}
+
+	// a whole secret
+	sec, err := r.Secret(name)
+	if err != nil {
+		return nil, err
+	}
+	vals := make(map[string]string)
+	if sec.Type == secret.TypeNote && containsTag(sec.Tags, envTag) {
+		entries, err := envEntries(sec)
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range entries {
+			vals[e.Key] = e.Value
+		}
+		return vals, nil
+	}
+	for k, val := range sec.Fields {
+		if val != "" {
+			vals[k] = val
+		}
+	}
+	return vals, nil
+}
+
+// k8sDockerConfig builds a .dockerconfigjson from password secrets.
+func k8sDockerConfig(froms []string, r *refResolver) (map[string][]byte, error) {
+	type auth struct {
+		Username string `json:"username"`
+		Password string `json:"password"`
+		Auth     string `json:"auth"`
+	}
+	auths := make(map[string]auth)
+
+	for _, from := range froms {
+		name := from
+		if isSecretRef(from) {
+			ref, err := parseSecretRef(from)
+			if err != nil {
+				return nil, err
+			}
+			name = ref.Name
+		}
+		sec, err := r.Secret(name)
+		if err != nil {
+			return nil, err
+		}
+		if sec.Type != secret.TypePassword {
+			return nil, fmt.Errorf("secret %q is a %s; dockerconfigjson needs password secrets", sec.Name, sec.Type)
+		}
+		server := strings.TrimSpace(sec.URL())
+		if server == "" {
+			return nil, fmt.Errorf("secret %q has no url (the registry server)", sec.Name)
+		}
+		auths[server] = auth{
+			Username: sec.Username(),
+			Password: sec.Password(),
+			Auth:     base64.StdEncoding.EncodeToString([]byte(sec.Username() + ":" + sec.Password())),
+		}
+	}
+
+	b, err := json.Marshal(map[string]any{"auths": auths})
+	if err != nil {
+		return nil, err
+	}
+	return map[string][]byte{".dockerconfigjson": b}, nil
+}
+
+// formatK8sSecret renders the manifest as YAML.
+func formatK8sSecret(name, namespace, typ string, data map[string][]byte) string {
+	var b strings.Builder
+	b.WriteString("apiVersion: v1\n")
+	b.WriteString("kind: Secret\n")
+	b.WriteString("metadata:\n")
+	fmt.Fprintf(&b, "  name: %s\n", yamlScalar(name))
+	if namespace != "" {
+		fmt.Fprintf(&b, "  namespace: %s\n", yamlScalar(namespace))
+	}
+	fmt.Fprintf(&b, "type: %s\n", typ)
+	b.WriteString("data:\n")
+
+	keys := make([]string, 0, len(data))
+	for k := range data {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	for _, k := range keys {
+		fmt.Fprintf(&b, "  %s: %s\n", yamlScalar(k), base64.StdEncoding.EncodeToString(data[k]))
+	}
+	return b.String()
+}
+
+// yamlScalar quotes names and keys YAML would otherwise read as numbers,
+// booleans or null. Everything else passed here is already restricted to
+// letters, digits, -, _ and .
+func yamlScalar(s string) string {
+	switch strings.ToLower(s) {
+	case "y", "yes", "n", "no", "true", "false", "on", "off", "null", "~", ".inf", ".nan":
+		return `"` + s + `"`
+	}
+	if s[0] >= '0' && s[0] <= '9' || s[0] == '-' || s[0] == '.' && len(s) > 1 && s[1] >= '0' && s[1] <= '9' {
+		return `"` + s + `"`
+	}
+	return s
+}
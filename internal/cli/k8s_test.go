@@ -0,0 +1,191 @@
+package cli
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zarlcorp/zvault/internal/secret"
+)
+
+func testK8sResolver(t *testing.T, secrets ...secret.Secret) *refResolver {
+	t.Helper()
+	return newRefResolver(func(name string) (secret.Secret, error) {
+		for _, s := range secrets {
+			if s.Name == name {
+				return s, nil
+			}
+		}
+		return secret.Secret{}, fmt.Errorf("secret %q not found", name)
+	})
+}
+
+func TestK8sSecretDataOpaque(t *testing.T) {
+	db := mustPasswordSecret(t, "db", "postgres://db:5432/app", "app", "pg_pass")
+	env := mustEnvNote(t, "billing", "STRIPE_KEY=sk_live\nPORT=8080\n")
+	r := testK8sResolver(t, db, env)
+
+	data, err := k8sSecretData(k8sOpaque, []string{
+		"DB_PASSWORD=zvault://db/password",
+		"DB_MAIN=db",
+		"zvault://db/username",
+		"billing",
+	}, r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]string{
+		"DB_PASSWORD": "pg_pass",
+		"DB_MAIN":     "pg_pass",
+		"username":    "app",
+		"STRIPE_KEY":  "sk_live",
+		"PORT":        "8080",
+	}
+	if len(data) != len(want) {
+		t.Errorf("got %d keys, want %d: %q", len(data), len(want), data)
+	}
+	for k, v := range want {
+		if string(data[k]) != v {
+			t.Errorf("%s = %q, want %q", k, data[k], v)
+		}
+	}
+
+	if _, err := k8sSecretData(k8sOpaque, []string{"bad key=db"}, r); err == nil {
+		t.Error("expected error for an invalid key")
+	}
+	if _, err := k8sSecretData(k8sOpaque, []string{"zvault://db/nope"}, r); err == nil {
+		t.Error("expected error for a missing field")
+	}
+}
+
+func TestK8sSecretDataDockerConfig(t *testing.T) {
+	ghcr := mustPasswordSecret(t, "ghcr", "ghcr.io", "alice", "ghp_token")
+	r := testK8sResolver(t, ghcr)
+
+	data, err := k8sSecretData(k8sDockerConfigJSON, []string{"ghcr"}, r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	auth := base64.StdEncoding.EncodeToString([]byte("alice:ghp_token"))
+	want := `{"auths":{"ghcr.io":{"username":"alice","password":"ghp_token","auth":"` + auth + `"}}}`
+	if got := string(data[".dockerconfigjson"]); got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+
+	api, err := secret.NewAPIKey("stripe", "stripe", "sk")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r = testK8sResolver(t, api)
+	if _, err := k8sSecretData(k8sDockerConfigJSON, []string{"stripe"}, r); err == nil {
+		t.Error("expected error for a non-password secret")
+	}
+}
+
+func TestK8sSecretDataTLS(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "example.com"},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	crtPEM := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+	keyPEM := string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}))
+
+	site, err := secret.NewNote("site", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	site.Fields["cert"] = crtPEM
+	site.Fields["key"] = keyPEM
+	r := testK8sResolver(t, site)
+
+	froms := []string{"tls.crt=zvault://site/cert", "tls.key=zvault://site/key"}
+	if _, err := k8sSecretData(k8sTLS, froms, r); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := k8sSecretData(k8sTLS, froms[:1], r); err == nil {
+		t.Error("expected error without tls.key")
+	}
+	if _, err := k8sSecretData(k8sTLS, []string{"tls.crt=zvault://site/cert", "tls.key=zvault://site/cert"}, r); err == nil {
+		t.Error("expected error for a mismatched pair")
+	}
+}
+
+func TestFormatK8sSecret(t *testing.T) {
+	got := formatK8sSecret("api", "prod", k8sOpaque, map[string][]byte{
+		"b":   []byte("two"),
+		"a":   []byte("one"),
+		"yes": []byte("three"),
+	})
+	want := strings.Join([]string{
+		"apiVersion: v1",
+		"kind: Secret",
+		"metadata:",
+		"  name: api",
+		"  namespace: prod",
+		"type: Opaque",
+		"data:",
+		"  a: b25l",
+		"  b: dHdv",
+		`  "yes": dGhyZWU=`,
+		"",
+	}, "\n")
+	if got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestParseK8sType(t *testing.T) {
+	for in, want := range map[string]string{
+		"":                 k8sOpaque,
+		"tls":              k8sTLS,
+		"dockerconfigjson": k8sDockerConfigJSON,
+		k8sTLS:             k8sTLS,
+	} {
+		got, err := parseK8sType(in)
+		if err != nil || got != want {
+			t.Errorf("parseK8sType(%q) = %q, %v", in, got, err)
+		}
+	}
+	if _, err := parseK8sType("kubernetes.io/basic-auth"); err == nil {
+		t.Error("expected error for an unsupported type")
+	}
+}
+
+func TestK8sNamespacePattern(t *testing.T) {
+	for ns, want := range map[string]bool{
+		"prod":         true,
+		"team-a":       true,
+		"a.b":          false,
+		"-prod":        false,
+		"Prod":         false,
+		"kube-system1": true,
+	} {
+		if got := k8sLabelPattern.MatchString(ns); got != want {
+			t.Errorf("namespace %q valid = %v, want %v", ns, got, want)
+		}
+	}
+}
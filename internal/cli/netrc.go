@@ -0,0 +1,216 @@
+package cli
+
+import (
+	"cmp"
+	"fmt"
+	"net/url"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strings"
+	"syscall"
+
+	"github.com/zarlcorp/zvault/internal/secret"
+)
+
+// netrcEntry is one machine line of a netrc file.
+type netrcEntry struct {
+	Machine  string
+	Login    string
+	Password string
+}
+
+func netrcCommand() *command {
+	return &command{
+		name:    "netrc",
+		summary: "print or serve netrc credentials",
+		help: `
+Print netrc entries for password secrets, with the machine taken from
+the host in each secret's url.
+
+With --fifo nothing is written to disk: the first program to read the
+pipe (curl, pip, go) gets the entries and zvault exits. Run in the
+background, zvault can't prompt for the vault password, so pass it with
+--password-fd or ZVAULT_PASSWORD_FILE, and wait for the pipe to appear
+before starting the client.
+
+Example:
+  zvault netrc --fifo --password-fd 3 3< <(pass show zvault) &
+  until [ -p ~/.netrc ]; do sleep 0.1; done
+  curl --netrc https://api.example.com`,
+		flags: []*flag{
+			{name: "tag", usage: "only secrets with this tag", names: secretTags},
+			{name: "output", value: "<file>", usage: "write to a file (0600) instead of stdout", files: true},
+			{name: "fifo", kind: boolFlag, usage: "serve the entries once through a named pipe at --output (default ~/.netrc), then remove it"},
+		},
+		run: runNetrc,
+	}
+}
+
+func runNetrc(in *invocation) {
+	tag := in.value("tag")
+	output := in.value("output")
+	fifo := in.has("fifo")
+
+	if fifo && output == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			errf("%v", err)
+			exit(1)
+		}
+		output = filepath.Join(home, ".netrc")
+	}
+
+	v := openVault()
+	all, err := v.Secrets().List()
+	closeVault(v)
+	if err != nil {
+		errf("list secrets: %v", err)
+		exit(1)
+	}
+
+	entries := netrcEntries(all, tag)
+	if len(entries) == 0 {
+		errf("no password secrets with a url and password")
+		exit(1)
+	}
+	content := []byte(formatNetrc(entries))
+
+	switch {
+	case fifo:
+		if err := serveFifo(output, content); err != nil {
+			errf("%v", err)
+			exit(1)
+		}
+	case output != "" && output != "-":
+		if err := writePrivateFile(output, content); err != nil {
+			errf("write %s: %v", output, err)
+			exit(1)
+		}
+		fmt.Fprintf(os.Stderr, "%s %s (%d machines)\n", green("wrote"), output, len(entries))
+	default:
+		os.Stdout.Write(content)
+	}
+}
+
+// netrcEntries builds entries from password secrets with a url host and a
+// password, sorted by machine then name.
+func netrcEntries(all []secret.Secret, tag string) []netrcEntry {
+	var secs []secret.Secret
+	for _, sec := range all {
+		if sec.Type != secret.TypePassword || sec.Password() == "" {
+			continue
+		}
+		if tag != "" && !containsTag(sec.Tags, tag) {
+			continue
+		}
+		secs = append(secs, sec)
+	}
+	slices.SortFunc(secs, func(a, b secret.Secret) int {
+		return strings.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name))
+	})
+
+	var entries []netrcEntry
+	for _, sec := range secs {
+		host := netrcMachine(sec.URL())
+		if host == "" {
+			continue
+		}
+		e := netrcEntry{Machine: host, Login: sec.Username(), Password: sec.Password()}
+		// the first of several logins for a host wins, as in netrc itself
+		if slices.ContainsFunc(entries, func(o netrcEntry) bool {
+			return o.Machine == e.Machine && o.Login == e.Login
+		}) {
+			continue
+		}
+		entries = append(entries, e)
+	}
+	slices.SortStableFunc(entries, func(a, b netrcEntry) int {
+		return cmp.Compare(a.Machine, b.Machine)
+	})
+	return entries
+}
+
+// netrcMachine returns the lowercase host name of a url, without port.
+func netrcMachine(raw string) string {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return ""
+	}
+	if !strings.Contains(raw, "://") {
+		raw = "//" + raw
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(u.Hostname())
+}
+
+func formatNetrc(entries []netrcEntry) string {
+	var b strings.Builder
+	for _, e := range entries {
+		fmt.Fprintf(&b, "machine %s", e.Machine)
+		if e.Login != "" {
+			fmt.Fprintf(&b, " login %s", netrcQuote(e.Login))
+		}
+		fmt.Fprintf(&b, " password %s\n", netrcQuote(e.Password))
+	}
+	return b.String()
+}
+
+// netrcQuote double-quotes tokens containing whitespace or quotes, as
+// curl and Python's netrc understand.
+func netrcQuote(s string) string {
+	if !strings.ContainsAny(s, " \t\n\"\\") {
+		return s
+	}
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\t", `\t`)
+	return `"` + r.Replace(s) + `"`
+}
+
+// serveFifo creates a named pipe at path, writes content to the first
+// reader and removes the pipe again.
+func serveFifo(path string, content []byte) error {
+	if fi, err := os.Lstat(path); err == nil {
+		if fi.Mode()&os.ModeNamedPipe == 0 {
+			return fmt.Errorf("%s exists and is not a named pipe; move it away first", path)
+		}
+		// a pipe left behind by an earlier run
+		if err := os.Remove(path); err != nil {
+			return err
+		}
+	}
+
+	if err := makeFifo(path); err != nil {
+		return fmt.Errorf("create named pipe: %w", err)
+	}
+	defer os.Remove(path)
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		if _, ok := <-sigs; ok {
+			os.Remove(path)
+			exit(130)
+		}
+	}()
+	defer func() {
+		signal.Stop(sigs)
+		close(sigs)
+	}()
+
+	fmt.Fprintf(os.Stderr, "%s %s %s\n", green("serving"), path, muted("(waiting for one reader)"))
+
+	// opening for write blocks until a reader opens the pipe
+	f, err := os.OpenFile(path, os.O_WRONLY, 0)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(content); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,109 @@
+package cli
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/zarlcorp/zvault/internal/secret"
+)
+
+func TestNetrcEntries(t *testing.T) {
+	gh := mustPasswordSecret(t, "github", "https://GitHub.com/org", "alice", "gh-token")
+	gh.Tags = []string{"work"}
+	gh2 := mustPasswordSecret(t, "github-old", "github.com", "alice", "stale")
+	pypi := mustPasswordSecret(t, "pypi", "upload.pypi.org:443", "__token__", "pypi-token")
+	nopass := mustPasswordSecret(t, "empty", "https://example.com", "bob", "")
+	nourl := mustPasswordSecret(t, "bank", "", "carol", "pw")
+	api, err := secret.NewAPIKey("stripe", "stripe", "sk")
+	if err != nil {
+		t.Fatal(err)
+	}
+	all := []secret.Secret{pypi, gh2, nopass, nourl, api, gh}
+
+	got := netrcEntries(all, "")
+	want := []netrcEntry{
+		{Machine: "github.com", Login: "alice", Password: "gh-token"},
+		{Machine: "upload.pypi.org", Login: "__token__", Password: "pypi-token"},
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+
+	got = netrcEntries(all, "work")
+	if len(got) != 1 || got[0].Machine != "github.com" {
+		t.Errorf("tag filter: got %+v", got)
+	}
+}
+
+func TestFormatNetrc(t *testing.T) {
+	got := formatNetrc([]netrcEntry{
+		{Machine: "a.example", Login: "me", Password: "simple"},
+		{Machine: "b.example", Password: `has space "and" quote`},
+	})
+	want := "machine a.example login me password simple\n" +
+		`machine b.example password "has space \"and\" quote"` + "\n"
+	if got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestServeFifo(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("named pipes are not supported on windows")
+	}
+	path := filepath.Join(t.TempDir(), ".netrc")
+
+	done := make(chan error, 1)
+	go func() { done <- serveFifo(path, []byte("machine x password y\n")) }()
+
+	// wait for the pipe to appear
+	var fi os.FileInfo
+	for range 100 {
+		var err error
+		if fi, err = os.Lstat(path); err == nil {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if fi == nil || fi.Mode()&os.ModeNamedPipe == 0 {
+		t.Fatal("named pipe not created")
+	}
+	if fi.Mode().Perm() != 0o600 {
+		t.Errorf("mode = %v, want 0600", fi.Mode().Perm())
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := io.ReadAll(f)
+	f.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "machine x password y\n" {
+		t.Errorf("read %q", data)
+	}
+
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Lstat(path); !os.IsNotExist(err) {
+		t.Error("named pipe not removed after serving")
+	}
+}
+
+func TestServeFifoRefusesRegularFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".netrc")
+	if err := os.WriteFile(path, []byte("machine x\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := serveFifo(path, nil); err == nil {
+		t.Error("expected error for an existing regular file")
+	}
+}
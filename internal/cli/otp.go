@@ -0,0 +1,226 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/zarlcorp/zvault/internal/secret"
+	"github.com/zarlcorp/zvault/internal/totp"
+)
+
+func otpCommand() *command {
+	return &command{
+		name:     "otp",
+		summary:  "print TOTP codes, import authenticator exports",
+		args:     "<id-or-name>",
+		argNames: secretNames,
+		minArgs:  1,
+		maxArgs:  1,
+		help: `
+With a secret instead of a command, print its current TOTP code. With
+--copy the code goes to the clipboard instead, which is cleared after
+--clear-after unless something else has been copied since.`,
+		flags: []*flag{
+			{name: "copy", short: 'c', kind: boolFlag, usage: "copy the code to the clipboard"},
+			clearAfterFlag(),
+		},
+		run: runOTPCode,
+		subs: []*command{
+			{
+				name:    "import-migration",
+				summary: "import a Google Authenticator export",
+				args:    "[<uri>...]",
+				maxArgs: unlimited,
+				// the uris hold every account's totp secret
+				secretArgs: true,
+				help: `
+Import reads otpauth-migration://offline?data=... URIs from the arguments,
+or one per line from stdin. Each account becomes a password secret with
+its totp_secret and parameters. Accounts already in the vault (same issuer
+and account name) are skipped.`,
+				run: runOTPImportMigration,
+			},
+		},
+	}
+}
+
+func runOTPCode(in *invocation) {
+	v := openVault()
+	defer closeVault(v)
+
+	sec, err := resolveSecret(v, in.args[0])
+	if err != nil {
+		errf("%v", err)
+		exit(1)
+	}
+	if sec.TOTPSecret() == "" {
+		errf("secret %q has no totp secret", sec.Name)
+		exit(1)
+	}
+
+	p, err := totp.ParseParams(sec.TOTPAlgorithm(), sec.TOTPDigits(), sec.TOTPPeriod())
+	if err != nil {
+		errf("%v", err)
+		exit(1)
+	}
+	code, remaining, err := totp.GenerateWith(sec.TOTPSecret(), p)
+	if err != nil {
+		errf("%v", err)
+		exit(1)
+	}
+
+	if in.has("copy") {
+		after := clearAfter(in)
+		if err := copyToClipboard(code, after); err != nil {
+			errf("%v", err)
+			exit(1)
+		}
+		if jsonOutput {
+			writeJSON(otpOutput{ID: sec.ID, Name: sec.Name, Code: code, ExpiresIn: remaining})
+			return
+		}
+		fmt.Fprintf(os.Stderr, "%s %s\n", green("copied"), copiedNote(sec.Name+" code", after))
+		fmt.Fprintln(os.Stderr, muted(fmt.Sprintf("expires in %ds", remaining)))
+		return
+	}
+
+	if jsonOutput {
+		writeJSON(otpOutput{ID: sec.ID, Name: sec.Name, Code: code, ExpiresIn: remaining})
+		return
+	}
+	fmt.Println(code)
+	fmt.Fprintln(os.Stderr, muted(fmt.Sprintf("expires in %ds", remaining)))
+}
+
+func runOTPImportMigration(in *invocation) {
+	uris := in.args
+	if len(uris) == 0 {
+		in, piped := readStdin()
+		if !piped {
+			errfCode(codeUsage, "migration uri required (argument or stdin)")
+			exit(1)
+		}
+		uris = strings.Fields(in)
+	}
+
+	var accounts []totp.Account
+	for _, uri := range uris {
+		batch, err := totp.ParseMigration(uri)
+		if err != nil {
+			errf("%v", err)
+			exit(1)
+		}
+		accounts = append(accounts, batch...)
+	}
+
+	v := openVault()
+	defer closeVault(v)
+
+	existing, err := v.Secrets().List()
+	if err != nil {
+		errf("list secrets: %v", err)
+		exit(1)
+	}
+
+	imported, skipped := 0, 0
+	for _, acc := range accounts {
+		label := otpLabel(acc)
+
+		if acc.HOTP {
+			fmt.Fprintf(os.Stderr, "%s %s (counter-based codes are not supported)\n", yellow("skip"), label)
+			skipped++
+			continue
+		}
+		if hasOTPAccount(existing, acc) {
+			fmt.Fprintf(os.Stderr, "%s %s (already in vault)\n", muted("skip"), label)
+			skipped++
+			continue
+		}
+
+		sec, err := secretFromOTPAccount(acc, existing)
+		if err != nil {
+			errf("create secret: %v", err)
+			exit(1)
+		}
+		if err := v.Secrets().Add(sec); err != nil {
+			errf("store secret: %v", err)
+			exit(1)
+		}
+		existing = append(existing, sec)
+		imported++
+
+		fmt.Printf("%s %s\n", green(sec.ID), bold(sec.Name))
+	}
+
+	fmt.Fprintf(os.Stderr, "imported %d, skipped %d\n", imported, skipped)
+}
+
+// secretFromOTPAccount builds a password secret named after the issuer.
+// When another secret already uses that name, the account is appended.
+func secretFromOTPAccount(acc totp.Account, existing []secret.Secret) (secret.Secret, error) {
+	name := acc.Issuer
+	if name == "" {
+		name = acc.Name
+	}
+	for _, s := range existing {
+		if strings.EqualFold(s.Name, name) && acc.Issuer != "" && acc.Name != "" {
+			name = fmt.Sprintf("%s (%s)", acc.Issuer, acc.Name)
+			break
+		}
+	}
+
+	sec, err := secret.NewPassword(name, "", acc.Name, "")
+	if err != nil {
+		return secret.Secret{}, err
+	}
+
+	sec.Fields["totp_secret"] = acc.Secret
+	if acc.Issuer != "" {
+		sec.Fields["totp_issuer"] = acc.Issuer
+	}
+	if acc.Params.Algorithm != totp.Default.Algorithm {
+		sec.Fields["totp_algorithm"] = acc.Params.Algorithm
+	}
+	if acc.Params.Digits != totp.Default.Digits {
+		sec.Fields["totp_digits"] = strconv.Itoa(acc.Params.Digits)
+	}
+	if acc.Params.Period != totp.Default.Period {
+		sec.Fields["totp_period"] = strconv.Itoa(acc.Params.Period)
+	}
+	sec.Tags = []string{"otp"}
+	return sec, nil
+}
+
+// hasOTPAccount reports whether a secret with a totp secret already exists
+// for the same issuer and account name.
+func hasOTPAccount(secrets []secret.Secret, acc totp.Account) bool {
+	for _, s := range secrets {
+		if s.TOTPSecret() == "" {
+			continue
+		}
+		if !strings.EqualFold(s.Username(), acc.Name) {
+			continue
+		}
+		// secrets created by hand carry the issuer only in their name
+		issuer := s.TOTPIssuer()
+		if issuer == "" && acc.Issuer == "" {
+			return true
+		}
+		if issuer == "" {
+			issuer = s.Name
+		}
+		if strings.EqualFold(issuer, acc.Issuer) {
+			return true
+		}
+	}
+	return false
+}
+
+func otpLabel(acc totp.Account) string {
+	if acc.Issuer == "" {
+		return acc.Name
+	}
+	return acc.Issuer + ":" + acc.Name
+}
@@ -0,0 +1,79 @@
+package cli
+
+import (
+	"testing"
+
+	"github.com/zarlcorp/zvault/internal/secret"
+	"github.com/zarlcorp/zvault/internal/totp"
+)
+
+func TestSecretFromOTPAccount(t *testing.T) {
+	acc := totp.Account{
+		Secret: "JBSWY3DPEHPK3PXP",
+		Issuer: "GitHub",
+		Name:   "alice",
+		Params: totp.Params{Algorithm: "SHA256", Digits: 8, Period: 30},
+	}
+
+	sec, err := secretFromOTPAccount(acc, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sec.Name != "GitHub" || sec.Username() != "alice" {
+		t.Errorf("name/username = %q/%q", sec.Name, sec.Username())
+	}
+	if sec.TOTPSecret() != "JBSWY3DPEHPK3PXP" || sec.TOTPIssuer() != "GitHub" {
+		t.Errorf("totp fields = %q/%q", sec.TOTPSecret(), sec.TOTPIssuer())
+	}
+	if sec.TOTPAlgorithm() != "SHA256" || sec.TOTPDigits() != "8" {
+		t.Errorf("params = %q/%q", sec.TOTPAlgorithm(), sec.TOTPDigits())
+	}
+	if sec.TOTPPeriod() != "" {
+		t.Errorf("default period should not be stored, got %q", sec.TOTPPeriod())
+	}
+
+	// a second GitHub account gets a distinct name
+	other, err := secretFromOTPAccount(totp.Account{Secret: "AAAA", Issuer: "GitHub", Name: "bob", Params: totp.Default}, []secret.Secret{sec})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if other.Name != "GitHub (bob)" {
+		t.Errorf("name = %q, want %q", other.Name, "GitHub (bob)")
+	}
+}
+
+func TestHasOTPAccount(t *testing.T) {
+	imported, err := secretFromOTPAccount(totp.Account{Secret: "AAAA", Issuer: "GitHub", Name: "alice", Params: totp.Default}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	manual, err := secret.NewPassword("gitlab", "https://gitlab.com", "bob", "pw")
+	if err != nil {
+		t.Fatal(err)
+	}
+	manual.Fields["totp_secret"] = "BBBB"
+	noTOTP, err := secret.NewPassword("Example", "", "carol", "pw")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	secrets := []secret.Secret{imported, manual, noTOTP}
+
+	tests := []struct {
+		issuer, name string
+		want         bool
+	}{
+		{"GitHub", "alice", true},
+		{"github", "ALICE", true},
+		{"GitHub", "bob", false},
+		{"GitLab", "bob", true},
+		{"Example", "carol", false}, // no totp secret stored
+	}
+
+	for _, tt := range tests {
+		got := hasOTPAccount(secrets, totp.Account{Issuer: tt.issuer, Name: tt.name})
+		if got != tt.want {
+			t.Errorf("hasOTPAccount(%q, %q) = %v, want %v", tt.issuer, tt.name, got, tt.want)
+		}
+	}
+}
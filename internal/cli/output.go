@@ -0,0 +1,174 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/zarlcorp/zvault/internal/secret"
+	"github.com/zarlcorp/zvault/internal/task"
+	"github.com/zarlcorp/zvault/internal/vault"
+)
+
+// jsonOutput is set by the global --json flag. Commands that support it
+// print the structures below to stdout, and errf writes errors to stderr
+// as errorOutput objects.
+var jsonOutput bool
+
+// Error codes reported with --json.
+const (
+	codeError     = "error"     // anything not covered below
+	codeUsage     = "usage"     // missing, unknown or invalid arguments
+	codeNotFound  = "not_found" // no secret or task matched
+	codeAmbiguous = "ambiguous" // a reference matched several secrets or tasks
+	codeVault     = "vault"     // the vault could not be opened
+)
+
+// errorOutput is how errors are written to stderr with --json.
+type errorOutput struct {
+	Error struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// errorCode classifies errf arguments for --json output by the typed
+// errors among them.
+func errorCode(args []any) string {
+	for _, a := range args {
+		err, ok := a.(error)
+		if !ok {
+			continue
+		}
+		var nf *vault.NotFoundError
+		if errors.As(err, &nf) {
+			return codeNotFound
+		}
+		var amb *vault.AmbiguousError
+		if errors.As(err, &amb) {
+			return codeAmbiguous
+		}
+		var ue *usageError
+		if errors.As(err, &ue) {
+			return codeUsage
+		}
+	}
+	return codeError
+}
+
+func writeJSONError(code, msg string) {
+	var out errorOutput
+	out.Error.Code = code
+	out.Error.Message = msg
+	b, _ := json.Marshal(out)
+	fmt.Fprintln(os.Stderr, string(b))
+}
+
+// writeJSON prints v as indented JSON to stdout.
+func writeJSON(v any) {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		errf("write json: %v", err)
+		exit(1)
+	}
+}
+
+// secretOutput is a secret as printed by secret list, get and search.
+// Fields holds only identifying values (url, username, service, public
+// key details) unless values were asked for with --show.
+type secretOutput struct {
+	ID        string            `json:"id"`
+	Name      string            `json:"name"`
+	Type      string            `json:"type"`
+	Tags      []string          `json:"tags"`
+	Fields    map[string]string `json:"fields"`
+	CreatedAt time.Time         `json:"created_at"`
+	UpdatedAt time.Time         `json:"updated_at"`
+}
+
+func newSecretOutput(sec secret.Secret, show bool) secretOutput {
+	if !show {
+		sec = sec.Redacted()
+	}
+	out := secretOutput{
+		ID:        sec.ID,
+		Name:      sec.Name,
+		Type:      string(sec.Type),
+		Tags:      sec.Tags,
+		Fields:    sec.Fields,
+		CreatedAt: sec.CreatedAt,
+		UpdatedAt: sec.UpdatedAt,
+	}
+	if out.Tags == nil {
+		out.Tags = []string{}
+	}
+	if out.Fields == nil {
+		out.Fields = map[string]string{}
+	}
+	return out
+}
+
+func secretOutputs(secrets []secret.Secret) []secretOutput {
+	out := make([]secretOutput, len(secrets))
+	for i, sec := range secrets {
+		out[i] = newSecretOutput(sec, false)
+	}
+	return out
+}
+
+// taskOutput is a task as printed by task list and task <id>. Priority is
+// "", "low", "medium" or "high"; due_date and completed_at are null when
+// unset.
+type taskOutput struct {
+	ID          string     `json:"id"`
+	Title       string     `json:"title"`
+	Done        bool       `json:"done"`
+	Priority    string     `json:"priority"`
+	DueDate     *time.Time `json:"due_date"`
+	Tags        []string   `json:"tags"`
+	CreatedAt   time.Time  `json:"created_at"`
+	CompletedAt *time.Time `json:"completed_at"`
+}
+
+func newTaskOutput(tk task.Task) taskOutput {
+	out := taskOutput{
+		ID:          tk.ID,
+		Title:       tk.Title,
+		Done:        tk.Done,
+		Priority:    string(tk.Priority),
+		DueDate:     tk.DueDate,
+		Tags:        tk.Tags,
+		CreatedAt:   tk.CreatedAt,
+		CompletedAt: tk.CompletedAt,
+	}
+	if out.Tags == nil {
+		out.Tags = []string{}
+	}
+	return out
+}
+
+func taskOutputs(tasks []task.Task) []taskOutput {
+	out := make([]taskOutput, len(tasks))
+	for i, tk := range tasks {
+		out[i] = newTaskOutput(tk)
+	}
+	return out
+}
+
+// otpOutput is the current code printed by otp.
+type otpOutput struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	Code      string `json:"code"`
+	ExpiresIn int    `json:"expires_in"`
+}
+
+// deletedOutput reports a deleted secret or task.
+type deletedOutput struct {
+	ID      string `json:"id"`
+	Name    string `json:"name,omitempty"`
+	Deleted bool   `json:"deleted"`
+}
@@ -0,0 +1,103 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zarlcorp/zvault/internal/task"
+	"github.com/zarlcorp/zvault/internal/vault"
+)
+
+func TestErrorCode(t *testing.T) {
+	nf := &vault.NotFoundError{Kind: "secret", Ref: "github"}
+	amb := &vault.AmbiguousError{Kind: "task", Ref: "deploy", Matches: []vault.Match{{ID: "a1", Name: "deploy api"}, {ID: "b2", Name: "deploy web"}}}
+	tests := []struct {
+		args []any
+		want string
+	}{
+		{[]any{nf}, codeNotFound},
+		{[]any{fmt.Errorf("resolve: %w", nf)}, codeNotFound},
+		{[]any{amb}, codeAmbiguous},
+		{[]any{usagef("unknown flag --x")}, codeUsage},
+		{[]any{"github", errors.New("invalid ssh key: truncated")}, codeError},
+		{nil, codeError},
+	}
+	for _, tt := range tests {
+		if got := errorCode(tt.args); got != tt.want {
+			t.Errorf("errorCode(%v) = %q, want %q", tt.args, got, tt.want)
+		}
+	}
+}
+
+func TestSecretOutput(t *testing.T) {
+	sec := mustPasswordSecret(t, "github", "github.com", "alice", "hunter2")
+
+	out := newSecretOutput(sec, false)
+	if _, ok := out.Fields["password"]; ok {
+		t.Error("password included without show")
+	}
+	if out.Fields["username"] != "alice" {
+		t.Errorf("username = %q", out.Fields["username"])
+	}
+	if out.Tags == nil {
+		t.Error("tags should be [] not null")
+	}
+
+	if got := newSecretOutput(sec, true).Fields["password"]; got != "hunter2" {
+		t.Errorf("password with show = %q", got)
+	}
+
+	b, err := json.Marshal(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{`"id"`, `"name"`, `"type"`, `"tags":[]`, `"fields"`, `"created_at"`, `"updated_at"`} {
+		if !strings.Contains(string(b), key) {
+			t.Errorf("json missing %s: %s", key, b)
+		}
+	}
+}
+
+func TestTaskOutput(t *testing.T) {
+	tk := task.Task{ID: "abcd1234", Title: "ship", CreatedAt: time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)}
+	b, err := json.Marshal(newTaskOutput(tk))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"id":"abcd1234","title":"ship","done":false,"priority":"","due_date":null,"tags":[],"created_at":"2024-01-02T00:00:00Z","completed_at":null}`
+	if string(b) != want {
+		t.Errorf("got  %s\nwant %s", b, want)
+	}
+}
+
+func TestSecretDeleteJSONNeedsYes(t *testing.T) {
+	stderr := os.Stderr
+	defer func() { exit, jsonOutput, os.Stderr = os.Exit, false, stderr }()
+	exit = func(code int) { panic(shellExit(code)) }
+	jsonOutput = true
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.Stderr = w
+
+	// it fails before opening the vault, so no prompt can block on stdin
+	code := func() (code shellExit) {
+		defer func() { code, _ = recover().(shellExit) }()
+		runSecretDelete(parseArgs(t, "secret", "delete", "github"))
+		return 0
+	}()
+	w.Close()
+	var out errorOutput
+	if err := json.NewDecoder(r).Decode(&out); err != nil {
+		t.Fatal(err)
+	}
+	if code != 1 || out.Error.Code != codeUsage {
+		t.Errorf("exit %d, error %+v; want exit 1 and a usage error", code, out.Error)
+	}
+}
@@ -0,0 +1,143 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/zarlcorp/zvault/internal/dotenv"
+	"github.com/zarlcorp/zvault/internal/secret"
+	"github.com/zarlcorp/zvault/internal/totp"
+)
+
+// refScheme prefixes secret references such as zvault://github/password.
+const refScheme = "zvault://"
+
+// secretRef points at one field of a secret. An empty Field means the
+// secret's main value.
+type secretRef struct {
+	Name  string
+	Field string
+}
+
+func isSecretRef(s string) bool {
+	return strings.HasPrefix(s, refScheme)
+}
+
+// parseSecretRef parses zvault://<id-or-name>[/<field>]. The field is taken
+// after the last slash, so names containing slashes need an explicit field.
+func parseSecretRef(s string) (secretRef, error) {
+	rest, ok := strings.CutPrefix(s, refScheme)
+	if !ok {
+		return secretRef{}, fmt.Errorf("invalid secret reference %q (want %s<name>/<field>)", s, refScheme)
+	}
+
+	var ref secretRef
+	if i := strings.LastIndex(rest, "/"); i >= 0 {
+		ref.Name, ref.Field = rest[:i], rest[i+1:]
+	} else {
+		ref.Name = rest
+	}
+	if ref.Name == "" {
+		return secretRef{}, fmt.Errorf("invalid secret reference %q: missing secret name", s)
+	}
+	return ref, nil
+}
+
+func (r secretRef) String() string {
+	if r.Field == "" {
+		return refScheme + r.Name
+	}
+	return refScheme + r.Name + "/" + r.Field
+}
+
+// refResolver resolves references, looking each secret up once.
+type refResolver struct {
+	lookup func(idOrName string) (secret.Secret, error)
+	cache  map[string]secret.Secret
+}
+
+func newRefResolver(lookup func(string) (secret.Secret, error)) *refResolver {
+	return &refResolver{lookup: lookup, cache: make(map[string]secret.Secret)}
+}
+
+// Resolve returns the value a reference string points at.
+func (r *refResolver) Resolve(s string) (string, error) {
+	ref, err := parseSecretRef(s)
+	if err != nil {
+		return "", err
+	}
+	return r.Field(ref.Name, ref.Field)
+}
+
+// Secret looks up a secret by id or name.
+func (r *refResolver) Secret(idOrName string) (secret.Secret, error) {
+	if sec, ok := r.cache[idOrName]; ok {
+		return sec, nil
+	}
+	sec, err := r.lookup(idOrName)
+	if err != nil {
+		return secret.Secret{}, err
+	}
+	r.cache[idOrName] = sec
+	return sec, nil
+}
+
+// Field returns a field of a secret, as secretFieldValue does.
+func (r *refResolver) Field(idOrName, field string) (string, error) {
+	sec, err := r.Secret(idOrName)
+	if err != nil {
+		return "", err
+	}
+	return secretFieldValue(sec, field)
+}
+
+// secretFieldValue returns a field of a secret. An empty field selects the
+// main value for the secret's type; "totp" generates the current code. For
+// env notes, a variable name selects that variable.
+func secretFieldValue(sec secret.Secret, field string) (string, error) {
+	if field == "" {
+		field = primaryField(sec.Type)
+	}
+
+	if field == "totp" {
+		if sec.TOTPSecret() == "" {
+			return "", fmt.Errorf("secret %q has no totp secret", sec.Name)
+		}
+		p, err := totp.ParseParams(sec.TOTPAlgorithm(), sec.TOTPDigits(), sec.TOTPPeriod())
+		if err != nil {
+			return "", err
+		}
+		code, _, err := totp.GenerateWith(sec.TOTPSecret(), p)
+		return code, err
+	}
+
+	if v, ok := sec.Fields[field]; ok {
+		return v, nil
+	}
+
+	// variables of a .env file imported before they were stored as fields
+	if sec.Type == secret.TypeNote && containsTag(sec.Tags, envTag) {
+		entries, err := envEntries(sec)
+		if err != nil {
+			return "", err
+		}
+		if v, ok := dotenv.Lookup(entries, field); ok {
+			return v, nil
+		}
+	}
+	return "", fmt.Errorf("secret %q has no field %q", sec.Name, field)
+}
+
+func primaryField(t secret.Type) string {
+	switch t {
+	case secret.TypePassword:
+		return "password"
+	case secret.TypeAPIKey:
+		return "key"
+	case secret.TypeSSHKey:
+		return "private_key"
+	case secret.TypeNote:
+		return "content"
+	}
+	return ""
+}
@@ -0,0 +1,101 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/zarlcorp/zvault/internal/secret"
+)
+
+func TestParseSecretRef(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    secretRef
+		wantErr bool
+	}{
+		{"zvault://github/password", secretRef{Name: "github", Field: "password"}, false},
+		{"zvault://aws-prod", secretRef{Name: "aws-prod"}, false},
+		{"zvault://github.com/org/repo/password", secretRef{Name: "github.com/org/repo", Field: "password"}, false},
+		{"zvault://github/", secretRef{Name: "github"}, false},
+		{"zvault:///password", secretRef{}, true},
+		{"github/password", secretRef{}, true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseSecretRef(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseSecretRef(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("parseSecretRef(%q) = %+v, want %+v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestRefResolver(t *testing.T) {
+	gh := mustPasswordSecret(t, "github", "https://github.com", "alice", "ghp_token")
+	gh.Fields["totp_secret"] = "JBSWY3DPEHPK3PXP"
+	api, err := secret.NewAPIKey("aws-prod", "aws", "AKIA")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lookups := 0
+	r := newRefResolver(func(name string) (secret.Secret, error) {
+		lookups++
+		switch name {
+		case "github":
+			return gh, nil
+		case "aws-prod":
+			return api, nil
+		}
+		return secret.Secret{}, fmt.Errorf("secret %q not found", name)
+	})
+
+	tests := []struct {
+		ref, want string
+	}{
+		{"zvault://github/password", "ghp_token"},
+		{"zvault://github/username", "alice"},
+		{"zvault://github", "ghp_token"},
+		{"zvault://aws-prod/key", "AKIA"},
+		{"zvault://aws-prod", "AKIA"},
+	}
+	for _, tt := range tests {
+		got, err := r.Resolve(tt.ref)
+		if err != nil {
+			t.Fatalf("Resolve(%q): %v", tt.ref, err)
+		}
+		if got != tt.want {
+			t.Errorf("Resolve(%q) = %q, want %q", tt.ref, got, tt.want)
+		}
+	}
+	if lookups != 2 {
+		t.Errorf("lookups = %d, want 2 (one per secret)", lookups)
+	}
+
+	code, err := r.Resolve("zvault://github/totp")
+	if err != nil || len(code) != 6 {
+		t.Errorf("totp code = %q, %v", code, err)
+	}
+
+	for _, ref := range []string{"zvault://github/nope", "zvault://missing/password", "zvault://aws-prod/totp"} {
+		if _, err := r.Resolve(ref); err == nil {
+			t.Errorf("Resolve(%q): expected error", ref)
+		}
+	}
+}
+
+func TestSecretRefString(t *testing.T) {
+	for _, s := range []string{"zvault://github/password", "zvault://note"} {
+		ref, err := parseSecretRef(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.EqualFold(ref.String(), s) {
+			t.Errorf("String() = %q, want %q", ref.String(), s)
+		}
+	}
+}
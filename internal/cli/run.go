@@ -0,0 +1,207 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"slices"
+	"strings"
+	"syscall"
+
+	"github.com/zarlcorp/zvault/internal/dotenv"
+	"github.com/zarlcorp/zvault/internal/secret"
+)
+
+func runCommand() *command {
+	return &command{
+		name:        "run",
+		summary:     "run a command with secrets in its environment",
+		args:        "[--] <cmd> [<arg>...]",
+		minArgs:     1,
+		maxArgs:     unlimited,
+		passthrough: true,
+		help: `
+Run a command with secrets in its environment. Values are references of
+the form zvault://<id-or-name>/<field>; without a field the main value is
+used (password, key, private_key, content), and /totp gives the current
+code. Inherited variables holding a reference are resolved too.
+
+Flags end at the command: everything from its name on is passed to it.
+
+Secrets only ever live in the child's environment, and ZVAULT_PASSWORD,
+ZVAULT_PASSWORD_FILE and ZVAULT_PASSWORD_COMMAND are not passed on.
+Ctrl-C reaches the command from the terminal, other signals are
+forwarded to it, and its exit code is returned.
+
+Examples:
+  zvault run --env GITHUB_TOKEN=zvault://github/password -- gh repo list
+  zvault run --env-file deploy.env -- ./deploy.sh`,
+		flags: []*flag{
+			{name: "env", kind: stringsFlag, value: "NAME=<ref>", usage: "set a variable"},
+			{name: "env-file", kind: stringsFlag, value: "<path>", usage: "read NAME=<ref> lines from a file", files: true},
+		},
+		run: runRun,
+	}
+}
+
+func runRun(in *invocation) {
+	var overrides []string
+	for _, path := range in.values("env-file") {
+		f, err := os.Open(path)
+		if err != nil {
+			errf("open env file: %v", err)
+			exit(1)
+		}
+		entries, err := parseEnvMapping(f)
+		f.Close()
+		if err != nil {
+			errf("%s: %v", path, err)
+			exit(1)
+		}
+		overrides = append(overrides, entries...)
+	}
+	for _, kv := range in.values("env") {
+		if !validEnvEntry(kv) {
+			errfCode(codeUsage, "invalid --env %q (want NAME=zvault://secret/field)", kv)
+			exit(1)
+		}
+		overrides = append(overrides, kv)
+	}
+
+	// the master password is for zvault, never for the command
+	base := slices.DeleteFunc(os.Environ(), func(kv string) bool {
+		name, _, _ := strings.Cut(kv, "=")
+		return name == "ZVAULT_PASSWORD" || name == "ZVAULT_PASSWORD_FILE" || name == "ZVAULT_PASSWORD_COMMAND"
+	})
+	env := mergeEnv(base, overrides)
+
+	if slices.ContainsFunc(env, envHasRef) {
+		v := openVault()
+		r := newRefResolver(func(name string) (secret.Secret, error) {
+			return resolveSecret(v, name)
+		})
+		var err error
+		env, err = resolveEnv(env, r)
+		closeVault(v) // don't hold the vault open while the command runs
+		if err != nil {
+			errf("%v", err)
+			exit(1)
+		}
+	}
+
+	exit(runChild(in.args, env))
+}
+
+func validEnvEntry(kv string) bool {
+	name, _, ok := strings.Cut(kv, "=")
+	return ok && name != "" && !strings.ContainsAny(name, " \t")
+}
+
+// parseEnvMapping reads a dotenv file of NAME=value lines, where values
+// are usually references.
+func parseEnvMapping(r io.Reader) ([]string, error) {
+	entries, err := dotenv.Parse(r)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]string, len(entries))
+	for i, e := range entries {
+		out[i] = e.Key + "=" + e.Value
+	}
+	return out, nil
+}
+
+// mergeEnv applies NAME=value overrides to base, later entries winning.
+func mergeEnv(base, overrides []string) []string {
+	env := slices.Clone(base)
+	index := make(map[string]int, len(env))
+	for i, kv := range env {
+		name, _, _ := strings.Cut(kv, "=")
+		index[name] = i
+	}
+	for _, kv := range overrides {
+		name, _, _ := strings.Cut(kv, "=")
+		if i, ok := index[name]; ok {
+			env[i] = kv
+			continue
+		}
+		index[name] = len(env)
+		env = append(env, kv)
+	}
+	return env
+}
+
+func envHasRef(kv string) bool {
+	_, value, _ := strings.Cut(kv, "=")
+	return isSecretRef(value)
+}
+
+// resolveEnv replaces reference values with the secrets they point at.
+func resolveEnv(env []string, r *refResolver) ([]string, error) {
+	out := make([]string, len(env))
+	for i, kv := range env {
+		name, value, _ := strings.Cut(kv, "=")
+		if !isSecretRef(value) {
+			out[i] = kv
+			continue
+		}
+		resolved, err := r.Resolve(value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		out[i] = name + "=" + resolved
+	}
+	return out, nil
+}
+
+// runChild runs the command to completion, forwarding signals, and returns
+// the exit code to use: the child's own, or 128+n if signal n killed it.
+func runChild(command []string, env []string) int {
+	cmd := exec.Command(command[0], command[1:]...)
+	cmd.Env = env
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	// Catching rather than ignoring terminalSignals keeps them at their
+	// default in the command, which an ignored signal would not be.
+	sigs := make(chan os.Signal, 8)
+	signal.Notify(sigs, slices.Concat(forwardSignals, terminalSignals)...)
+	defer func() {
+		signal.Stop(sigs)
+		close(sigs)
+	}()
+
+	if err := cmd.Start(); err != nil {
+		errf("%v", err)
+		if errors.Is(err, exec.ErrNotFound) {
+			return 127
+		}
+		return 126
+	}
+
+	go func() {
+		for sig := range sigs {
+			if !slices.Contains(terminalSignals, sig) {
+				_ = cmd.Process.Signal(sig)
+			}
+		}
+	}()
+
+	err := cmd.Wait()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) {
+			errf("%v", err)
+			return 1
+		}
+	}
+
+	if ws, ok := cmd.ProcessState.Sys().(syscall.WaitStatus); ok && ws.Signaled() {
+		return 128 + int(ws.Signal())
+	}
+	return cmd.ProcessState.ExitCode()
+}
@@ -0,0 +1,148 @@
+package cli
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/zarlcorp/zvault/internal/secret"
+)
+
+func TestParseEnvMapping(t *testing.T) {
+	in := `# deploy secrets
+GITHUB_TOKEN=zvault://github/password
+export AWS_KEY = "zvault://aws-prod/key"
+
+REGION='eu-west-1'
+`
+	got, err := parseEnvMapping(strings.NewReader(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"GITHUB_TOKEN=zvault://github/password",
+		"AWS_KEY=zvault://aws-prod/key",
+		"REGION=eu-west-1",
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	if _, err := parseEnvMapping(strings.NewReader("not a mapping\n")); err == nil {
+		t.Error("expected error for a line without =")
+	}
+}
+
+func TestMergeAndResolveEnv(t *testing.T) {
+	gh := mustPasswordSecret(t, "github", "", "alice", "ghp_token")
+	r := newRefResolver(func(string) (secret.Secret, error) { return gh, nil })
+
+	env := mergeEnv(
+		[]string{"PATH=/bin", "TOKEN=old", "INHERITED=zvault://github/username"},
+		[]string{"TOKEN=zvault://github/password", "NEW=1"},
+	)
+	env, err := resolveEnv(env, r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"PATH=/bin", "TOKEN=ghp_token", "INHERITED=alice", "NEW=1"}
+	if !slices.Equal(env, want) {
+		t.Errorf("env = %q, want %q", env, want)
+	}
+}
+
+func TestRunChildExitCode(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+
+	if code := runChild([]string{"sh", "-c", "exit 3"}, nil); code != 3 {
+		t.Errorf("exit code = %d, want 3", code)
+	}
+	if code := runChild([]string{"sh", "-c", `test "$TOKEN" = s3cret`}, []string{"TOKEN=s3cret"}); code != 0 {
+		t.Errorf("env not passed to child, exit code %d", code)
+	}
+	if code := runChild([]string{"sh", "-c", "kill -TERM $$"}, nil); code != 128+15 {
+		t.Errorf("exit code = %d, want %d for SIGTERM", code, 128+15)
+	}
+	if code := runChild([]string{"zvault-no-such-command"}, nil); code != 127 {
+		t.Errorf("exit code = %d, want 127 for a missing command", code)
+	}
+}
+
+func TestRunChildSignals(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh and unix signals")
+	}
+
+	// the command records the signals it gets and exits once done exists
+	dir := t.TempDir()
+	pidFile, logFile, done := filepath.Join(dir, "pid"), filepath.Join(dir, "log"), filepath.Join(dir, "done")
+	script := `trap 'echo INT >> "$LOG"' INT
+trap 'echo TERM >> "$LOG"' TERM
+echo $$ > "$PID"
+while [ ! -e "$DONE" ]; do sleep 0.05; done`
+	env := []string{"PID=" + pidFile, "LOG=" + logFile, "DONE=" + done, "PATH=" + os.Getenv("PATH")}
+
+	codes := make(chan int)
+	go func() { codes <- runChild([]string{"sh", "-c", script}, env) }()
+
+	var pid int
+	for range 100 {
+		data, err := os.ReadFile(pidFile)
+		if n, convErr := strconv.Atoi(strings.TrimSpace(string(data))); err == nil && convErr == nil {
+			pid = n
+			break
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	if pid == 0 {
+		t.Fatal("command did not start")
+	}
+	child, err := os.FindProcess(pid)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Ctrl-C goes to zvault and the command alike; SIGTERM only to zvault.
+	// Pausing between signals keeps a forwarded copy from merging with
+	// one still pending.
+	self, err := os.FindProcess(os.Getpid())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []struct {
+		p   *os.Process
+		sig os.Signal
+	}{{self, syscall.SIGINT}, {child, syscall.SIGINT}, {self, syscall.SIGTERM}} {
+		if err := s.p.Signal(s.sig); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(200 * time.Millisecond)
+	}
+
+	if err := os.WriteFile(done, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if code := <-codes; code != 0 {
+		t.Errorf("exit code = %d, want 0", code)
+	}
+
+	data, err := os.ReadFile(logFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := strings.Fields(string(data))
+	slices.Sort(got)
+	if !slices.Equal(got, []string{"INT", "TERM"}) {
+		t.Errorf("command got signals %q, want one INT and one TERM", got)
+	}
+}
@@ -1,76 +1,183 @@
 package cli
 
 import (
+	"cmp"
+	"encoding/json"
 	"fmt"
+	"io"
 	"os"
+	"slices"
 	"strings"
+	"time"
 
+	"github.com/zarlcorp/zvault/internal/qr"
 	"github.com/zarlcorp/zvault/internal/secret"
+	"github.com/zarlcorp/zvault/internal/sshkey"
 	"github.com/zarlcorp/zvault/internal/vault"
+	"golang.org/x/term"
 )
 
-func runSecret(args []string) {
-	if len(args) == 0 {
-		printSecretUsage()
-		os.Exit(1)
-	}
-
-	switch args[0] {
-	case "store":
-		runSecretStore(args[1:])
-	case "get":
-		runSecretGet(args[1:])
-	case "list", "ls":
-		runSecretList(args[1:])
-	case "delete", "rm":
-		runSecretDelete(args[1:])
-	case "search":
-		runSecretSearch(args[1:])
-	case "help", "--help", "-h":
-		printSecretUsage()
-	default:
-		errf("unknown secret command %q", args[0])
-		printSecretUsage()
-		os.Exit(1)
+// secretTypes lists the types accepted by secret store -t.
+var secretTypes = []string{"password", "apikey", "sshkey", "note"}
+
+func secretCommand() *command {
+	return &command{
+		name:    "secret",
+		summary: "manage secrets (store, get, edit, copy, list, delete, search, qr)",
+		help: `
+A secret is named by its id, its name (ignoring case), an id prefix of
+at least 4 characters, or either qualified by a tag as tag:name or
+tag/name; the tag work also covers work/aws. A reference matching
+several secrets is an error listing them, or on a terminal a prompt to
+pick one.
+
+With the global --json flag, store, get, edit, list and search print
+secrets as JSON objects; values are left out unless get is given --show.`,
+		subs: []*command{
+			{
+				name:    "store",
+				summary: "create a new secret",
+				help: `
+Create a secret, prompting for its values. A note's content may be piped on
+stdin.
+
+With any of the field flags, or --from-json, nothing is prompted for and
+fields not given are left empty. --from-json reads a secret shaped like the
+output of 'secret get --json --show'; flags override what it holds.
+
+  zvault secret store -t password -n github --field username=me --field-stdin password
+  zvault secret store -t sshkey -n deploy --field-from-file private_key=$HOME/.ssh/id_ed25519`,
+				flags: append([]*flag{
+					{name: "type", short: 't', usage: "secret type", choices: secretTypes},
+					{name: "name", short: 'n', usage: "secret name"},
+					{name: "tags", value: "<a,b>", usage: "optional tags", names: secretTags},
+				}, fieldFlags()...),
+				run: runSecretStore,
+			},
+			{
+				name:     "edit",
+				summary:  "change a secret's fields, name or tags",
+				args:     "<id-or-name>",
+				argNames: secretNames,
+				minArgs:  1,
+				maxArgs:  1,
+				help: `
+Change a secret without prompting. Fields not mentioned are kept, and an
+empty value (--field notes=) removes a field. With --from-json, the name,
+tags and fields it holds replace the secret's; the type can't change.
+
+  printf '%s' "$NEW" | zvault secret edit github --field-stdin password`,
+				flags: append([]*flag{
+					{name: "rename", value: "<name>", usage: "new name"},
+					{name: "add-tag", kind: stringsFlag, value: "<tag>", usage: "add a tag", names: secretTags},
+					{name: "remove-tag", kind: stringsFlag, value: "<tag>", usage: "remove a tag", names: secretTags},
+				}, fieldFlags()...),
+				run: runSecretEdit,
+			},
+			{
+				name:     "get",
+				summary:  "retrieve a secret",
+				args:     "<id-or-name>",
+				argNames: secretNames,
+				minArgs:  1,
+				maxArgs:  1,
+				flags: []*flag{
+					{name: "show", kind: boolFlag, usage: "reveal sensitive values (masked by default)"},
+				},
+				run: runSecretGet,
+			},
+			{
+				name:    "list",
+				aliases: []string{"ls"},
+				summary: "list secrets",
+				help:    listFormatHelp,
+				flags: append([]*flag{
+					{name: "type", short: 't', usage: "filter by type", choices: secretTypes},
+					{name: "tag", usage: "filter by tag", names: secretTags},
+				}, listFlags(secretSorts, secretColumns)...),
+				run: runSecretList,
+			},
+			{
+				name:     "delete",
+				aliases:  []string{"rm"},
+				summary:  "delete a secret",
+				args:     "<id-or-name>",
+				argNames: secretNames,
+				minArgs:  1,
+				maxArgs:  1,
+				help: `
+Asks before deleting, unless --yes is given. With --json there is no one
+to ask, so --yes is required.`,
+				flags: []*flag{
+					{name: "yes", short: 'y', kind: boolFlag, usage: "delete without asking"},
+				},
+				run: runSecretDelete,
+			},
+			{
+				name:    "search",
+				summary: "search secrets",
+				args:    "<query>...",
+				minArgs: 1,
+				maxArgs: unlimited,
+				help:    "\nResults come best match first, unless --sort is given.\n" + listFormatHelp,
+				flags:   listFlags(secretSorts, secretColumns),
+				run:     runSecretSearch,
+			},
+			{
+				name:     "copy",
+				summary:  "copy a secret to the clipboard",
+				args:     "<id-or-name>",
+				argNames: secretNames,
+				minArgs:  1,
+				maxArgs:  1,
+				help: `
+Copy a secret's main value (password, key, private key or note content),
+or the field named by --field; "totp" copies the current code. The
+clipboard is cleared after --clear-after unless something else has been
+copied since.`,
+				flags: []*flag{
+					{name: "field", usage: "field to copy", suggest: []string{"password", "username", "url", "key", "totp", "private_key", "public_key", "content"}},
+					clearAfterFlag(),
+				},
+				run: runSecretCopy,
+			},
+			{
+				name:     "qr",
+				summary:  "show a secret as a QR code",
+				args:     "<id-or-name>",
+				argNames: secretNames,
+				minArgs:  1,
+				maxArgs:  1,
+				help: `
+Print a QR code on the terminal. Without --field it encodes the totp uri,
+or else the secret's main value. Use "totp" for an otpauth uri, or "wifi"
+for a wi-fi join code.`,
+				flags: []*flag{
+					{name: "field", usage: "field to encode", suggest: []string{"totp", "wifi", "password", "key", "public_key", "content"}},
+				},
+				run: runSecretQR,
+			},
+		},
 	}
 }
 
-func printSecretUsage() {
-	fmt.Fprint(os.Stderr, `Usage: zvault secret <command>
-
-Commands:
-  store   create a new secret
-  get     retrieve a secret
-  list    list secrets
-  delete  delete a secret
-  search  search secrets
-
-Store flags:
-  -t <type>         secret type: password, apikey, sshkey, note
-  -n <name>         secret name
-  --tags tag1,tag2  optional tags
-
-Get flags:
-  --show            reveal sensitive values (masked by default)
-
-List flags:
-  -t <type>         filter by type
-  --tag <tag>       filter by tag
-`)
-}
+func runSecretStore(in *invocation) {
+	if hasFieldFlags(in) {
+		storeFromFields(in)
+		return
+	}
 
-func runSecretStore(args []string) {
-	typ := flagValue(args, "-t")
-	name := flagValue(args, "-n")
-	tags := parseTags(flagValue(args, "--tags"))
+	typ := in.value("type")
+	name := in.value("name")
+	tags := parseTags(in.value("tags"))
 
 	if typ == "" {
-		errf("secret type required (-t password|apikey|sshkey|note)")
-		os.Exit(1)
+		errfCode(codeUsage, "secret type required (-t password|apikey|sshkey|note)")
+		exit(1)
 	}
 	if name == "" {
-		errf("secret name required (-n <name>)")
-		os.Exit(1)
+		errfCode(codeUsage, "secret name required (-n <name>)")
+		exit(1)
 	}
 
 	var sec secret.Secret
@@ -111,62 +218,331 @@ func runSecretStore(args []string) {
 		}
 
 		sec, err = secret.NewSSHKey(name, label, privKey, pubKey)
-
-	default:
-		errf("unknown secret type %q (use password, apikey, sshkey, note)", typ)
-		os.Exit(1)
+		if err == nil {
+			if verr := sshkey.Validate(&sec); verr != nil {
+				errf("invalid ssh key: %v", verr)
+				exit(1)
+			}
+		}
 	}
 
 	if err != nil {
 		errf("create secret: %v", err)
-		os.Exit(1)
+		exit(1)
 	}
 
 	sec.Tags = tags
 
 	v := openVault()
-	defer v.Close()
+	defer closeVault(v)
 
 	if err := v.Secrets().Add(sec); err != nil {
 		errf("store secret: %v", err)
-		os.Exit(1)
+		exit(1)
 	}
 
+	if jsonOutput {
+		writeJSON(newSecretOutput(sec, false))
+		return
+	}
 	fmt.Printf("%s %s stored\n", green(sec.ID), bold(sec.Name))
 }
 
-func runSecretGet(args []string) {
-	show := hasFlag(args, "--show")
-	pos := stripFlags(args, nil, []string{"--show"})
+// storeFromFields is secret store given its values by flags or JSON rather
+// than prompts.
+func storeFromFields(in *invocation) {
+	input, fields := readFieldInput(in)
+
+	typ := input.Type
+	if in.given("type") {
+		typ = in.value("type")
+	}
+	name := input.Name
+	if in.given("name") {
+		name = in.value("name")
+	}
+	tags := input.Tags
+	if in.given("tags") {
+		tags = parseTags(in.value("tags"))
+	}
+
+	if typ == "" {
+		errfCode(codeUsage, "secret type required (-t password|apikey|sshkey|note)")
+		exit(1)
+	}
+	if !slices.Contains(secretTypes, typ) {
+		errfCode(codeUsage, "unknown secret type %q (use password, apikey, sshkey or note)", typ)
+		exit(1)
+	}
+	if name == "" {
+		errfCode(codeUsage, "secret name required (-n <name>)")
+		exit(1)
+	}
+
+	sec, err := newEmptySecret(secret.Type(typ), name)
+	if err != nil {
+		errf("create secret: %v", err)
+		exit(1)
+	}
+	setFields(&sec, fields)
+	sec.Tags = tags
+
+	if sec.Type == secret.TypeSSHKey {
+		if err := sshkey.Validate(&sec); err != nil {
+			errf("invalid ssh key: %v", err)
+			exit(1)
+		}
+	}
+
+	v := openVault()
+	defer closeVault(v)
+
+	if err := v.Secrets().Add(sec); err != nil {
+		errf("store secret: %v", err)
+		exit(1)
+	}
+
+	if jsonOutput {
+		writeJSON(newSecretOutput(sec, false))
+		return
+	}
+	fmt.Printf("%s %s stored\n", green(sec.ID), bold(sec.Name))
+}
 
-	if len(pos) == 0 {
-		errf("secret ID or name required")
-		os.Exit(1)
+func runSecretEdit(in *invocation) {
+	if !hasFieldFlags(in) && !in.given("rename") && !in.given("add-tag") && !in.given("remove-tag") {
+		errfCode(codeUsage, "nothing to change (use --field, --rename, --add-tag, --remove-tag or --from-json)")
+		exit(1)
 	}
+	input, fields := readFieldInput(in)
 
 	v := openVault()
-	defer v.Close()
+	defer closeVault(v)
 
-	sec, err := resolveSecret(v, pos[0])
+	sec, err := resolveSecret(v, in.args[0])
 	if err != nil {
 		errf("%v", err)
-		os.Exit(1)
+		exit(1)
+	}
+
+	if input.Type != "" && input.Type != string(sec.Type) {
+		errfCode(codeUsage, "can't change %s from %s to %s", sec.Name, sec.Type, input.Type)
+		exit(1)
+	}
+	if input.Name != "" {
+		sec.Name = input.Name
+	}
+	if input.Tags != nil {
+		sec.Tags = input.Tags
+	}
+	if in.given("rename") {
+		if in.value("rename") == "" {
+			errfCode(codeUsage, "--rename needs a name")
+			exit(1)
+		}
+		sec.Name = in.value("rename")
+	}
+	for _, t := range in.values("add-tag") {
+		for _, tag := range parseTags(t) {
+			if !containsTag(sec.Tags, tag) {
+				sec.Tags = append(sec.Tags, tag)
+			}
+		}
+	}
+	for _, t := range in.values("remove-tag") {
+		for _, tag := range parseTags(t) {
+			sec.Tags = slices.DeleteFunc(sec.Tags, func(s string) bool { return s == tag })
+		}
 	}
 
-	printSecretDetail(sec, show)
+	if sec.Fields == nil {
+		sec.Fields = make(map[string]string)
+	}
+	setFields(&sec, fields)
+
+	if sec.Type == secret.TypeSSHKey {
+		// a new private key with no public key given gets its own public
+		// key rather than failing to match the old one
+		_, newPriv := fields["private_key"]
+		_, newPub := fields["public_key"]
+		if newPriv && !newPub {
+			delete(sec.Fields, "public_key")
+		}
+		if err := sshkey.Validate(&sec); err != nil {
+			errf("invalid ssh key: %v", err)
+			exit(1)
+		}
+	}
+
+	if err := v.Secrets().Update(sec); err != nil {
+		errf("update secret: %v", err)
+		exit(1)
+	}
+
+	if jsonOutput {
+		writeJSON(newSecretOutput(sec, false))
+		return
+	}
+	fmt.Printf("%s %s updated\n", green(sec.ID), bold(sec.Name))
 }
 
-func runSecretList(args []string) {
-	typ := flagValue(args, "-t")
-	tag := flagValue(args, "--tag")
+// fieldFlags are the flags that give store and edit a secret's values.
+func fieldFlags() []*flag {
+	return []*flag{
+		{name: "field", kind: stringsFlag, value: "<key>=<value>", usage: "set a field", secret: true},
+		{name: "field-from-file", kind: stringsFlag, value: "<key>=<path>", usage: "set a field to a file's contents", files: true},
+		{name: "field-stdin", value: "<key>", usage: "set a field from stdin, or a masked prompt on a terminal",
+			suggest: []string{"password", "key", "private_key", "passphrase", "content", "totp_secret"}},
+		{name: "from-json", kind: boolFlag, usage: "read the secret as JSON from stdin"},
+	}
+}
+
+func hasFieldFlags(in *invocation) bool {
+	return in.given("field") || in.given("field-from-file") || in.given("field-stdin") || in.has("from-json")
+}
+
+// secretInput is a secret as read by --from-json. It has the shape secret
+// get --json --show prints; ids and times are ignored.
+type secretInput struct {
+	Name   string            `json:"name"`
+	Type   string            `json:"type"`
+	Fields map[string]string `json:"fields"`
+	Tags   []string          `json:"tags"`
+}
+
+// readFieldInput reads --from-json and the field flags. The fields of the
+// JSON come first and the flags override them.
+func readFieldInput(in *invocation) (secretInput, map[string]string) {
+	var input secretInput
+	if in.has("from-json") && in.given("field-stdin") {
+		errfCode(codeUsage, "--from-json and --field-stdin both read stdin; use one")
+		exit(1)
+	}
+	if in.has("from-json") {
+		if err := json.NewDecoder(os.Stdin).Decode(&input); err != nil {
+			errf("read secret json: %v", err)
+			exit(1)
+		}
+	}
+
+	fields := make(map[string]string)
+	for k, v := range input.Fields {
+		fields[k] = v
+	}
+	if err := parseFieldFlags(in, fields); err != nil {
+		errf("%v", err)
+		exit(1)
+	}
+	return input, fields
+}
+
+// parseFieldFlags adds the values of --field, --field-from-file and
+// --field-stdin to fields.
+func parseFieldFlags(in *invocation, fields map[string]string) error {
+	for _, f := range in.values("field") {
+		k, v, err := splitField("--field", f)
+		if err != nil {
+			return err
+		}
+		fields[k] = v
+	}
+	for _, f := range in.values("field-from-file") {
+		k, path, err := splitField("--field-from-file", f)
+		if err != nil {
+			return err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("field %s: %w", k, err)
+		}
+		fields[k] = strings.TrimRight(string(data), "\r\n")
+	}
+	if in.given("field-stdin") {
+		k := in.value("field-stdin")
+		if k == "" {
+			return usagef("--field-stdin needs a field name")
+		}
+		if term.IsTerminal(int(os.Stdin.Fd())) {
+			fields[k] = promptPassword(k + ": ")
+		} else {
+			data, err := io.ReadAll(os.Stdin)
+			if err != nil {
+				return fmt.Errorf("read stdin: %w", err)
+			}
+			fields[k] = strings.TrimRight(string(data), "\r\n")
+		}
+	}
+	return nil
+}
+
+func splitField(flagName, s string) (key, value string, err error) {
+	key, value, ok := strings.Cut(s, "=")
+	if !ok || key == "" {
+		return "", "", usagef("invalid %s %q (want key=value)", flagName, s)
+	}
+	return key, value, nil
+}
+
+// setFields copies fields into sec. An empty value removes the field.
+func setFields(sec *secret.Secret, fields map[string]string) {
+	for k, v := range fields {
+		if v == "" {
+			delete(sec.Fields, k)
+		} else {
+			sec.Fields[k] = v
+		}
+	}
+}
+
+// newEmptySecret returns a new secret of type typ with its fields empty.
+func newEmptySecret(typ secret.Type, name string) (secret.Secret, error) {
+	switch typ {
+	case secret.TypePassword:
+		return secret.NewPassword(name, "", "", "")
+	case secret.TypeAPIKey:
+		return secret.NewAPIKey(name, "", "")
+	case secret.TypeSSHKey:
+		return secret.NewSSHKey(name, "", "", "")
+	default:
+		return secret.NewNote(name, "")
+	}
+}
+
+func runSecretGet(in *invocation) {
+	show := in.has("show")
 
 	v := openVault()
-	defer v.Close()
+	defer closeVault(v)
+
+	sec, err := resolveSecret(v, in.args[0])
+	if err != nil {
+		errf("%v", err)
+		exit(1)
+	}
+
+	if jsonOutput {
+		writeJSON(newSecretOutput(sec, show))
+		return
+	}
+	printSecretDetail(os.Stdout, sec, show)
+}
+
+func runSecretList(in *invocation) {
+	typ := in.value("type")
+	tag := in.value("tag")
+	lf, err := newListFormat(in, secretColumns, true)
+	if err != nil {
+		errf("%v", err)
+		exit(1)
+	}
+
+	v := openVault()
+	defer closeVault(v)
 
 	all, err := v.Secrets().List()
 	if err != nil {
 		errf("list secrets: %v", err)
-		os.Exit(1)
+		exit(1)
 	}
 
 	var filtered []secret.Secret
@@ -180,98 +556,160 @@ func runSecretList(args []string) {
 		filtered = append(filtered, sec)
 	}
 
-	if len(filtered) == 0 {
-		fmt.Fprintln(os.Stderr, muted("no secrets found"))
-		return
-	}
+	printSecrets(in, lf, filtered, "no secrets found")
+}
 
-	for _, sec := range filtered {
-		printSecretRow(sec)
+// printSecrets prints the secrets found by list or search, sorted and
+// limited by the flags: as JSON, with --format or --columns, or as rows.
+func printSecrets(in *invocation, lf *listFormat, secrets []secret.Secret, none string) {
+	if by := in.value("sort"); by != "" {
+		sortSecrets(secrets, by)
+	}
+	secrets = arrange(in, secrets)
+
+	switch {
+	case jsonOutput:
+		writeJSON(secretOutputs(secrets))
+	case lf != nil:
+		if err := printList(os.Stdout, lf, secretOutputs(secrets)); err != nil {
+			errf("format: %v", err)
+			exit(1)
+		}
+	case len(secrets) == 0:
+		fmt.Fprintln(os.Stderr, muted(none))
+	default:
+		for _, sec := range secrets {
+			printSecretRow(sec)
+		}
 	}
 }
 
-func runSecretDelete(args []string) {
-	if len(args) == 0 {
-		errf("secret ID required")
-		os.Exit(1)
+func runSecretDelete(in *invocation) {
+	yes := in.has("yes")
+	if jsonOutput && !yes {
+		errfCode(codeUsage, "--json needs --yes to delete without asking")
+		exit(1)
 	}
 
 	v := openVault()
-	defer v.Close()
+	defer closeVault(v)
 
-	sec, err := resolveSecret(v, args[0])
+	sec, err := resolveSecret(v, in.args[0])
 	if err != nil {
 		errf("%v", err)
-		os.Exit(1)
+		exit(1)
 	}
 
-	if !promptConfirm(fmt.Sprintf("delete %q?", sec.Name)) {
-		fmt.Fprintln(os.Stderr, "cancelled")
-		return
+	if !yes && !promptConfirm(fmt.Sprintf("delete %q?", sec.Name)) {
+		errf("cancelled")
+		exit(1)
 	}
 
 	if err := v.Secrets().Delete(sec.ID); err != nil {
 		errf("delete secret: %v", err)
-		os.Exit(1)
+		exit(1)
 	}
 
+	if jsonOutput {
+		writeJSON(deletedOutput{ID: sec.ID, Name: sec.Name, Deleted: true})
+		return
+	}
 	fmt.Printf("%s deleted\n", bold(sec.Name))
 }
 
-func runSecretSearch(args []string) {
-	if len(args) == 0 {
-		errf("search query required")
-		os.Exit(1)
+func runSecretSearch(in *invocation) {
+	query := strings.Join(in.args, " ")
+	lf, err := newListFormat(in, secretColumns, true)
+	if err != nil {
+		errf("%v", err)
+		exit(1)
 	}
 
-	query := strings.Join(args, " ")
-
 	v := openVault()
-	defer v.Close()
+	defer closeVault(v)
 
 	results, err := v.Secrets().Search(query)
 	if err != nil {
 		errf("search: %v", err)
-		os.Exit(1)
+		exit(1)
 	}
 
-	if len(results) == 0 {
-		fmt.Fprintln(os.Stderr, muted("no results"))
-		return
+	printSecrets(in, lf, results, "no results")
+}
+
+func runSecretCopy(in *invocation) {
+	v := openVault()
+	defer closeVault(v)
+
+	sec, err := resolveSecret(v, in.args[0])
+	if err != nil {
+		errf("%v", err)
+		exit(1)
+	}
+
+	field := in.value("field")
+	val, err := secretFieldValue(sec, field)
+	if err != nil {
+		errf("%v", err)
+		exit(1)
+	}
+	if val == "" {
+		errf("secret %q has an empty %s", sec.Name, cmp.Or(field, primaryField(sec.Type)))
+		exit(1)
 	}
 
-	for _, sec := range results {
-		printSecretRow(sec)
+	after := clearAfter(in)
+	if err := copyToClipboard(val, after); err != nil {
+		errf("%v", err)
+		exit(1)
 	}
+	fmt.Fprintf(os.Stderr, "%s %s\n", green("copied"), copiedNote(sec.Name, after))
 }
 
-// resolveSecret finds a secret by ID, name, or ID prefix.
-func resolveSecret(v *vault.Vault, idOrName string) (secret.Secret, error) {
-	// try by exact ID first
-	sec, err := v.Secrets().Get(idOrName)
-	if err == nil {
-		return sec, nil
+// copiedNote describes a copied value and when it will be cleared.
+func copiedNote(name string, after time.Duration) string {
+	if after <= 0 {
+		return bold(name)
 	}
+	return bold(name) + muted(fmt.Sprintf(" (clears in %s)", after))
+}
 
-	// try by name (case-insensitive) or ID prefix
-	all, err := v.Secrets().List()
+func runSecretQR(in *invocation) {
+	field := in.value("field")
+
+	v := openVault()
+	defer closeVault(v)
+
+	sec, err := resolveSecret(v, in.args[0])
 	if err != nil {
-		return secret.Secret{}, fmt.Errorf("list secrets: %w", err)
+		errf("%v", err)
+		exit(1)
 	}
 
-	for _, s := range all {
-		if strings.EqualFold(s.Name, idOrName) {
-			return s, nil
-		}
+	payload, err := qr.Payload(sec, field)
+	if err != nil {
+		errf("%v", err)
+		exit(1)
 	}
 
-	for _, s := range all {
-		if strings.HasPrefix(s.ID, idOrName) {
-			return s, nil
-		}
+	code, err := qr.Encode(payload, qr.M)
+	if err != nil {
+		errf("%v", err)
+		exit(1)
 	}
 
-	return secret.Secret{}, fmt.Errorf("secret %q not found", idOrName)
+	fmt.Print(code.Terminal())
+	fmt.Fprintln(os.Stderr, muted(sec.Name))
+}
+
+// resolveSecret finds a secret by reference (see vault.SecretStore.Resolve).
+// When the reference is ambiguous on a terminal, it asks which was meant.
+func resolveSecret(v *vault.Vault, ref string) (secret.Secret, error) {
+	sec, err := v.Secrets().Resolve(ref)
+	if id, ok := pickMatch(err); ok {
+		return v.Secrets().Get(id)
+	}
+	return sec, err
 }
 
 func printSecretRow(sec secret.Secret) {
@@ -292,56 +730,70 @@ func printSecretRow(sec secret.Secret) {
 	)
 }
 
-func printSecretDetail(sec secret.Secret, show bool) {
-	fmt.Printf("%s %s\n", boldMauve(sec.Name), muted(sec.ID))
-	fmt.Printf("  %s %s\n", muted("type:"), peach(string(sec.Type)))
+func printSecretDetail(w io.Writer, sec secret.Secret, show bool) {
+	fmt.Fprintf(w, "%s %s\n", boldMauve(sec.Name), muted(sec.ID))
+	fmt.Fprintf(w, "  %s %s\n", muted("type:"), peach(string(sec.Type)))
 
 	if len(sec.Tags) > 0 {
 		var parts []string
 		for _, t := range sec.Tags {
 			parts = append(parts, blue("#"+t))
 		}
-		fmt.Printf("  %s %s\n", muted("tags:"), strings.Join(parts, " "))
+		fmt.Fprintf(w, "  %s %s\n", muted("tags:"), strings.Join(parts, " "))
 	}
 
-	fmt.Printf("  %s %s\n", muted("created:"), sec.CreatedAt.Format("2006-01-02 15:04"))
+	fmt.Fprintf(w, "  %s %s\n", muted("created:"), sec.CreatedAt.Format("2006-01-02 15:04"))
 
 	mask := "********"
 
 	switch sec.Type {
 	case secret.TypePassword:
-		fmt.Printf("  %s %s\n", muted("url:"), sec.URL())
-		fmt.Printf("  %s %s\n", muted("username:"), sec.Username())
+		fmt.Fprintf(w, "  %s %s\n", muted("url:"), sec.URL())
+		fmt.Fprintf(w, "  %s %s\n", muted("username:"), sec.Username())
 		if show {
-			fmt.Printf("  %s %s\n", muted("password:"), sec.Password())
+			fmt.Fprintf(w, "  %s %s\n", muted("password:"), sec.Password())
 		} else {
-			fmt.Printf("  %s %s\n", muted("password:"), muted(mask))
+			fmt.Fprintf(w, "  %s %s\n", muted("password:"), muted(mask))
 		}
 
 	case secret.TypeAPIKey:
-		fmt.Printf("  %s %s\n", muted("service:"), sec.Service())
+		fmt.Fprintf(w, "  %s %s\n", muted("service:"), sec.Service())
 		if show {
-			fmt.Printf("  %s %s\n", muted("key:"), sec.Key())
+			fmt.Fprintf(w, "  %s %s\n", muted("key:"), sec.Key())
 		} else {
-			fmt.Printf("  %s %s\n", muted("key:"), muted(mask))
+			fmt.Fprintf(w, "  %s %s\n", muted("key:"), muted(mask))
 		}
 
 	case secret.TypeSSHKey:
-		fmt.Printf("  %s %s\n", muted("label:"), sec.Label())
+		fmt.Fprintf(w, "  %s %s\n", muted("label:"), sec.Label())
 		if show {
-			fmt.Printf("  %s\n%s\n", muted("private key:"), sec.PrivateKey())
-			fmt.Printf("  %s\n%s\n", muted("public key:"), sec.PublicKey())
+			fmt.Fprintf(w, "  %s\n%s\n", muted("private key:"), sec.PrivateKey())
+			fmt.Fprintf(w, "  %s\n%s\n", muted("public key:"), sec.PublicKey())
 		} else {
-			fmt.Printf("  %s %s\n", muted("private key:"), muted(mask))
-			fmt.Printf("  %s %s\n", muted("public key:"), muted(mask))
+			fmt.Fprintf(w, "  %s %s\n", muted("private key:"), muted(mask))
+			fmt.Fprintf(w, "  %s %s\n", muted("public key:"), muted(mask))
+		}
+		if sec.Fingerprint() != "" {
+			fmt.Fprintf(w, "  %s %s\n", muted("fingerprint:"), sshkey.Summary(sec))
 		}
 
 	case secret.TypeNote:
+		// env secrets and imported cards keep everything in fields
+		if sec.Content() != "" || len(sec.CustomFields()) == 0 {
+			if show {
+				fmt.Fprintf(w, "  %s\n%s\n", muted("content:"), sec.Content())
+			} else {
+				fmt.Fprintf(w, "  %s %s\n", muted("content:"), muted(mask))
+			}
+		}
+	}
+
+	for _, k := range sec.CustomFields() {
+		v := muted(mask)
 		if show {
-			fmt.Printf("  %s\n%s\n", muted("content:"), sec.Content())
-		} else {
-			fmt.Printf("  %s %s\n", muted("content:"), muted(mask))
+			v = sec.Fields[k]
 		}
+		fmt.Fprintf(w, "  %s %s\n", muted(strings.ReplaceAll(k, "_", " ")+":"), v)
 	}
 }
 
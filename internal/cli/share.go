@@ -0,0 +1,246 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"filippo.io/age"
+	"github.com/zarlcorp/zvault/internal/secret"
+	"github.com/zarlcorp/zvault/internal/share"
+	"github.com/zarlcorp/zvault/internal/sshkey"
+	"github.com/zarlcorp/zvault/internal/vault"
+)
+
+func shareCommand() *command {
+	return &command{
+		name:     "share",
+		summary:  "encrypt a secret for someone else",
+		args:     "<id-or-name>",
+		argNames: secretNames,
+		minArgs:  1,
+		maxArgs:  1,
+		help: `
+Encrypt one secret, with all of its fields, for someone else and print it
+as an ASCII-armored age file. They import it with zvault receive, or read
+it with age -d.
+
+Recipients:
+  age1...             an age public key
+  ssh-ed25519 ...     an ssh public key (ed25519 or rsa)
+  <file>              a file of recipients, one per line`,
+		flags: []*flag{
+			{name: "to", kind: stringsFlag, value: "<recipient>", usage: "encrypt to this recipient", files: true},
+			{name: "passphrase", kind: boolFlag, usage: "encrypt with a passphrase instead"},
+		},
+		run: runShare,
+	}
+}
+
+func runShare(in *invocation) {
+	to := in.values("to")
+	usePassphrase := in.has("passphrase")
+
+	if len(to) == 0 && !usePassphrase {
+		errfCode(codeUsage, "--to <recipient> or --passphrase required")
+		exit(1)
+	}
+	if len(to) > 0 && usePassphrase {
+		errfCode(codeUsage, "--passphrase can't be combined with --to")
+		exit(1)
+	}
+
+	var recipients []age.Recipient
+	for _, r := range to {
+		rs, err := share.ParseRecipients(r)
+		if err != nil {
+			errf("%v", err)
+			exit(1)
+		}
+		recipients = append(recipients, rs...)
+	}
+	if usePassphrase {
+		pass := promptPassword("share passphrase: ")
+		if pass == "" {
+			errf("passphrase cannot be empty")
+			exit(1)
+		}
+		if promptPassword("confirm passphrase: ") != pass {
+			errf("passphrases do not match")
+			exit(1)
+		}
+		r, err := share.PassphraseRecipient(pass)
+		if err != nil {
+			errf("%v", err)
+			exit(1)
+		}
+		recipients = append(recipients, r)
+	}
+
+	v := openVault()
+	defer closeVault(v)
+
+	sec, err := resolveSecret(v, in.args[0])
+	if err != nil {
+		errf("%v", err)
+		exit(1)
+	}
+	if err := share.Encrypt(os.Stdout, sec, recipients...); err != nil {
+		errf("%v", err)
+		exit(1)
+	}
+
+	if usePassphrase {
+		fmt.Fprintf(os.Stderr, "%s shared with a passphrase\n", bold(sec.Name))
+	} else {
+		fmt.Fprintf(os.Stderr, "%s shared with %d recipient(s)\n", bold(sec.Name), len(recipients))
+	}
+}
+
+func receiveCommand() *command {
+	return &command{
+		name:     "receive",
+		summary:  "import a secret made with share",
+		args:     "<file>",
+		minArgs:  1,
+		maxArgs:  1,
+		argFiles: true,
+		help: `
+Decrypt a secret made with zvault share and store it as a new secret.
+Use - to read from stdin.
+
+Without --identity, the ed25519 and rsa ssh keys in the vault and
+~/.ssh/id_ed25519 and ~/.ssh/id_rsa are tried. A secret shared with a
+passphrase asks for it.`,
+		flags: []*flag{
+			{name: "identity", kind: stringsFlag, value: "<file>", usage: "age identity file or ssh private key", files: true},
+			{name: "name", usage: "store under a different name"},
+		},
+		run: runReceive,
+	}
+}
+
+func runReceive(in *invocation) {
+	identityFiles := in.values("identity")
+	name := in.value("name")
+	path := in.args[0]
+
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		errf("%v", err)
+		exit(1)
+	}
+
+	v := openVault()
+	defer closeVault(v)
+
+	var identities []age.Identity
+	if share.Passphrase(data) {
+		id, err := share.PassphraseIdentity(promptPassword("share passphrase: "))
+		if err != nil {
+			errf("%v", err)
+			exit(1)
+		}
+		identities = append(identities, id)
+	} else {
+		identities = receiveIdentities(v, identityFiles)
+		if len(identities) == 0 {
+			errf("no keys to decrypt with; use --identity or store an ed25519 or rsa ssh key in the vault")
+			exit(1)
+		}
+	}
+
+	shared, err := share.Decrypt(data, identities...)
+	if err != nil {
+		errf("%v", err)
+		exit(1)
+	}
+
+	sec, err := shared.Clone()
+	if err != nil {
+		errf("%v", err)
+		exit(1)
+	}
+	if name != "" {
+		sec.Name = name
+	}
+	if sec.Type == secret.TypeSSHKey {
+		if err := sshkey.Validate(&sec); err != nil {
+			errf("%v", err)
+			exit(1)
+		}
+	}
+
+	if err := v.Secrets().Add(sec); err != nil {
+		errf("store secret: %v", err)
+		exit(1)
+	}
+	fmt.Printf("%s %s received\n", green(sec.ID), bold(sec.Name))
+}
+
+// receiveIdentities collects the keys a shared secret may be encrypted
+// to: the --identity files if given, otherwise the vault's ssh keys and
+// the default keys in ~/.ssh.
+func receiveIdentities(v *vault.Vault, files []string) []age.Identity {
+	var ids []age.Identity
+	addFile := func(path string, required bool) {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			if required {
+				errf("%v", err)
+				exit(1)
+			}
+			return
+		}
+		fileIDs, err := share.ParseIdentityFile(data, func() ([]byte, error) {
+			return []byte(promptPassword("passphrase for " + path + ": ")), nil
+		})
+		if err != nil {
+			if required {
+				errf("%s: %v", path, err)
+				exit(1)
+			}
+			return
+		}
+		ids = append(ids, fileIDs...)
+	}
+
+	if len(files) > 0 {
+		for _, f := range files {
+			addFile(f, true)
+		}
+		return ids
+	}
+
+	secrets, err := v.Secrets().List()
+	if err != nil {
+		errf("list secrets: %v", err)
+		exit(1)
+	}
+	for _, sec := range secrets {
+		if sec.Type != secret.TypeSSHKey {
+			continue
+		}
+		key, err := sshkey.ParsePrivateKey(sec.PrivateKey(), sec.Passphrase())
+		if err != nil {
+			continue
+		}
+		if id, err := share.SSHIdentity(key); err == nil {
+			ids = append(ids, id)
+		}
+	}
+
+	if home, err := os.UserHomeDir(); err == nil {
+		for _, name := range []string{"id_ed25519", "id_rsa"} {
+			addFile(filepath.Join(home, ".ssh", name), false)
+		}
+	}
+	return ids
+}
@@ -0,0 +1,579 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"golang.org/x/term"
+
+	"github.com/zarlcorp/zvault/internal/vault"
+)
+
+// shellCommands are the commands zvault shell runs. The rest either run
+// other programs (run, ssh-agent) or are called by them.
+var shellCommands = []string{"secret", "task", "otp", "export"}
+
+// defaultLockAfter is how long zvault shell may sit idle before it locks.
+const defaultLockAfter = 5 * time.Minute
+
+// shellHistorySize bounds the lines kept for the up arrow.
+const shellHistorySize = 200
+
+func shellCommand() *command {
+	return &command{
+		name:    "shell",
+		summary: "run secret, task, otp and export commands with one unlock",
+		help: `
+Unlock the vault once and run secret, task, otp and export commands
+without retyping the password, one per line and without the "zvault".
+Words are split like a POSIX shell, so quote names with spaces.
+
+  zvault> secret get 'bank login'
+  zvault> task add "renew passport" --due +30d
+  zvault> help secret store
+
+Tab completes commands, flags, secret names and task ids, and the arrow
+keys recall earlier lines. Lines that give a secret value on the command
+line (secret store --field, otp import-migration) are left out of that
+history, and nothing is written to disk.
+
+After --lock-after without a command the vault is locked, and the next
+command asks for the password again; 'lock' locks it at once. Leave with
+'exit', 'quit', Ctrl-D or Ctrl-C.
+
+On a terminal with TERM=dumb, or when stdin isn't a terminal, lines are
+read as typed, without editing, completion or history.`,
+		flags: []*flag{
+			{name: "lock-after", kind: durationFlag, usage: "lock the vault when idle this long; 0 never locks (default 5m)"},
+		},
+		run: runShell,
+	}
+}
+
+// shellExit is what exit panics with inside zvault shell, so a failing
+// command ends its line rather than the shell.
+type shellExit int
+
+// shell is a running zvault shell.
+type shell struct {
+	root      *command
+	lockAfter time.Duration
+	term      *term.Terminal // nil when reading plain lines
+	fd        int
+
+	mu    sync.Mutex
+	busy  bool // a command line is running
+	timer *time.Timer
+}
+
+func runShell(in *invocation) {
+	sh := &shell{root: in.cmd.parent, lockAfter: defaultLockAfter}
+	if in.given("lock-after") {
+		sh.lockAfter = in.duration("lock-after")
+	}
+
+	session = openVault()
+	defer sh.lock()
+
+	exit = func(code int) { panic(shellExit(code)) }
+	defer func() { exit = os.Exit }()
+
+	sh.fd = int(os.Stdin.Fd())
+	if term.IsTerminal(sh.fd) && os.Getenv("TERM") != "dumb" {
+		sh.term = term.NewTerminal(struct {
+			io.Reader
+			io.Writer
+		}{os.Stdin, os.Stdout}, "")
+		sh.term.History = &shellHistory{root: sh.root}
+		sh.term.AutoCompleteCallback = sh.complete
+	}
+	if sh.lockAfter > 0 {
+		sh.timer = time.AfterFunc(sh.lockAfter, sh.idle)
+		defer sh.timer.Stop()
+	}
+
+	for {
+		line, err := sh.readLine()
+		if err != nil {
+			if err != io.EOF {
+				errf("read: %v", err)
+			}
+			return
+		}
+		if !sh.runLine(line) {
+			return
+		}
+	}
+}
+
+// prompt says whether the vault is unlocked, in words rather than color.
+// The caller holds sh.mu, as the lock timer may close the session.
+func (sh *shell) prompt() string {
+	if session == nil {
+		return "zvault (locked)> "
+	}
+	return "zvault> "
+}
+
+// readLine reads a command line, with editing on a terminal.
+func (sh *shell) readLine() (string, error) {
+	if sh.term == nil {
+		return sh.readPlain()
+	}
+	old, err := term.MakeRaw(sh.fd)
+	if err != nil {
+		return "", err
+	}
+	defer term.Restore(sh.fd, old)
+	if w, h, err := term.GetSize(int(os.Stdout.Fd())); err == nil && w > 0 {
+		sh.term.SetSize(w, h)
+	}
+	sh.mu.Lock()
+	sh.term.SetPrompt(sh.prompt())
+	sh.mu.Unlock()
+	line, err := sh.term.ReadLine()
+	if err == term.ErrPasteIndicator {
+		err = nil // a pasted line is still a line
+	}
+	return line, err
+}
+
+// readPlain reads a line a byte at a time, leaving the rest of stdin to
+// the commands that read it (confirmations, --field-stdin).
+func (sh *shell) readPlain() (string, error) {
+	if term.IsTerminal(sh.fd) {
+		sh.mu.Lock()
+		fmt.Fprint(os.Stderr, sh.prompt())
+		sh.mu.Unlock()
+	}
+	var line []byte
+	b := make([]byte, 1)
+	for {
+		n, err := os.Stdin.Read(b)
+		if n == 1 {
+			if b[0] == '\n' {
+				return strings.TrimSuffix(string(line), "\r"), nil
+			}
+			line = append(line, b[0])
+			continue
+		}
+		if err != nil {
+			if err == io.EOF && len(line) > 0 {
+				return string(line), nil
+			}
+			return "", err
+		}
+	}
+}
+
+// runLine runs a command line. It reports false when the shell should end.
+func (sh *shell) runLine(line string) bool {
+	jsonOutput = false
+	words, open := splitLine(line)
+	if open {
+		errfCode(codeUsage, "unterminated quote")
+		return true
+	}
+	if len(words) == 0 {
+		return true
+	}
+	args := make([]string, len(words))
+	for i, w := range words {
+		args[i] = w.text
+	}
+
+	switch args[0] {
+	case "exit", "quit":
+		return false
+	case "lock":
+		sh.lock()
+		fmt.Fprintln(os.Stderr, "vault locked")
+		return true
+	case "help", "?":
+		if len(args) == 1 {
+			sh.writeHelp(os.Stderr)
+			return true
+		}
+		args[0] = "help"
+	}
+
+	sh.mu.Lock()
+	sh.busy = true
+	if sh.timer != nil {
+		sh.timer.Stop()
+	}
+	sh.mu.Unlock()
+	defer func() {
+		sh.mu.Lock()
+		sh.busy = false
+		if sh.timer != nil {
+			sh.timer.Reset(sh.lockAfter)
+		}
+		sh.mu.Unlock()
+	}()
+
+	sh.call(func() { sh.run(args) })
+	return true
+}
+
+// run runs one of shellCommands, or help, unlocking the vault first if
+// the shell has locked it.
+func (sh *shell) run(args []string) {
+	in, err := sh.root.parse(args)
+	jsonOutput = in.has("json")
+
+	top := in.cmd
+	for top.parent != nil && top.parent != sh.root {
+		top = top.parent
+	}
+	if top != sh.root && top.name != "help" && !slices.Contains(shellCommands, top.name) {
+		errfCode(codeUsage, "%s isn't available in zvault shell", top.name)
+		exit(1)
+	}
+	if err != nil {
+		errf("%v", err)
+		if !jsonOutput {
+			fmt.Fprintf(os.Stderr, "Run '%s --help' for usage.\n", strings.TrimPrefix(in.cmd.path(), "zvault "))
+		}
+		exit(1)
+	}
+
+	switch {
+	case top == sh.root:
+		sh.writeHelp(os.Stderr)
+	case in.has("help"):
+		in.cmd.writeHelp(os.Stderr)
+	case in.cmd.run == nil, len(in.cmd.subs) > 0 && len(in.args) == 0:
+		in.cmd.writeHelp(os.Stderr)
+	case top.name == "help":
+		in.cmd.run(in)
+	default:
+		if session == nil {
+			session = openVault()
+		}
+		in.cmd.run(in)
+	}
+}
+
+// call runs fn, recovering the exit of a failed command.
+func (sh *shell) call(fn func()) {
+	defer func() {
+		if r := recover(); r != nil {
+			if _, ok := r.(shellExit); !ok {
+				panic(r)
+			}
+		}
+	}()
+	fn()
+}
+
+// idle locks the vault when the lock timer fires.
+func (sh *shell) idle() {
+	if !sh.lock() {
+		return
+	}
+	msg := fmt.Sprintf("vault locked after %s idle\n", sh.lockAfter)
+	if sh.term == nil {
+		fmt.Fprint(os.Stderr, msg)
+		return
+	}
+	// redraws the prompt, now saying locked
+	sh.mu.Lock()
+	sh.term.SetPrompt(sh.prompt())
+	sh.mu.Unlock()
+	fmt.Fprint(sh.term, msg)
+}
+
+// lock closes the session, leaving a running command line alone. It
+// reports whether the vault was open.
+func (sh *shell) lock() bool {
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+	if sh.busy || session == nil {
+		return false
+	}
+	session.Close()
+	session = nil
+	return true
+}
+
+func (sh *shell) writeHelp(w io.Writer) {
+	var rows [][2]string
+	for _, name := range shellCommands {
+		rows = append(rows, [2]string{name, sh.root.sub(name).summary})
+	}
+	rows = append(rows,
+		[2]string{"help", "show this list, or help for a command (help secret store)"},
+		[2]string{"lock", "lock the vault; the next command asks for the password"},
+		[2]string{"exit", "leave the shell (also quit, Ctrl-D)"},
+	)
+	fmt.Fprint(w, "Commands:\n")
+	writeRows(w, rows)
+}
+
+// shellHistory is the line history of zvault shell. It lives in memory
+// only, and lines carrying secret values are never added.
+type shellHistory struct {
+	root  *command
+	lines []string // oldest first
+}
+
+func (h *shellHistory) Add(line string) {
+	words, open := splitLine(line)
+	if open || len(words) == 0 {
+		return
+	}
+	args := make([]string, len(words))
+	for i, w := range words {
+		args[i] = w.text
+	}
+	if carriesSecret(h.root, args) {
+		return
+	}
+	if n := len(h.lines); n > 0 && h.lines[n-1] == line {
+		return
+	}
+	h.lines = append(h.lines, line)
+	if len(h.lines) > shellHistorySize {
+		h.lines = h.lines[1:]
+	}
+}
+
+func (h *shellHistory) Len() int { return len(h.lines) }
+
+func (h *shellHistory) At(i int) string { return h.lines[len(h.lines)-1-i] }
+
+// carriesSecret reports whether a command line gives a value of a secret
+// flag, or a positional argument of a command with secretArgs.
+func carriesSecret(root *command, args []string) bool {
+	for i := range args {
+		at, ok := completionAt(root, args[:i+1])
+		switch {
+		case !ok:
+			return true // another command line; it could hold anything
+		case at.flag != nil:
+			if at.flag.secret {
+				return true
+			}
+		case at.flagName:
+		case at.cmd.secretArgs:
+			return true
+		}
+	}
+	return false
+}
+
+// shellWord is a word of a command line, and the byte offsets it spans
+// in the line, quotes included.
+type shellWord struct {
+	text       string
+	start, end int
+}
+
+// splitLine splits a command line into words like a POSIX shell, minus
+// expansions: blanks separate words, single and double quotes group them
+// and a backslash escapes the next character (inside double quotes, only
+// a quote or backslash). open reports a quote left unterminated; the last
+// word then runs to the end of the line.
+func splitLine(line string) (words []shellWord, open bool) {
+	var (
+		cur    strings.Builder
+		in     bool // inside a word
+		quote  byte // the open quote, or 0
+		start  int
+		escape bool
+	)
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		if !in {
+			if c == ' ' || c == '\t' {
+				continue
+			}
+			in, start = true, i
+		}
+		switch {
+		case escape:
+			if quote == '"' && c != '"' && c != '\\' {
+				cur.WriteByte('\\')
+			}
+			cur.WriteByte(c)
+			escape = false
+		case c == '\\' && quote != '\'':
+			escape = true
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			} else {
+				cur.WriteByte(c)
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == ' ' || c == '\t':
+			words = append(words, shellWord{text: cur.String(), start: start, end: i})
+			cur.Reset()
+			in = false
+		default:
+			cur.WriteByte(c)
+		}
+	}
+	if in {
+		words = append(words, shellWord{text: cur.String(), start: start, end: len(line)})
+	}
+	return words, quote != 0 || escape
+}
+
+// shellQuote quotes s as a single word when it needs it. With partial,
+// the closing quote is left off, so typing can carry on inside it.
+func shellQuote(s string, partial bool) string {
+	if s != "" && !strings.ContainsAny(s, " \t'\"\\") {
+		return s
+	}
+	q := "'" + strings.ReplaceAll(s, "'", `'\''`)
+	if partial {
+		return q
+	}
+	return q + "'"
+}
+
+// complete is the terminal's tab handler. One candidate replaces the word
+// before the cursor; several extend it to what they have in common, or
+// are listed when it can't be extended.
+func (sh *shell) complete(line string, pos int, key rune) (string, int, bool) {
+	sh.mu.Lock()
+	if sh.timer != nil && !sh.busy {
+		sh.timer.Reset(sh.lockAfter) // typing isn't idle
+	}
+	sh.mu.Unlock()
+	if key != '\t' {
+		return "", 0, false
+	}
+	head, tail := line[:pos], line[pos:]
+
+	words, _ := splitLine(head)
+	var args []string
+	for _, w := range words {
+		args = append(args, w.text)
+	}
+	start := pos
+	if n := len(words); n > 0 && words[n-1].end == len(head) {
+		start = words[n-1].start
+	} else {
+		args = append(args, "")
+	}
+
+	cands := shellCandidates(sh.root, args)
+	if len(cands) == 0 {
+		return "", 0, false
+	}
+	word := args[len(args)-1]
+
+	var repl string
+	switch common := commonPrefix(cands); {
+	case len(cands) == 1:
+		repl = shellQuote(cands[0][0], false)
+		if !strings.HasSuffix(repl, "=") && !strings.HasSuffix(repl, ",") {
+			repl += " "
+		}
+	case len(common) > len(word):
+		repl = shellQuote(common, true)
+	default:
+		var b strings.Builder
+		writeRows(&b, cands)
+		var list strings.Builder
+		for line := range strings.Lines(b.String()) {
+			list.WriteString(strings.TrimRight(line, " \n") + "\n")
+		}
+		fmt.Fprint(sh.term, list.String())
+		return "", 0, false
+	}
+	return head[:start] + repl + tail, start + len(repl), true
+}
+
+// shellCandidates returns what the last of args completes to, with
+// descriptions: a command, a flag, a flag value or a name from the vault.
+func shellCandidates(root *command, args []string) [][2]string {
+	at, ok := completionAt(root, args)
+	if !ok {
+		return nil
+	}
+	var out [][2]string
+	add := func(word, desc string) {
+		if strings.HasPrefix(word, at.word) {
+			out = append(out, [2]string{word, desc})
+		}
+	}
+	addNames := func(kind nameKind) {
+		if kind == noNames {
+			return
+		}
+		names, err := vault.ReadNames(vault.DefaultDir())
+		if err != nil {
+			return
+		}
+		for _, c := range nameCompletions(names, kind, at.word) {
+			word, desc, _ := strings.Cut(c, "\t")
+			out = append(out, [2]string{word, desc})
+		}
+	}
+
+	switch {
+	case at.flag != nil:
+		for _, w := range at.flag.words() {
+			add(w, "")
+		}
+		addNames(at.flag.names)
+		if last := args[len(args)-1]; strings.HasPrefix(last, "--") {
+			// --flag=value: complete the whole word
+			i := strings.IndexByte(last, '=')
+			for j := range out {
+				out[j][0] = last[:i+1] + out[j][0]
+			}
+		}
+	case at.flagName:
+		for _, f := range at.cmd.allFlags() {
+			name := "--" + f.name
+			if f.kind != boolFlag {
+				name += "="
+			}
+			add(name, f.usage)
+		}
+	case at.positional == 0:
+		if at.cmd == root {
+			for _, name := range shellCommands {
+				add(name, root.sub(name).summary)
+			}
+			add("help", "show help")
+			add("lock", "lock the vault")
+			add("exit", "leave the shell")
+			return out
+		}
+		for _, sub := range at.cmd.visibleSubs() {
+			add(sub.name, sub.summary)
+		}
+		for _, w := range at.cmd.argChoices {
+			add(w, "")
+		}
+		addNames(at.cmd.argNames)
+	}
+	return out
+}
+
+// commonPrefix returns the longest prefix of every candidate.
+func commonPrefix(cands [][2]string) string {
+	p := cands[0][0]
+	for _, c := range cands[1:] {
+		for !strings.HasPrefix(c[0], p) {
+			p = p[:len(p)-1]
+		}
+	}
+	for !utf8.ValidString(p) {
+		p = p[:len(p)-1] // don't split a character
+	}
+	return p
+}
@@ -0,0 +1,132 @@
+package cli
+
+import (
+	"os"
+	"slices"
+	"testing"
+)
+
+func TestSplitLine(t *testing.T) {
+	tests := []struct {
+		line string
+		want []string
+		open bool
+	}{
+		{"", nil, false},
+		{"  secret  list ", []string{"secret", "list"}, false},
+		{`secret get 'bank login'`, []string{"secret", "get", "bank login"}, false},
+		{`task add "say \"hi\"" --due +3d`, []string{"task", "add", `say "hi"`, "--due", "+3d"}, false},
+		{`secret get bank\ login`, []string{"secret", "get", "bank login"}, false},
+		{`secret get 'it'\''s'`, []string{"secret", "get", "it's"}, false},
+		{`secret get "a\b"`, []string{"secret", "get", `a\b`}, false},
+		{`secret get ''`, []string{"secret", "get", ""}, false},
+		{`secret get 'bank lo`, []string{"secret", "get", "bank lo"}, true},
+		{`secret get bank\`, []string{"secret", "get", "bank"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			words, open := splitLine(tt.line)
+			var got []string
+			for _, w := range words {
+				got = append(got, w.text)
+			}
+			if !slices.Equal(got, tt.want) || open != tt.open {
+				t.Errorf("splitLine = %q, %v, want %q, %v", got, open, tt.want, tt.open)
+			}
+		})
+	}
+
+	words, _ := splitLine(`get  'a b' c`)
+	if w := words[1]; w.start != 5 || w.end != 10 {
+		t.Errorf("'a b' spans %d-%d, want 5-10", w.start, w.end)
+	}
+}
+
+func TestShellQuote(t *testing.T) {
+	for _, s := range []string{"github", "bank login", "it's", `a"b`, `back\slash`, ""} {
+		words, open := splitLine(shellQuote(s, false))
+		if open || len(words) != 1 || words[0].text != s {
+			t.Errorf("shellQuote(%q) = %s, which splits to %v", s, shellQuote(s, false), words)
+		}
+	}
+	if got := shellQuote("bank lo", true); got != "'bank lo" {
+		t.Errorf("partial quote = %s, want 'bank lo", got)
+	}
+}
+
+func TestShellHistory(t *testing.T) {
+	h := &shellHistory{root: rootCommand("test")}
+	lines := []string{
+		"secret get github",
+		"secret store -t password -n github --field password=hunter2",
+		"secret edit github --field=password=hunter2",
+		"secret edit github --field-stdin password",
+		"otp import-migration otpauth-migration://offline?data=abc",
+		"task add 'unterminated",
+		"secret get github",
+		"otp github --copy",
+	}
+	for _, l := range lines {
+		h.Add(l)
+	}
+
+	var got []string
+	for i := h.Len() - 1; i >= 0; i-- {
+		got = append(got, h.At(i))
+	}
+	want := []string{
+		"secret get github",
+		"secret edit github --field-stdin password",
+		"secret get github",
+		"otp github --copy",
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("history = %q, want %q", got, want)
+	}
+}
+
+func TestShellCandidates(t *testing.T) {
+	root := rootCommand("test")
+	words := func(args ...string) []string {
+		var out []string
+		for _, c := range shellCandidates(root, args) {
+			out = append(out, c[0])
+		}
+		return out
+	}
+
+	// only the commands the shell runs
+	if got := words(""); !slices.Equal(got, []string{"secret", "task", "otp", "export", "help", "lock", "exit"}) {
+		t.Errorf("commands = %q", got)
+	}
+	if got := words("s"); !slices.Equal(got, []string{"secret"}) {
+		t.Errorf("s = %q", got)
+	}
+	if got := words("secret", "st"); !slices.Equal(got, []string{"store"}) {
+		t.Errorf("secret st = %q", got)
+	}
+	if got := words("secret", "list", "--ty"); !slices.Equal(got, []string{"--type="}) {
+		t.Errorf("secret list --ty = %q", got)
+	}
+	if got := words("secret", "list", "--type=n"); !slices.Equal(got, []string{"--type=note"}) {
+		t.Errorf("secret list --type=n = %q", got)
+	}
+	if got := words("secret", "store", "-t", "a"); !slices.Equal(got, []string{"apikey"}) {
+		t.Errorf("secret store -t a = %q", got)
+	}
+}
+
+func TestShellCallRecoversExit(t *testing.T) {
+	defer func() { exit = os.Exit }()
+	exit = func(code int) { panic(shellExit(code)) }
+
+	sh := &shell{}
+	ran := false
+	sh.call(func() {
+		exit(1)
+		ran = true
+	})
+	if ran {
+		t.Error("exit returned")
+	}
+}
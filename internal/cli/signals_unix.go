@@ -0,0 +1,24 @@
+//go:build !windows
+
+package cli
+
+import (
+	"os"
+	"syscall"
+)
+
+// forwardSignals are passed on to commands started by zvault run.
+var forwardSignals = []os.Signal{
+	syscall.SIGTERM,
+	syscall.SIGHUP,
+	syscall.SIGUSR1,
+	syscall.SIGUSR2,
+}
+
+// terminalSignals come from the terminal, which sends them to the whole
+// foreground process group: the command gets them directly, and zvault
+// drops them so that it doesn't exit before the command does.
+var terminalSignals = []os.Signal{
+	syscall.SIGINT,
+	syscall.SIGQUIT,
+}
@@ -0,0 +1,12 @@
+package cli
+
+import "os"
+
+// forwardSignals are passed on to commands started by zvault run. Windows
+// can't send signals to another process.
+var forwardSignals []os.Signal
+
+// terminalSignals come from the console, which sends Ctrl-C to every
+// process attached to it: the command gets it directly, and zvault drops
+// it so that it doesn't exit before the command does.
+var terminalSignals = []os.Signal{os.Interrupt}
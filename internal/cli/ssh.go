@@ -0,0 +1,97 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/zarlcorp/zvault/internal/secret"
+	"github.com/zarlcorp/zvault/internal/sshkey"
+)
+
+func sshCommand() *command {
+	return &command{
+		name:    "ssh",
+		summary: "generate ssh keys in the vault",
+		subs: []*command{
+			{
+				name:    "keygen",
+				summary: "generate a key pair and store it",
+				help:    "The public key is printed on stdout, ready for authorized_keys or a Git host.",
+				flags: []*flag{
+					{name: "name", short: 'n', usage: "secret name (required)"},
+					{name: "type", usage: "key type, default ed25519", choices: []string{sshkey.TypeEd25519, sshkey.TypeRSA, sshkey.TypeECDSA}},
+					{name: "bits", kind: intFlag, usage: "rsa: 2048-16384 (default 3072); ecdsa: 256, 384, 521"},
+					{name: "comment", value: "<text>", usage: "key comment (default: the secret name)"},
+					{name: "passphrase", kind: boolFlag, usage: "prompt for a passphrase to encrypt the private key"},
+					{name: "tags", value: "<a,b>", usage: "tags for the secret", names: secretTags},
+				},
+				run: runSSHKeygen,
+			},
+		},
+	}
+}
+
+func runSSHKeygen(in *invocation) {
+	name := in.value("name")
+	typ := in.value("type")
+	comment := in.value("comment")
+	tags := parseTags(in.value("tags"))
+
+	if name == "" {
+		errfCode(codeUsage, "secret name required (-n <name>)")
+		exit(1)
+	}
+	if typ == "" {
+		typ = sshkey.TypeEd25519
+	}
+	if comment == "" {
+		comment = name
+	}
+
+	bits := in.int("bits")
+	if in.given("bits") && bits <= 0 {
+		errfCode(codeUsage, "invalid key size %d", bits)
+		exit(1)
+	}
+
+	var passphrase string
+	if in.has("passphrase") {
+		passphrase = promptPassword("key passphrase: ")
+		if passphrase != promptPassword("confirm passphrase: ") {
+			errf("passphrases do not match")
+			exit(1)
+		}
+	}
+
+	// open first so a wrong vault password doesn't waste an rsa keygen
+	v := openVault()
+	defer closeVault(v)
+
+	pair, err := sshkey.Generate(typ, bits, comment, passphrase)
+	if err != nil {
+		errf("%v", err)
+		exit(1)
+	}
+
+	sec, err := secret.NewSSHKey(name, comment, pair.PrivateKey, pair.PublicKey)
+	if err != nil {
+		errf("create secret: %v", err)
+		exit(1)
+	}
+	if passphrase != "" {
+		sec.Fields["passphrase"] = passphrase
+	}
+	if err := sshkey.Validate(&sec); err != nil {
+		errf("invalid ssh key: %v", err)
+		exit(1)
+	}
+	sec.Tags = tags
+
+	if err := v.Secrets().Add(sec); err != nil {
+		errf("store secret: %v", err)
+		exit(1)
+	}
+
+	fmt.Fprintf(os.Stderr, "%s %s stored\n", green(sec.ID), bold(sec.Name))
+	fmt.Println(pair.PublicKey)
+}
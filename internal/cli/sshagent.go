@@ -0,0 +1,154 @@
+package cli
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/zarlcorp/zvault/internal/secret"
+	"github.com/zarlcorp/zvault/internal/sshagent"
+	"github.com/zarlcorp/zvault/internal/vault"
+)
+
+// confirmTag marks ssh key secrets that always need confirmation per use.
+const confirmTag = "ssh-confirm"
+
+func sshAgentCommand() *command {
+	return &command{
+		name:    "ssh-agent",
+		summary: "serve ssh keys from the vault to ssh",
+		help: `
+Serve sshkey secrets over the OpenSSH agent protocol. Encrypted keys are
+decrypted with their stored passphrase. The agent runs in the foreground
+(in its own terminal or as a user service) and prints the socket to use:
+
+  export SSH_AUTH_SOCK=$XDG_RUNTIME_DIR/zvault-agent.sock
+
+Keys tagged "ssh-confirm" always ask before signing. Confirmation uses
+$SSH_ASKPASS when set, otherwise the agent's terminal.`,
+		flags: []*flag{
+			{name: "socket", value: "<path>", usage: "socket path (default $XDG_RUNTIME_DIR/zvault-agent.sock)", files: true},
+			{name: "tag", usage: "only serve keys with this tag", names: secretTags},
+			{name: "confirm", kind: boolFlag, usage: "ask before every signature"},
+			{name: "lifetime", kind: durationFlag, usage: "forget keys after this long (e.g. 30m, 8h)"},
+		},
+		run: runSSHAgent,
+	}
+}
+
+func runSSHAgent(in *invocation) {
+	socket := in.value("socket")
+	tag := in.value("tag")
+	confirmAll := in.has("confirm")
+
+	lifetime := in.duration("lifetime")
+	if in.given("lifetime") && lifetime < time.Second {
+		errfCode(codeUsage, "invalid lifetime %s (use a duration like 30m or 8h)", lifetime)
+		exit(1)
+	}
+
+	if socket == "" {
+		socket = defaultAgentSocket()
+	}
+
+	v := openVault()
+	all, err := v.Secrets().List()
+	closeVault(v) // keys live in the agent from here on
+	if err != nil {
+		errf("list secrets: %v", err)
+		exit(1)
+	}
+
+	a := sshagent.New(confirmSignature)
+	loaded := 0
+	for _, sec := range all {
+		if sec.Type != secret.TypeSSHKey {
+			continue
+		}
+		if tag != "" && !containsTag(sec.Tags, tag) {
+			continue
+		}
+		confirm := confirmAll || containsTag(sec.Tags, confirmTag)
+		if err := a.AddSecret(sec, lifetime, confirm); err != nil {
+			fmt.Fprintf(os.Stderr, "%s %v\n", yellow("skip"), err)
+			continue
+		}
+		note := ""
+		if confirm {
+			note = muted(" (confirm)")
+		}
+		fmt.Fprintf(os.Stderr, "%s %s%s\n", green("added"), sec.Name, note)
+		loaded++
+	}
+
+	if loaded == 0 {
+		errf("no ssh keys to serve")
+		exit(1)
+	}
+
+	l, err := sshagent.Listen(socket)
+	if err != nil {
+		errf("%v", err)
+		exit(1)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	fmt.Printf("SSH_AUTH_SOCK=%s; export SSH_AUTH_SOCK;\n", socket)
+	fmt.Fprintln(os.Stderr, muted(fmt.Sprintf("serving %d key(s) — ctrl+c to stop", loaded)))
+
+	if err := sshagent.Serve(ctx, l, a); err != nil {
+		errf("%v", err)
+		exit(1)
+	}
+}
+
+// defaultAgentSocket prefers the per-user runtime directory, falling back
+// to the vault directory, which is already private to the user.
+func defaultAgentSocket() string {
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		return filepath.Join(dir, "zvault-agent.sock")
+	}
+	return filepath.Join(vault.DefaultDir(), "agent.sock")
+}
+
+// confirmMu serialises prompts from concurrent connections.
+var confirmMu sync.Mutex
+
+// confirmSignature asks whether a key may be used, via $SSH_ASKPASS in the
+// same way ssh-agent -c does, or on the controlling terminal.
+func confirmSignature(comment string) bool {
+	confirmMu.Lock()
+	defer confirmMu.Unlock()
+
+	prompt := fmt.Sprintf("allow use of key %s?", comment)
+
+	if askpass := os.Getenv("SSH_ASKPASS"); askpass != "" {
+		cmd := exec.Command(askpass, prompt)
+		cmd.Env = append(os.Environ(), "SSH_ASKPASS_PROMPT=confirm")
+		return cmd.Run() == nil
+	}
+
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return false
+	}
+	defer tty.Close()
+
+	fmt.Fprint(tty, yellow(prompt)+" [y/N] ")
+	line, err := bufio.NewReader(tty).ReadString('\n')
+	if err != nil {
+		return false
+	}
+	ans := strings.TrimSpace(strings.ToLower(line))
+	return ans == "y" || ans == "yes"
+}
@@ -7,84 +7,112 @@ import (
 	"time"
 
 	"github.com/zarlcorp/zvault/internal/task"
+	"github.com/zarlcorp/zvault/internal/vault"
 )
 
-func runTask(args []string) {
-	if len(args) == 0 {
-		printTaskUsage()
-		os.Exit(1)
-	}
-
-	switch args[0] {
-	case "add":
-		runTaskAdd(args[1:])
-	case "list", "ls":
-		runTaskList(args[1:])
-	case "done":
-		runTaskDone(args[1:])
-	case "edit":
-		runTaskEdit(args[1:])
-	case "rm":
-		runTaskRm(args[1:])
-	case "clear":
-		runTaskClear()
-	case "help", "--help", "-h":
-		printTaskUsage()
-	default:
-		// bare ID: show task detail
-		runTaskDetail(args[0])
+// priorities are completed for -p; parsePriority also takes the long names.
+var priorities = []string{"h", "m", "l"}
+
+func taskCommand() *command {
+	return &command{
+		name:     "task",
+		summary:  "manage tasks (add, list, done, edit, rm, clear)",
+		args:     "<id>",
+		argNames: taskIDs,
+		minArgs:  1,
+		maxArgs:  1,
+		help: `
+With an id instead of a command, show that task.
+
+Wherever a task <id> is taken, it may also be an id prefix of at least 4
+characters, the task's title or part of it, or either qualified by a tag
+as tag:title or tag/title. A reference matching several tasks is an
+error listing them, or on a terminal a prompt to pick one.
+
+With the global --json flag, add, list, done, edit and <id> print tasks
+as JSON objects.`,
+		run: runTaskDetail,
+		subs: []*command{
+			{
+				name:    "add",
+				summary: "add a new task",
+				args:    "<title>...",
+				minArgs: 1,
+				maxArgs: unlimited,
+				flags: []*flag{
+					{name: "priority", short: 'p', value: "<h|m|l>", usage: "priority (high, medium, low)", suggest: priorities},
+					{name: "due", short: 'd', value: "<date>", usage: "due date (YYYY-MM-DD, tomorrow, next week, +3d)"},
+					{name: "tags", value: "<a,b>", usage: "optional tags", names: taskTags},
+				},
+				run: runTaskAdd,
+			},
+			{
+				name:    "list",
+				aliases: []string{"ls"},
+				summary: "list tasks",
+				help:    listFormatHelp,
+				flags: append([]*flag{
+					{name: "pending", kind: boolFlag, usage: "show only pending tasks"},
+					{name: "done", kind: boolFlag, usage: "show only completed tasks"},
+					{name: "priority", short: 'p', value: "<h|m|l>", usage: "filter by priority", suggest: priorities},
+					{name: "tag", usage: "filter by tag", names: taskTags},
+				}, listFlags(taskSorts, taskColumns)...),
+				run: runTaskList,
+			},
+			{
+				name:     "done",
+				summary:  "mark task(s) complete",
+				args:     "<id>[,<id>...]",
+				argNames: taskIDs,
+				minArgs:  1,
+				maxArgs:  1,
+				run:      runTaskDone,
+			},
+			{
+				name:     "edit",
+				summary:  "rename a task",
+				args:     "<id> <title>...",
+				argNames: taskIDs,
+				minArgs:  2,
+				maxArgs:  unlimited,
+				run:      runTaskEdit,
+			},
+			{
+				name:     "rm",
+				summary:  "delete task(s)",
+				args:     "<id>[,<id>...]",
+				argNames: taskIDs,
+				minArgs:  1,
+				maxArgs:  1,
+				run:      runTaskRm,
+			},
+			{
+				name:    "clear",
+				summary: "delete all completed tasks",
+				run:     runTaskClear,
+			},
+		},
 	}
 }
 
-func printTaskUsage() {
-	fmt.Fprint(os.Stderr, `Usage: zvault task <command>
-
-Commands:
-  add     add a new task
-  list    list tasks (alias: ls)
-  done    mark task(s) complete
-  edit    rename a task
-  rm      delete task(s)
-  clear   delete all completed tasks
-  <id>    show task detail
-
-Add flags:
-  -p <h|m|l>        priority (high, medium, low)
-  -d <date>          due date (YYYY-MM-DD, tomorrow, next week, +3d)
-  --tags tag1,tag2   optional tags
-
-List flags:
-  --pending          show only pending tasks
-  --done             show only completed tasks
-  -p <h|m|l>        filter by priority
-  --tag <tag>        filter by tag
-`)
-}
-
-func runTaskAdd(args []string) {
-	pri := flagValue(args, "-p")
-	dueStr := flagValue(args, "-d")
-	tags := parseTags(flagValue(args, "--tags"))
-
-	pos := stripFlags(args, []string{"-p", "-d", "--tags"}, nil)
-	if len(pos) == 0 {
-		errf("task title required")
-		os.Exit(1)
-	}
+func runTaskAdd(in *invocation) {
+	pri := in.value("priority")
+	dueStr := in.value("due")
+	tags := parseTags(in.value("tags"))
 
-	title := strings.Join(pos, " ")
+	title := strings.Join(in.args, " ")
 	tk, err := task.New(title)
 	if err != nil {
 		errf("create task: %v", err)
-		os.Exit(1)
+		exit(1)
 	}
 	tk.Tags = tags
 
 	if pri != "" {
 		p, ok := parsePriority(pri)
 		if !ok {
-			errf("invalid priority %q (use h, m, or l)", pri)
-			os.Exit(1)
+			errfCode(codeUsage, "invalid priority %q (use h, m, or l)", pri)
+			exit(1)
 		}
 		tk.Priority = p
 	}
@@ -93,79 +121,97 @@ func runTaskAdd(args []string) {
 		due, err := parseDate(dueStr)
 		if err != nil {
 			errf("%v", err)
-			os.Exit(1)
+			exit(1)
 		}
 		tk.DueDate = &due
 	}
 
 	v := openVault()
-	defer v.Close()
+	defer closeVault(v)
 
 	if err := v.Tasks().Add(tk); err != nil {
 		errf("add task: %v", err)
-		os.Exit(1)
+		exit(1)
 	}
 
+	if jsonOutput {
+		writeJSON(newTaskOutput(tk))
+		return
+	}
 	fmt.Printf("%s %s\n", green(tk.ID), title)
 }
 
-func runTaskList(args []string) {
+func runTaskList(in *invocation) {
 	var f task.Filter
 
-	if hasFlag(args, "--pending") {
+	if in.has("pending") {
 		f.Status = task.FilterPending
-	} else if hasFlag(args, "--done") {
+	} else if in.has("done") {
 		f.Status = task.FilterDone
 	}
 
-	pri := flagValue(args, "-p")
+	pri := in.value("priority")
 	if pri != "" {
 		p, ok := parsePriority(pri)
 		if !ok {
-			errf("invalid priority %q (use h, m, or l)", pri)
-			os.Exit(1)
+			errfCode(codeUsage, "invalid priority %q (use h, m, or l)", pri)
+			exit(1)
 		}
 		f.Priority = p
 	}
 
-	f.Tag = flagValue(args, "--tag")
+	f.Tag = in.value("tag")
+
+	lf, err := newListFormat(in, taskColumns, false)
+	if err != nil {
+		errf("%v", err)
+		exit(1)
+	}
 
 	v := openVault()
-	defer v.Close()
+	defer closeVault(v)
 
 	tasks, err := v.Tasks().List(f)
 	if err != nil {
 		errf("list tasks: %v", err)
-		os.Exit(1)
+		exit(1)
 	}
 
-	if len(tasks) == 0 {
-		fmt.Fprintln(os.Stderr, muted("no tasks found"))
-		return
+	if by := in.value("sort"); by != "" {
+		sortTasks(tasks, by)
 	}
+	tasks = arrange(in, tasks)
 
-	for _, tk := range tasks {
-		printTaskRow(tk)
+	switch {
+	case jsonOutput:
+		writeJSON(taskOutputs(tasks))
+	case lf != nil:
+		if err := printList(os.Stdout, lf, taskOutputs(tasks)); err != nil {
+			errf("format: %v", err)
+			exit(1)
+		}
+	case len(tasks) == 0:
+		fmt.Fprintln(os.Stderr, muted("no tasks found"))
+	default:
+		for _, tk := range tasks {
+			printTaskRow(tk)
+		}
 	}
 }
 
-func runTaskDone(args []string) {
-	if len(args) == 0 {
-		errf("task ID(s) required")
-		os.Exit(1)
-	}
-
-	ids := parseIDs(args[0])
+func runTaskDone(in *invocation) {
+	ids := parseIDs(in.args[0])
 
 	v := openVault()
-	defer v.Close()
+	defer closeVault(v)
 
 	now := time.Now()
+	var completed []task.Task
 	for _, id := range ids {
-		tk, err := v.Tasks().Get(id)
+		tk, err := resolveTask(v, id)
 		if err != nil {
-			errf("task %q not found", id)
-			os.Exit(1)
+			errf("%v", err)
+			exit(1)
 		}
 
 		tk.Done = true
@@ -173,71 +219,99 @@ func runTaskDone(args []string) {
 
 		if err := v.Tasks().Update(tk); err != nil {
 			errf("update task: %v", err)
-			os.Exit(1)
+			exit(1)
 		}
 
-		fmt.Printf("%s %s\n", green("[x]"), tk.Title)
+		completed = append(completed, tk)
+		if !jsonOutput {
+			fmt.Printf("%s %s\n", green("[x]"), tk.Title)
+		}
 	}
-}
-
-func runTaskEdit(args []string) {
-	if len(args) < 2 {
-		errf("usage: zvault task edit <id> <new title>")
-		os.Exit(1)
+	if jsonOutput {
+		writeJSON(taskOutputs(completed))
 	}
+}
 
-	id := args[0]
-	title := strings.Join(args[1:], " ")
+func runTaskEdit(in *invocation) {
+	id := in.args[0]
+	title := strings.Join(in.args[1:], " ")
 
 	v := openVault()
-	defer v.Close()
+	defer closeVault(v)
 
-	tk, err := v.Tasks().Get(id)
+	tk, err := resolveTask(v, id)
 	if err != nil {
-		errf("task %q not found", id)
-		os.Exit(1)
+		errf("%v", err)
+		exit(1)
 	}
 
 	tk.Title = title
 
 	if err := v.Tasks().Update(tk); err != nil {
 		errf("update task: %v", err)
-		os.Exit(1)
+		exit(1)
 	}
 
+	if jsonOutput {
+		writeJSON(newTaskOutput(tk))
+		return
+	}
 	fmt.Printf("%s %s\n", muted(tk.ID), title)
 }
 
-func runTaskRm(args []string) {
-	if len(args) == 0 {
-		errf("task ID(s) required")
-		os.Exit(1)
-	}
-
-	ids := parseIDs(args[0])
+func runTaskRm(in *invocation) {
+	ids := parseIDs(in.args[0])
 
 	v := openVault()
-	defer v.Close()
+	defer closeVault(v)
 
+	deleted := []deletedOutput{}
 	for _, id := range ids {
-		if err := v.Tasks().Delete(id); err != nil {
+		tk, err := resolveTask(v, id)
+		if err != nil {
+			errf("%v", err)
+			exit(1)
+		}
+		if err := v.Tasks().Delete(tk.ID); err != nil {
 			errf("delete task %q: %v", id, err)
-			os.Exit(1)
+			exit(1)
 		}
-		fmt.Printf("%s deleted\n", muted(id))
+		deleted = append(deleted, deletedOutput{ID: tk.ID, Deleted: true})
+		if !jsonOutput {
+			fmt.Printf("%s deleted\n", muted(tk.ID))
+		}
+	}
+	if jsonOutput {
+		writeJSON(deleted)
+	}
+}
+
+// resolveTask finds a task by reference (see vault.TaskStore.Resolve),
+// asking which was meant when the reference is ambiguous on a terminal.
+func resolveTask(v *vault.Vault, ref string) (task.Task, error) {
+	tk, err := v.Tasks().Resolve(ref)
+	if id, ok := pickMatch(err); ok {
+		return v.Tasks().Get(id)
 	}
+	return tk, err
 }
 
-func runTaskClear() {
+func runTaskClear(*invocation) {
 	v := openVault()
-	defer v.Close()
+	defer closeVault(v)
 
 	count, err := v.Tasks().ClearDone()
 	if err != nil {
 		errf("clear done: %v", err)
-		os.Exit(1)
+		exit(1)
 	}
 
+	if jsonOutput {
+		writeJSON(struct {
+			Cleared int `json:"cleared"`
+		}{count})
+		return
+	}
 	if count == 0 {
 		fmt.Fprintln(os.Stderr, muted("no completed tasks to clear"))
 		return
@@ -246,16 +320,22 @@ func runTaskClear() {
 	fmt.Printf("cleared %d completed task(s)\n", count)
 }
 
-func runTaskDetail(id string) {
+func runTaskDetail(in *invocation) {
+	id := in.args[0]
+
 	v := openVault()
-	defer v.Close()
+	defer closeVault(v)
 
-	tk, err := v.Tasks().Get(id)
+	tk, err := resolveTask(v, id)
 	if err != nil {
-		errf("task %q not found", id)
-		os.Exit(1)
+		errf("%v", err)
+		exit(1)
 	}
 
+	if jsonOutput {
+		writeJSON(newTaskOutput(tk))
+		return
+	}
 	printTaskDetail(tk)
 }
 
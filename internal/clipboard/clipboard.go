@@ -0,0 +1,78 @@
+// Package clipboard copies values to the system clipboard and clears them
+// again, but only while the clipboard still holds what was copied.
+package clipboard
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	"github.com/zarlcorp/core/pkg/zclipboard"
+)
+
+// Copy writes s to the system clipboard.
+func Copy(s string) error {
+	return zclipboard.Copy(s)
+}
+
+// Read returns the text on the system clipboard.
+func Read() (string, error) {
+	var cmd *exec.Cmd
+
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("pbpaste")
+	case "linux":
+		if _, err := exec.LookPath("xclip"); err == nil {
+			cmd = exec.Command("xclip", "-selection", "clipboard", "-o")
+		} else if _, err := exec.LookPath("xsel"); err == nil {
+			cmd = exec.Command("xsel", "--clipboard", "--output")
+		} else {
+			return "", fmt.Errorf("no clipboard tool: install xclip or xsel")
+		}
+	case "windows":
+		cmd = exec.Command("powershell", "-NoProfile", "-Command", "Get-Clipboard -Raw")
+	default:
+		return "", fmt.Errorf("clipboard not supported on %s", runtime.GOOS)
+	}
+
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("clipboard: %w", err)
+	}
+	return out.String(), nil
+}
+
+// Sum returns the hash a copied value is recognised by, so the value
+// itself needn't be kept around until the clipboard is cleared. Trailing
+// line breaks are ignored, as some clipboard tools add one.
+func Sum(s string) string {
+	h := sha256.Sum256([]byte(strings.TrimRight(s, "\r\n")))
+	return hex.EncodeToString(h[:])
+}
+
+// ClearIf empties the clipboard if it still holds the value with hash sum,
+// leaving anything copied since alone. It reports whether it cleared.
+func ClearIf(sum string) (bool, error) {
+	return clearIf(sum, Read, zclipboard.Clear)
+}
+
+func clearIf(sum string, read func() (string, error), clear func() error) (bool, error) {
+	cur, err := read()
+	if err != nil {
+		return false, err
+	}
+	if subtle.ConstantTimeCompare([]byte(Sum(cur)), []byte(sum)) != 1 {
+		return false, nil
+	}
+	if err := clear(); err != nil {
+		return false, err
+	}
+	return true, nil
+}
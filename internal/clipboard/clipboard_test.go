@@ -0,0 +1,48 @@
+package clipboard
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestSum(t *testing.T) {
+	if Sum("hunter2") != Sum("hunter2\n") || Sum("hunter2") != Sum("hunter2\r\n") {
+		t.Error("trailing line breaks should not change the sum")
+	}
+	if Sum("hunter2") == Sum("hunter3") {
+		t.Error("different values have the same sum")
+	}
+	if Sum(" hunter2") == Sum("hunter2") {
+		t.Error("leading space should change the sum")
+	}
+}
+
+func TestClearIf(t *testing.T) {
+	tests := []struct {
+		name    string
+		current string
+		readErr error
+		want    bool
+	}{
+		{"still ours", "hunter2", nil, true},
+		{"tool added a newline", "hunter2\n", nil, true},
+		{"copied over", "something else", nil, false},
+		{"already empty", "", nil, false},
+		{"unreadable", "", errors.New("no clipboard"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cleared := false
+			read := func() (string, error) { return tt.current, tt.readErr }
+			clear := func() error { cleared = true; return nil }
+
+			got, err := clearIf(Sum("hunter2"), read, clear)
+			if (err != nil) != (tt.readErr != nil) {
+				t.Fatalf("err = %v", err)
+			}
+			if got != tt.want || cleared != tt.want {
+				t.Errorf("clearIf = %v, cleared = %v, want %v", got, cleared, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,321 @@
+// Package dotenv parses and formats .env files.
+//
+// Supported syntax: KEY=value lines, an optional "export " prefix, blank
+// lines and # comments (whole-line, or after whitespace in an unquoted
+// value). Double-quoted values may span lines and understand \n, \r, \t,
+// \", \\ and \$ escapes; single- and backtick-quoted values are literal and
+// may span lines. Variables are not expanded.
+package dotenv
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+)
+
+// Entry is one KEY=value assignment.
+type Entry struct {
+	Key   string
+	Value string
+}
+
+// Output formats accepted by Format.
+const (
+	FormatDotenv = "dotenv"
+	FormatShell  = "shell"
+	FormatFish   = "fish"
+	FormatJSON   = "json"
+)
+
+// Formats lists the output formats, for usage text and completion.
+var Formats = []string{FormatDotenv, FormatShell, FormatFish, FormatJSON}
+
+// ParseError reports a syntax error and the line it starts on.
+type ParseError struct {
+	Line int
+	Msg  string
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Msg)
+}
+
+// Parse reads entries in file order. A repeated key keeps its first
+// position and takes its last value.
+func Parse(r io.Reader) ([]Entry, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{src: strings.ReplaceAll(string(data), "\r\n", "\n"), line: 1}
+	return p.parse()
+}
+
+type parser struct {
+	src  string
+	pos  int
+	line int
+}
+
+func (p *parser) eof() bool { return p.pos >= len(p.src) }
+
+func (p *parser) peek() byte { return p.src[p.pos] }
+
+func (p *parser) next() byte {
+	c := p.src[p.pos]
+	p.pos++
+	if c == '\n' {
+		p.line++
+	}
+	return c
+}
+
+func (p *parser) errorf(line int, format string, args ...any) error {
+	return &ParseError{Line: line, Msg: fmt.Sprintf(format, args...)}
+}
+
+func (p *parser) skipBlanks() {
+	for !p.eof() && (p.peek() == ' ' || p.peek() == '\t') {
+		p.pos++
+	}
+}
+
+func (p *parser) skipLine() {
+	for !p.eof() && p.peek() != '\n' {
+		p.pos++
+	}
+	if !p.eof() {
+		p.next()
+	}
+}
+
+func (p *parser) parse() ([]Entry, error) {
+	var entries []Entry
+	index := make(map[string]int)
+
+	for {
+		// skip whitespace, blank lines and comments
+		for !p.eof() {
+			c := p.peek()
+			if c == ' ' || c == '\t' || c == '\n' {
+				p.next()
+				continue
+			}
+			if c == '#' {
+				p.skipLine()
+				continue
+			}
+			break
+		}
+		if p.eof() {
+			return entries, nil
+		}
+
+		e, err := p.entry()
+		if err != nil {
+			return nil, err
+		}
+		if i, ok := index[e.Key]; ok {
+			entries[i].Value = e.Value
+			continue
+		}
+		index[e.Key] = len(entries)
+		entries = append(entries, e)
+	}
+}
+
+func (p *parser) entry() (Entry, error) {
+	line := p.line
+
+	if strings.HasPrefix(p.src[p.pos:], "export") {
+		rest := p.src[p.pos+len("export"):]
+		if rest != "" && (rest[0] == ' ' || rest[0] == '\t') {
+			p.pos += len("export")
+			p.skipBlanks()
+		}
+	}
+
+	start := p.pos
+	for !p.eof() && isKeyByte(p.peek(), p.pos == start) {
+		p.pos++
+	}
+	key := p.src[start:p.pos]
+	if key == "" {
+		return Entry{}, p.errorf(line, "expected a variable name")
+	}
+
+	p.skipBlanks()
+	if p.eof() || p.peek() != '=' {
+		return Entry{}, p.errorf(line, "expected = after %s", key)
+	}
+	p.pos++
+	p.skipBlanks()
+
+	value, err := p.value()
+	if err != nil {
+		return Entry{}, err
+	}
+	return Entry{Key: key, Value: value}, nil
+}
+
+func isKeyByte(c byte, first bool) bool {
+	switch {
+	case c == '_', c >= 'A' && c <= 'Z', c >= 'a' && c <= 'z':
+		return true
+	case c >= '0' && c <= '9', c == '.', c == '-':
+		return !first
+	}
+	return false
+}
+
+func (p *parser) value() (string, error) {
+	if p.eof() {
+		return "", nil
+	}
+
+	switch q := p.peek(); q {
+	case '"', '\'', '`':
+		line := p.line
+		p.next()
+		var b strings.Builder
+		for {
+			if p.eof() {
+				return "", p.errorf(line, "unterminated %c quote", q)
+			}
+			c := p.next()
+			if c == q {
+				break
+			}
+			if c == '\\' && q == '"' && !p.eof() {
+				b.WriteString(unescape(p.next()))
+				continue
+			}
+			b.WriteByte(c)
+		}
+
+		// only a comment may follow a quoted value
+		p.skipBlanks()
+		if !p.eof() && p.peek() != '\n' && p.peek() != '#' {
+			return "", p.errorf(p.line, "unexpected text after closing quote")
+		}
+		p.skipLine()
+		return b.String(), nil
+	}
+
+	start := p.pos
+	end := start
+	for !p.eof() && p.peek() != '\n' {
+		c := p.peek()
+		if c == '#' && p.pos > start && (p.src[p.pos-1] == ' ' || p.src[p.pos-1] == '\t') {
+			break
+		}
+		p.pos++
+		if c != ' ' && c != '\t' {
+			end = p.pos
+		}
+	}
+	value := p.src[start:end]
+	p.skipLine()
+	return value, nil
+}
+
+func unescape(c byte) string {
+	switch c {
+	case 'n':
+		return "\n"
+	case 'r':
+		return "\r"
+	case 't':
+		return "\t"
+	case '"', '\\', '$', '`':
+		return string(c)
+	}
+	return "\\" + string(c)
+}
+
+// ShellName reports whether key can be exported by a shell: a letter or
+// underscore, then letters, digits and underscores. Keys with . or -
+// are fine in a .env file but not in the shell and fish formats.
+func ShellName(key string) bool {
+	for i := 0; i < len(key); i++ {
+		if c := key[i]; c == '.' || c == '-' || !isKeyByte(c, i == 0) {
+			return false
+		}
+	}
+	return key != ""
+}
+
+// Format writes entries in one of the output formats. The shell and fish
+// formats leave out keys that aren't a ShellName.
+func Format(w io.Writer, entries []Entry, format string) error {
+	var b strings.Builder
+	switch format {
+	case "", FormatDotenv:
+		for _, e := range entries {
+			fmt.Fprintf(&b, "%s=%s\n", e.Key, quoteDotenv(e.Value))
+		}
+	case FormatShell:
+		for _, e := range entries {
+			if ShellName(e.Key) {
+				fmt.Fprintf(&b, "export %s=%s\n", e.Key, quoteShell(e.Value))
+			}
+		}
+	case FormatFish:
+		for _, e := range entries {
+			if ShellName(e.Key) {
+				fmt.Fprintf(&b, "set -gx %s %s\n", e.Key, quoteFish(e.Value))
+			}
+		}
+	case FormatJSON:
+		// keep file order rather than the sorted order of a map
+		b.WriteString("{")
+		for i, e := range entries {
+			if i > 0 {
+				b.WriteString(",")
+			}
+			k, _ := json.Marshal(e.Key)
+			v, _ := json.Marshal(e.Value)
+			fmt.Fprintf(&b, "\n  %s: %s", k, v)
+		}
+		if len(entries) > 0 {
+			b.WriteString("\n")
+		}
+		b.WriteString("}\n")
+	default:
+		return fmt.Errorf("unknown format %q (use %s)", format, strings.Join(Formats, ", "))
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// quoteDotenv leaves simple values bare and double-quotes the rest, so
+// Parse reads back exactly the same value.
+func quoteDotenv(v string) string {
+	if v != "" && !strings.ContainsFunc(v, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("_-./:@,+%=", r))
+	}) {
+		return v
+	}
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`, "\t", `\t`, "$", `\$`, "`", "\\`")
+	return `"` + r.Replace(v) + `"`
+}
+
+func quoteShell(v string) string {
+	return "'" + strings.ReplaceAll(v, "'", `'\''`) + "'"
+}
+
+func quoteFish(v string) string {
+	r := strings.NewReplacer(`\`, `\\`, `'`, `\'`)
+	return "'" + r.Replace(v) + "'"
+}
+
+// Lookup returns the value of key.
+func Lookup(entries []Entry, key string) (string, bool) {
+	i := slices.IndexFunc(entries, func(e Entry) bool { return e.Key == key })
+	if i < 0 {
+		return "", false
+	}
+	return entries[i].Value, true
+}
@@ -0,0 +1,170 @@
+package dotenv
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	in := `# service config
+PLAIN=value
+export EXPORTED=yes
+  SPACED = padded value
+EMPTY=
+INLINE=abc # comment
+HASH=abc#def
+SINGLE='literal $HOME \n'
+DOUBLE="tab\tnew\nline \"quoted\" \$HOME \\ \q"
+BACKTICK=` + "`it's`" + `
+MULTI="first
+second"
+MULTI_SINGLE='a
+b' # trailing comment
+DUP=one
+DUP=two
+dotted.key-name=ok
+WINDOWS=crlf` + "\r\n"
+
+	got, err := Parse(strings.NewReader(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Entry{
+		{"PLAIN", "value"},
+		{"EXPORTED", "yes"},
+		{"SPACED", "padded value"},
+		{"EMPTY", ""},
+		{"INLINE", "abc"},
+		{"HASH", "abc#def"},
+		{"SINGLE", `literal $HOME \n`},
+		{"DOUBLE", "tab\tnew\nline \"quoted\" $HOME \\ \\q"},
+		{"BACKTICK", "it's"},
+		{"MULTI", "first\nsecond"},
+		{"MULTI_SINGLE", "a\nb"},
+		{"DUP", "two"},
+		{"dotted.key-name", "ok"},
+		{"WINDOWS", "crlf"},
+	}
+	if !slices.Equal(got, want) {
+		for i := range max(len(got), len(want)) {
+			var g, w Entry
+			if i < len(got) {
+				g = got[i]
+			}
+			if i < len(want) {
+				w = want[i]
+			}
+			if g != w {
+				t.Errorf("entry %d = %q, want %q", i, g, w)
+			}
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		in   string
+		line int
+	}{
+		{"A=1\nnot an assignment\n", 2},
+		{"A=1\n\nB=\"open\nstill open\n", 3},
+		{"=value\n", 1},
+		{"A='x' trailing\n", 1},
+		{"1ABC=x\n", 1},
+	}
+
+	for _, tt := range tests {
+		_, err := Parse(strings.NewReader(tt.in))
+		var pe *ParseError
+		if !errors.As(err, &pe) {
+			t.Errorf("Parse(%q) error = %v, want ParseError", tt.in, err)
+			continue
+		}
+		if pe.Line != tt.line {
+			t.Errorf("Parse(%q) line = %d, want %d", tt.in, pe.Line, tt.line)
+		}
+	}
+}
+
+func TestFormat(t *testing.T) {
+	entries := []Entry{
+		{"A", "simple"},
+		{"B", "it's a \"test\"\nline $HOME"},
+		{"C", ""},
+		{"app.name", "skipped by shells"},
+	}
+
+	tests := []struct {
+		format, want string
+	}{
+		{FormatDotenv, "A=simple\nB=\"it's a \\\"test\\\"\\nline \\$HOME\"\nC=\"\"\napp.name=\"skipped by shells\"\n"},
+		{FormatShell, "export A='simple'\nexport B='it'\\''s a \"test\"\nline $HOME'\nexport C=''\n"},
+		{FormatFish, "set -gx A 'simple'\nset -gx B 'it\\'s a \"test\"\nline $HOME'\nset -gx C ''\n"},
+		{FormatJSON, "{\n  \"A\": \"simple\",\n  \"B\": \"it's a \\\"test\\\"\\nline $HOME\",\n  \"C\": \"\",\n  \"app.name\": \"skipped by shells\"\n}\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			var b strings.Builder
+			if err := Format(&b, entries, tt.format); err != nil {
+				t.Fatal(err)
+			}
+			if b.String() != tt.want {
+				t.Errorf("got:\n%s\nwant:\n%s", b.String(), tt.want)
+			}
+		})
+	}
+
+	if err := Format(&strings.Builder{}, entries, "yaml"); err == nil {
+		t.Error("expected error for unknown format")
+	}
+}
+
+func TestShellName(t *testing.T) {
+	for key, want := range map[string]bool{
+		"PATH":     true,
+		"_x1":      true,
+		"app.name": false,
+		"my-key":   false,
+		"1ABC":     false,
+		"":         false,
+	} {
+		if got := ShellName(key); got != want {
+			t.Errorf("ShellName(%q) = %v, want %v", key, got, want)
+		}
+	}
+}
+
+func TestFormatRoundTrip(t *testing.T) {
+	entries := []Entry{
+		{"URL", "postgres://u:p@db:5432/app?sslmode=require"},
+		{"CERT", "-----BEGIN CERTIFICATE-----\nMIIB\n-----END CERTIFICATE-----"},
+		{"WEIRD", "a # b 'c' \"d\" \\e `f` $g\t"},
+		{"EMPTY", ""},
+	}
+
+	var b strings.Builder
+	if err := Format(&b, entries, FormatDotenv); err != nil {
+		t.Fatal(err)
+	}
+	got, err := Parse(strings.NewReader(b.String()))
+	if err != nil {
+		t.Fatalf("parse formatted output: %v\n%s", err, b.String())
+	}
+	if !slices.Equal(got, entries) {
+		t.Errorf("round trip = %q, want %q", got, entries)
+	}
+}
+
+func TestLookup(t *testing.T) {
+	entries := []Entry{{"A", "1"}, {"B", "2"}}
+	if v, ok := Lookup(entries, "B"); !ok || v != "2" {
+		t.Errorf("Lookup(B) = %q, %v", v, ok)
+	}
+	if _, ok := Lookup(entries, "C"); ok {
+		t.Error("Lookup(C) should report missing")
+	}
+}
@@ -0,0 +1,225 @@
+package importer
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/zarlcorp/zvault/internal/secret"
+)
+
+// Bitwarden item types.
+const (
+	bwLogin      = 1
+	bwSecureNote = 2
+	bwCard       = 3
+	bwIdentity   = 4
+	bwSSHKey     = 5
+)
+
+// bwLinkedField is a custom field that points at another field instead of
+// holding a value.
+const bwLinkedField = 3
+
+type bwExport struct {
+	Encrypted   bool           `json:"encrypted"`
+	Folders     []bwFolder     `json:"folders"`
+	Collections []bwCollection `json:"collections"`
+	Items       []bwItem       `json:"items"`
+}
+
+type bwFolder struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type bwCollection struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type bwItem struct {
+	Type          int            `json:"type"`
+	Name          string         `json:"name"`
+	Notes         string         `json:"notes"`
+	FolderID      string         `json:"folderId"`
+	CollectionIDs []string       `json:"collectionIds"`
+	Fields        []bwField      `json:"fields"`
+	Login         *bwLoginData   `json:"login"`
+	Card          map[string]any `json:"card"`
+	Identity      map[string]any `json:"identity"`
+	SSHKey        *bwSSHKeyData  `json:"sshKey"`
+	CreationDate  time.Time      `json:"creationDate"`
+	RevisionDate  time.Time      `json:"revisionDate"`
+	DeletedDate   *time.Time     `json:"deletedDate"`
+}
+
+type bwField struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+	Type  int    `json:"type"`
+}
+
+type bwLoginData struct {
+	URIs     []bwURI `json:"uris"`
+	Username string  `json:"username"`
+	Password string  `json:"password"`
+	TOTP     string  `json:"totp"`
+}
+
+type bwURI struct {
+	URI string `json:"uri"`
+}
+
+type bwSSHKeyData struct {
+	PrivateKey string `json:"privateKey"`
+	PublicKey  string `json:"publicKey"`
+}
+
+// bwCardFields and bwIdentityFields map Bitwarden's keys to field names,
+// in display order.
+var bwCardFields = [][2]string{
+	{"cardholderName", "cardholder"},
+	{"brand", "brand"},
+	{"number", "number"},
+	{"expMonth", "exp_month"},
+	{"expYear", "exp_year"},
+	{"code", "code"},
+}
+
+var bwIdentityFields = [][2]string{
+	{"title", "title"},
+	{"firstName", "first_name"},
+	{"middleName", "middle_name"},
+	{"lastName", "last_name"},
+	{"username", "username"},
+	{"company", "company"},
+	{"email", "email"},
+	{"phone", "phone"},
+	{"address1", "address1"},
+	{"address2", "address2"},
+	{"address3", "address3"},
+	{"city", "city"},
+	{"state", "state"},
+	{"postalCode", "postal_code"},
+	{"country", "country"},
+	{"ssn", "ssn"},
+	{"passportNumber", "passport_number"},
+	{"licenseNumber", "license_number"},
+}
+
+// parseBitwarden reads an unencrypted Bitwarden JSON export, personal or
+// organization.
+func parseBitwarden(r io.Reader) (Result, error) {
+	var exp bwExport
+	if err := json.NewDecoder(r).Decode(&exp); err != nil {
+		return Result{}, fmt.Errorf("parse bitwarden export: %w", err)
+	}
+	if exp.Encrypted {
+		return Result{}, fmt.Errorf("encrypted bitwarden exports are not supported; export as unencrypted json")
+	}
+
+	folders := make(map[string]string)
+	for _, f := range exp.Folders {
+		folders[f.ID] = f.Name
+	}
+	for _, c := range exp.Collections {
+		folders[c.ID] = c.Name
+	}
+
+	var res Result
+	for _, item := range exp.Items {
+		if item.DeletedDate != nil {
+			continue
+		}
+		sec, err := bitwardenSecret(item, &res)
+		if err != nil {
+			res.skipf("%s: %v", item.Name, err)
+			continue
+		}
+
+		if f := folders[item.FolderID]; f != "" {
+			addTag(&sec, f)
+		}
+		for _, id := range item.CollectionIDs {
+			if c := folders[id]; c != "" {
+				addTag(&sec, c)
+			}
+		}
+		for _, f := range item.Fields {
+			if f.Type != bwLinkedField {
+				setField(&sec, f.Name, f.Value)
+			}
+		}
+		setTimes(&sec, item.CreationDate, item.RevisionDate)
+		res.Secrets = append(res.Secrets, sec)
+	}
+	return res, nil
+}
+
+func bitwardenSecret(item bwItem, res *Result) (secret.Secret, error) {
+	switch item.Type {
+	case bwLogin:
+		var login bwLoginData
+		if item.Login != nil {
+			login = *item.Login
+		}
+		var uri string
+		if len(login.URIs) > 0 {
+			uri = login.URIs[0].URI
+		}
+		sec, err := newLogin(item.Name, uri, login.Username, login.Password)
+		if err != nil {
+			return secret.Secret{}, err
+		}
+		for i, u := range login.URIs[min(1, len(login.URIs)):] {
+			setField(&sec, "url_"+strconv.Itoa(i+2), u.URI)
+		}
+		if err := setTOTP(&sec, login.TOTP); err != nil {
+			res.warnf("%s: totp not imported: %v", item.Name, err)
+		}
+		setField(&sec, "notes", item.Notes)
+		return sec, nil
+
+	case bwSecureNote:
+		return newNote(item.Name, item.Notes, "")
+
+	case bwCard:
+		sec, err := newNote(item.Name, item.Notes, "card")
+		if err != nil {
+			return secret.Secret{}, err
+		}
+		setMapFields(&sec, item.Card, bwCardFields)
+		return sec, nil
+
+	case bwIdentity:
+		sec, err := newNote(item.Name, item.Notes, "identity")
+		if err != nil {
+			return secret.Secret{}, err
+		}
+		setMapFields(&sec, item.Identity, bwIdentityFields)
+		return sec, nil
+
+	case bwSSHKey:
+		if item.SSHKey == nil {
+			return secret.Secret{}, fmt.Errorf("ssh key item has no key")
+		}
+		sec, err := secret.NewSSHKey(item.Name, "", item.SSHKey.PrivateKey, item.SSHKey.PublicKey)
+		if err != nil {
+			return secret.Secret{}, err
+		}
+		setField(&sec, "notes", item.Notes)
+		return sec, nil
+	}
+	return secret.Secret{}, fmt.Errorf("unsupported item type %d", item.Type)
+}
+
+func setMapFields(sec *secret.Secret, m map[string]any, keys [][2]string) {
+	for _, k := range keys {
+		if v, ok := m[k[0]].(string); ok {
+			setField(sec, k[1], v)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package importer
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zarlcorp/zvault/internal/secret"
+)
+
+func parseFile(t *testing.T, format, path string) Result {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	res, err := Parse(format, f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return res
+}
+
+func byName(t *testing.T, res Result, name string) secret.Secret {
+	t.Helper()
+	for _, s := range res.Secrets {
+		if s.Name == name {
+			return s
+		}
+	}
+	t.Fatalf("no secret named %q", name)
+	return secret.Secret{}
+}
+
+func TestParseBitwarden(t *testing.T) {
+	res := parseFile(t, BitwardenJSON, "testdata/bitwarden.json")
+
+	// deleted item skipped, unknown type warned about
+	if len(res.Secrets) != 5 {
+		t.Fatalf("got %d secrets, want 5", len(res.Secrets))
+	}
+
+	gh := byName(t, res, "GitHub")
+	if gh.Type != secret.TypePassword || gh.URL() != "https://github.com/login" || gh.Username() != "alice" || gh.Password() != "gh-pass" {
+		t.Errorf("login = %+v", gh.Fields)
+	}
+	want := map[string]string{
+		"url_2":           "https://gist.github.com",
+		"notes":           "recovery codes in the safe",
+		"recovery_email":  "alice@example.com",
+		"custom_password": "old-one",
+		"totp_secret":     "JBSWY3DPEHPK3PXP",
+		"totp_issuer":     "GitHub",
+		"totp_digits":     "8",
+	}
+	for k, v := range want {
+		if gh.Fields[k] != v {
+			t.Errorf("GitHub %s = %q, want %q", k, gh.Fields[k], v)
+		}
+	}
+	if strings.Join(gh.Tags, ",") != "work-stuff" {
+		t.Errorf("tags = %q", gh.Tags)
+	}
+	if !gh.CreatedAt.Equal(time.Date(2023, 4, 1, 10, 0, 0, 0, time.UTC)) || !gh.UpdatedAt.Equal(time.Date(2024, 2, 3, 4, 5, 6, 0, time.UTC)) {
+		t.Errorf("times = %v, %v", gh.CreatedAt, gh.UpdatedAt)
+	}
+
+	note := byName(t, res, "Wifi")
+	if note.Type != secret.TypeNote || note.Content() != "SSID home / pass hunter2" {
+		t.Errorf("note = %+v", note)
+	}
+
+	card := byName(t, res, "Visa")
+	if card.Type != secret.TypeNote || card.Fields["number"] != "4111111111111111" || card.Fields["exp_year"] != "2030" || card.Tags[0] != "card" {
+		t.Errorf("card = %+v %q", card.Fields, card.Tags)
+	}
+
+	id := byName(t, res, "Passport")
+	if id.Fields["passport_number"] != "X123" || id.Tags[0] != "identity" {
+		t.Errorf("identity = %+v", id.Fields)
+	}
+
+	bank := byName(t, res, "Bank")
+	if bank.TOTPSecret() != "" {
+		t.Error("steam totp should not be imported")
+	}
+
+	if len(res.Skipped) != 1 || len(res.Warnings) != 1 {
+		t.Errorf("skipped = %q, warnings = %q", res.Skipped, res.Warnings)
+	}
+}
+
+func TestParseBitwardenEncrypted(t *testing.T) {
+	_, err := Parse(BitwardenJSON, strings.NewReader(`{"encrypted": true, "items": []}`))
+	if err == nil || !strings.Contains(err.Error(), "encrypted") {
+		t.Errorf("err = %v", err)
+	}
+}
@@ -0,0 +1,243 @@
+package importer
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/zarlcorp/zvault/internal/secret"
+)
+
+// csvTable is a CSV file read as rows keyed by lowercased header.
+type csvTable struct {
+	header []string
+	rows   [][]string
+}
+
+func readCSV(r io.Reader) (csvTable, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	records, err := cr.ReadAll()
+	if err != nil {
+		return csvTable{}, fmt.Errorf("parse csv: %w", err)
+	}
+	if len(records) == 0 {
+		return csvTable{}, errors.New("csv file is empty")
+	}
+
+	header := make([]string, len(records[0]))
+	for i, h := range records[0] {
+		// Excel and some managers write a byte order mark
+		h = strings.TrimPrefix(h, "\ufeff")
+		header[i] = strings.ToLower(strings.TrimSpace(h))
+	}
+	return csvTable{header: header, rows: records[1:]}, nil
+}
+
+// col returns the index of the first of names in the header, or -1.
+func (t csvTable) col(names ...string) int {
+	for _, n := range names {
+		for i, h := range t.header {
+			if h == n {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+func (t csvTable) require(names ...string) error {
+	for _, n := range names {
+		if t.col(n) < 0 {
+			return fmt.Errorf("csv has no %q column", n)
+		}
+	}
+	return nil
+}
+
+// cell returns a trimmed cell, for names, urls, usernames and the like.
+func cell(row []string, i int) string {
+	return strings.TrimSpace(rawCell(row, i))
+}
+
+// rawCell returns a cell as written, for passwords, notes and other
+// values whose spaces may matter.
+func rawCell(row []string, i int) string {
+	if i < 0 || i >= len(row) {
+		return ""
+	}
+	return row[i]
+}
+
+// parseChrome reads a Chrome, Edge or Brave password export:
+// name,url,username,password[,note].
+func parseChrome(r io.Reader) (Result, error) {
+	t, err := readCSV(r)
+	if err != nil {
+		return Result{}, err
+	}
+	if err := t.require("name", "url", "username", "password"); err != nil {
+		return Result{}, err
+	}
+	name, url, user, pass, note := t.col("name"), t.col("url"), t.col("username"), t.col("password"), t.col("note")
+
+	var res Result
+	for i, row := range t.rows {
+		sec, err := newLogin(cell(row, name), cell(row, url), cell(row, user), rawCell(row, pass))
+		if err != nil {
+			res.skipf("row %d: %v", i+2, err)
+			continue
+		}
+		setField(&sec, "notes", rawCell(row, note))
+		res.Secrets = append(res.Secrets, sec)
+	}
+	return res, nil
+}
+
+// parseFirefox reads a Firefox password export. It has no entry names, so
+// logins are named after their host; times are in milliseconds.
+func parseFirefox(r io.Reader) (Result, error) {
+	t, err := readCSV(r)
+	if err != nil {
+		return Result{}, err
+	}
+	if err := t.require("url", "username", "password"); err != nil {
+		return Result{}, err
+	}
+	url, user, pass := t.col("url"), t.col("username"), t.col("password")
+	created, changed := t.col("timecreated"), t.col("timepasswordchanged")
+
+	var res Result
+	for i, row := range t.rows {
+		sec, err := newLogin("", cell(row, url), cell(row, user), rawCell(row, pass))
+		if err != nil {
+			res.skipf("row %d: %v", i+2, err)
+			continue
+		}
+		setTimes(&sec, unixMillis(cell(row, created)), unixMillis(cell(row, changed)))
+		res.Secrets = append(res.Secrets, sec)
+	}
+	return res, nil
+}
+
+func unixMillis(s string) time.Time {
+	ms, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || ms <= 0 {
+		return time.Time{}
+	}
+	return time.UnixMilli(ms)
+}
+
+// genericColumns are the header names recognised by the generic importer,
+// covering the CSV exports of Bitwarden, LastPass, 1Password and others.
+// Other columns become custom fields.
+var genericColumns = map[string][]string{
+	"name":     {"name", "title", "label"},
+	"url":      {"url", "uri", "login_uri", "website", "web site"},
+	"username": {"username", "login_username", "user", "login", "email"},
+	"password": {"password", "login_password"},
+	"notes":    {"notes", "note", "extra", "comments", "comment"},
+	"totp":     {"totp", "login_totp", "otp", "otpauth", "one-time password"},
+	"folder":   {"folder", "group", "grouping", "category"},
+	"tags":     {"tags"},
+	"type":     {"type"},
+	"created":  {"created", "creation_date", "created_at"},
+	"updated":  {"modified", "updated", "revision_date", "updated_at", "last_modified"},
+	"fields":   {"fields"},
+}
+
+// ignoredColumns only matter to the manager that wrote them.
+var ignoredColumns = map[string]bool{"favorite": true, "fav": true, "reprompt": true}
+
+// parseGenericCSV reads any CSV with a header row. Rows typed as notes, or
+// without a url, username or password, become notes. Folder columns and
+// comma-separated tags columns become tags.
+func parseGenericCSV(r io.Reader) (Result, error) {
+	t, err := readCSV(r)
+	if err != nil {
+		return Result{}, err
+	}
+
+	cols := make(map[string]int)
+	known := make(map[int]bool)
+	for field, names := range genericColumns {
+		i := t.col(names...)
+		cols[field] = i
+		if i >= 0 {
+			known[i] = true
+		}
+	}
+	if cols["name"] < 0 && cols["url"] < 0 {
+		return Result{}, errors.New("csv needs a name or url column")
+	}
+
+	var res Result
+	for i, row := range t.rows {
+		get := func(field string) string { return cell(row, cols[field]) }
+		raw := func(field string) string { return rawCell(row, cols[field]) }
+
+		var sec secret.Secret
+		var err error
+		// LastPass marks secure notes with the url http://sn
+		isNote := get("type") == "note" || get("url") == "http://sn" ||
+			get("url") == "" && get("username") == "" && get("password") == ""
+		if isNote {
+			sec, err = newNote(get("name"), raw("notes"), "")
+		} else {
+			sec, err = newLogin(get("name"), get("url"), get("username"), raw("password"))
+		}
+		if err != nil {
+			res.skipf("row %d: %v", i+2, err)
+			continue
+		}
+
+		if !isNote {
+			setField(&sec, "notes", raw("notes"))
+			if err := setTOTP(&sec, get("totp")); err != nil {
+				res.warnf("%s: totp not imported: %v", sec.Name, err)
+			}
+		}
+		addTag(&sec, get("folder"))
+		for _, tag := range strings.Split(get("tags"), ",") {
+			addTag(&sec, tag)
+		}
+		// Bitwarden writes custom fields as "name: value" lines
+		for _, line := range strings.Split(get("fields"), "\n") {
+			if name, value, ok := strings.Cut(line, ": "); ok {
+				setField(&sec, name, strings.TrimSpace(value))
+			}
+		}
+		for j, h := range t.header {
+			if !known[j] && !ignoredColumns[h] {
+				setField(&sec, h, rawCell(row, j))
+			}
+		}
+		setTimes(&sec, csvTime(get("created")), csvTime(get("updated")))
+		res.Secrets = append(res.Secrets, sec)
+	}
+	return res, nil
+}
+
+// csvTime accepts RFC 3339, a plain date-time, or unix seconds or
+// milliseconds.
+func csvTime(s string) time.Time {
+	if s == "" {
+		return time.Time{}
+	}
+	for _, layout := range []string{time.RFC3339, "2006-01-02 15:04:05", "2006-01-02"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t
+		}
+	}
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil && n > 0 {
+		if n > 1e11 {
+			return time.UnixMilli(n)
+		}
+		return time.Unix(n, 0)
+	}
+	return time.Time{}
+}
@@ -0,0 +1,95 @@
+package importer
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zarlcorp/zvault/internal/secret"
+)
+
+func TestParseChrome(t *testing.T) {
+	in := "\ufeffname,url,username,password,note\n" +
+		"github.com,https://github.com/,alice, gh-pass ,work account\n" +
+		",https://www.example.com/login,bob,ex-pass,\n"
+	res, err := Parse(ChromeCSV, strings.NewReader(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Secrets) != 2 {
+		t.Fatalf("got %d secrets", len(res.Secrets))
+	}
+	gh := byName(t, res, "github.com")
+	// passwords keep their spaces
+	if gh.Password() != " gh-pass " || gh.Fields["notes"] != "work account" {
+		t.Errorf("github = %+v", gh.Fields)
+	}
+	byName(t, res, "example.com")
+
+	if _, err := Parse(ChromeCSV, strings.NewReader("a,b\n1,2\n")); err == nil {
+		t.Error("expected error for a csv without chrome columns")
+	}
+}
+
+func TestParseFirefox(t *testing.T) {
+	in := `"url","username","password","httpRealm","formActionOrigin","guid","timeCreated","timeLastUsed","timePasswordChanged"
+"https://accounts.example.org","carol","ff-pass",,"https://accounts.example.org","{guid}","1600000000000","1700000000000","1650000000000"
+`
+	res, err := Parse(FirefoxCSV, strings.NewReader(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sec := byName(t, res, "accounts.example.org")
+	if sec.Username() != "carol" || sec.Password() != "ff-pass" {
+		t.Errorf("fields = %+v", sec.Fields)
+	}
+	if !sec.CreatedAt.Equal(time.UnixMilli(1600000000000)) || !sec.UpdatedAt.Equal(time.UnixMilli(1650000000000)) {
+		t.Errorf("times = %v, %v", sec.CreatedAt, sec.UpdatedAt)
+	}
+}
+
+func TestParseGenericCSV(t *testing.T) {
+	// Bitwarden's csv export
+	in := `folder,favorite,type,name,notes,fields,reprompt,login_uri,login_username,login_password,login_totp
+Social,1,login,Twitter,,"Backup: codes
+PIN: 0000",0,https://twitter.com,dave,tw-pass,JBSWY3DPEHPK3PXP
+,,note,Lock combo,12-34-56,,0,,,,
+`
+	res, err := Parse(GenericCSV, strings.NewReader(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tw := byName(t, res, "Twitter")
+	if tw.URL() != "https://twitter.com" || tw.Username() != "dave" || tw.TOTPSecret() != "JBSWY3DPEHPK3PXP" {
+		t.Errorf("twitter = %+v", tw.Fields)
+	}
+	if strings.Join(tw.Tags, ",") != "social" || tw.Fields["backup"] != "codes" || tw.Fields["pin"] != "0000" {
+		t.Errorf("twitter tags %q fields %+v", tw.Tags, tw.Fields)
+	}
+	if _, ok := tw.Fields["favorite"]; ok {
+		t.Error("favorite column should be ignored")
+	}
+	lock := byName(t, res, "Lock combo")
+	if lock.Type != secret.TypeNote || lock.Content() != "12-34-56" {
+		t.Errorf("lock = %+v", lock)
+	}
+
+	// LastPass
+	in = "url,username,password,totp,extra,name,grouping,fav\n" +
+		"http://sn,,,,wifi is hunter2,Wifi,Home,0\n" +
+		"https://bank.example,me,bank-pass,,,Bank,Finance,1\n"
+	res, err = Parse(GenericCSV, strings.NewReader(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wifi := byName(t, res, "Wifi"); wifi.Type != secret.TypeNote || wifi.Content() != "wifi is hunter2" {
+		t.Errorf("lastpass note = %+v", wifi)
+	}
+	if bank := byName(t, res, "Bank"); bank.Password() != "bank-pass" || bank.Tags[0] != "finance" {
+		t.Errorf("lastpass login = %+v", bank)
+	}
+
+	if _, err := Parse(GenericCSV, strings.NewReader("foo,bar\n1,2\n")); err == nil {
+		t.Error("expected error without name or url column")
+	}
+}
@@ -0,0 +1,230 @@
+// Package importer converts exports from other password managers into
+// secrets.
+//
+// Logins become password secrets, secure notes become notes, and cards and
+// identities become notes tagged "card" or "identity" with one field per
+// value. Folders and groups become tags, TOTP seeds and custom fields are
+// kept as fields, and creation and modification times are preserved when
+// the export has them.
+package importer
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/zarlcorp/zvault/internal/secret"
+	"github.com/zarlcorp/zvault/internal/totp"
+)
+
+// Supported export formats.
+const (
+	BitwardenJSON = "bitwarden-json"
+	KeePassXML    = "keepass-xml"
+	ChromeCSV     = "chrome-csv"
+	FirefoxCSV    = "firefox-csv"
+	GenericCSV    = "generic-csv"
+)
+
+// Formats lists the supported formats, for usage text and completion.
+var Formats = []string{BitwardenJSON, KeePassXML, ChromeCSV, FirefoxCSV, GenericCSV}
+
+// Result holds the converted secrets. Skipped lists the entries that were
+// left out, Warnings the parts of imported entries that were dropped.
+type Result struct {
+	Secrets  []secret.Secret
+	Skipped  []string
+	Warnings []string
+}
+
+func (r *Result) skipf(format string, args ...any) {
+	r.Skipped = append(r.Skipped, fmt.Sprintf(format, args...))
+}
+
+func (r *Result) warnf(format string, args ...any) {
+	r.Warnings = append(r.Warnings, fmt.Sprintf(format, args...))
+}
+
+// Parse reads an export in the given format.
+func Parse(format string, r io.Reader) (Result, error) {
+	switch format {
+	case BitwardenJSON:
+		return parseBitwarden(r)
+	case KeePassXML:
+		return parseKeePass(r)
+	case ChromeCSV:
+		return parseChrome(r)
+	case FirefoxCSV:
+		return parseFirefox(r)
+	case GenericCSV:
+		return parseGenericCSV(r)
+	}
+	return Result{}, fmt.Errorf("unknown import format %q (use %s)", format, strings.Join(Formats, ", "))
+}
+
+// Duplicate returns the secret in existing with the same name and url as
+// sec. URLs are compared without scheme, case of the host or a trailing
+// slash.
+func Duplicate(existing []secret.Secret, sec secret.Secret) (secret.Secret, bool) {
+	for _, e := range existing {
+		if strings.EqualFold(e.Name, sec.Name) && normalizeURL(e.URL()) == normalizeURL(sec.URL()) {
+			return e, true
+		}
+	}
+	return secret.Secret{}, false
+}
+
+func normalizeURL(s string) string {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return ""
+	}
+	raw := s
+	if !strings.Contains(raw, "://") {
+		raw = "//" + raw
+	}
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return strings.ToLower(strings.TrimSuffix(s, "/"))
+	}
+	return strings.ToLower(u.Host) + strings.TrimSuffix(u.Path, "/")
+}
+
+// hostName returns the host of a url, for naming entries that have no
+// title of their own.
+func hostName(s string) string {
+	raw := strings.TrimSpace(s)
+	if !strings.Contains(raw, "://") {
+		raw = "//" + raw
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+}
+
+// newLogin builds a password secret, naming it after the url's host when
+// the entry has no name.
+func newLogin(name, rawURL, username, password string) (secret.Secret, error) {
+	if name == "" {
+		name = hostName(rawURL)
+	}
+	if name == "" {
+		name = username
+	}
+	if name == "" {
+		return secret.Secret{}, fmt.Errorf("entry has no name, url or username")
+	}
+	return secret.NewPassword(name, rawURL, username, password)
+}
+
+// newNote builds a note secret; typ tags cards and identities.
+func newNote(name, content, typ string) (secret.Secret, error) {
+	if name == "" {
+		return secret.Secret{}, fmt.Errorf("entry has no name")
+	}
+	sec, err := secret.NewNote(name, content)
+	if err != nil {
+		return secret.Secret{}, err
+	}
+	if typ != "" {
+		sec.Tags = append(sec.Tags, typ)
+	}
+	return sec, nil
+}
+
+// setTOTP stores a TOTP seed, given as an otpauth:// URI or a bare base32
+// secret, in the totp fields.
+func setTOTP(sec *secret.Secret, value string) error {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil
+	}
+
+	if !strings.HasPrefix(strings.ToLower(value), "otpauth://") {
+		seed := strings.ToUpper(strings.ReplaceAll(value, " ", ""))
+		if _, _, err := totp.Generate(seed); err != nil {
+			return fmt.Errorf("unsupported totp seed")
+		}
+		sec.Fields["totp_secret"] = seed
+		return nil
+	}
+
+	acc, err := totp.ParseURI(value)
+	if err != nil {
+		return err
+	}
+	if acc.HOTP {
+		return fmt.Errorf("counter-based codes are not supported")
+	}
+	sec.Fields["totp_secret"] = acc.Secret
+	if acc.Issuer != "" {
+		sec.Fields["totp_issuer"] = acc.Issuer
+	}
+	if acc.Params.Algorithm != totp.Default.Algorithm {
+		sec.Fields["totp_algorithm"] = acc.Params.Algorithm
+	}
+	if acc.Params.Digits != totp.Default.Digits {
+		sec.Fields["totp_digits"] = strconv.Itoa(acc.Params.Digits)
+	}
+	if acc.Params.Period != totp.Default.Period {
+		sec.Fields["totp_period"] = strconv.Itoa(acc.Params.Period)
+	}
+	return nil
+}
+
+var nonFieldChars = regexp.MustCompile(`[^a-z0-9]+`)
+
+// setField stores a custom field under a snake_case key. Keys that clash
+// with a field the secret already has get a custom_ prefix, then a number.
+func setField(sec *secret.Secret, name, value string) {
+	if value == "" {
+		return
+	}
+	key := strings.Trim(nonFieldChars.ReplaceAllString(strings.ToLower(name), "_"), "_")
+	if key == "" {
+		key = "field"
+	}
+	if _, taken := sec.Fields[key]; taken {
+		key = "custom_" + key
+	}
+	base := key
+	for i := 2; ; i++ {
+		if _, taken := sec.Fields[key]; !taken {
+			break
+		}
+		key = base + "_" + strconv.Itoa(i)
+	}
+	sec.Fields[key] = value
+}
+
+// addTag adds a folder or group path as a tag: lowercase, with runs of
+// whitespace turned into dashes.
+func addTag(sec *secret.Secret, folder string) {
+	tag := strings.Join(strings.Fields(strings.ToLower(folder)), "-")
+	if tag == "" {
+		return
+	}
+	for _, t := range sec.Tags {
+		if t == tag {
+			return
+		}
+	}
+	sec.Tags = append(sec.Tags, tag)
+}
+
+// setTimes keeps the source's timestamps when it has them.
+func setTimes(sec *secret.Secret, created, updated time.Time) {
+	if !created.IsZero() {
+		sec.CreatedAt = created
+		sec.UpdatedAt = created
+	}
+	if !updated.IsZero() {
+		sec.UpdatedAt = updated
+	}
+}
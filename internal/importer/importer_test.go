@@ -0,0 +1,111 @@
+package importer
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/zarlcorp/zvault/internal/secret"
+)
+
+func mustLogin(t *testing.T, name, url string) secret.Secret {
+	t.Helper()
+	sec, err := secret.NewPassword(name, url, "user", "pass")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sec
+}
+
+func TestDuplicate(t *testing.T) {
+	existing := []secret.Secret{
+		mustLogin(t, "GitHub", "https://github.com/"),
+		mustLogin(t, "Router", ""),
+	}
+
+	tests := []struct {
+		name, url string
+		want      bool
+	}{
+		{"github", "github.com", true},
+		{"GitHub", "http://GITHUB.com", true},
+		{"GitHub", "https://github.com/org", false},
+		{"GitLab", "https://github.com", false},
+		{"router", "", true},
+		{"router", "192.168.0.1", false},
+	}
+	for _, tt := range tests {
+		_, got := Duplicate(existing, mustLogin(t, tt.name, tt.url))
+		if got != tt.want {
+			t.Errorf("Duplicate(%q, %q) = %v, want %v", tt.name, tt.url, got, tt.want)
+		}
+	}
+}
+
+func TestSetField(t *testing.T) {
+	sec := mustLogin(t, "x", "")
+	setField(&sec, "Recovery Email", "a@example.com")
+	setField(&sec, "password", "old")
+	setField(&sec, "Password", "older")
+	setField(&sec, "!!!", "odd")
+	setField(&sec, "empty", "")
+
+	want := map[string]string{
+		"recovery_email":    "a@example.com",
+		"custom_password":   "old",
+		"custom_password_2": "older",
+		"field":             "odd",
+	}
+	for k, v := range want {
+		if sec.Fields[k] != v {
+			t.Errorf("field %s = %q, want %q", k, sec.Fields[k], v)
+		}
+	}
+	if _, ok := sec.Fields["empty"]; ok {
+		t.Error("empty values should not be stored")
+	}
+	if sec.Password() != "pass" {
+		t.Errorf("password overwritten: %q", sec.Password())
+	}
+}
+
+func TestSetTOTP(t *testing.T) {
+	sec := mustLogin(t, "x", "")
+	if err := setTOTP(&sec, "jbsw y3dp ehpk 3pxp"); err != nil {
+		t.Fatal(err)
+	}
+	if sec.TOTPSecret() != "JBSWY3DPEHPK3PXP" {
+		t.Errorf("totp_secret = %q", sec.TOTPSecret())
+	}
+
+	sec = mustLogin(t, "x", "")
+	if err := setTOTP(&sec, "otpauth://totp/Acme:me?secret=JBSWY3DPEHPK3PXP&issuer=Acme&algorithm=SHA256&period=60"); err != nil {
+		t.Fatal(err)
+	}
+	if sec.TOTPIssuer() != "Acme" || sec.TOTPAlgorithm() != "SHA256" || sec.TOTPPeriod() != "60" || sec.TOTPDigits() != "" {
+		t.Errorf("fields = %v", sec.Fields)
+	}
+
+	for _, bad := range []string{"steam://ABC", "otpauth://hotp/x?secret=JBSWY3DPEHPK3PXP"} {
+		sec = mustLogin(t, "x", "")
+		if err := setTOTP(&sec, bad); err == nil {
+			t.Errorf("setTOTP(%q) should fail", bad)
+		}
+	}
+}
+
+func TestAddTag(t *testing.T) {
+	sec := mustLogin(t, "x", "")
+	addTag(&sec, "Work Stuff")
+	addTag(&sec, "work  stuff")
+	addTag(&sec, "Clients/Acme Corp")
+	addTag(&sec, " ")
+	if got := strings.Join(sec.Tags, ","); got != "work-stuff,clients/acme-corp" {
+		t.Errorf("tags = %q", got)
+	}
+}
+
+func TestParseUnknownFormat(t *testing.T) {
+	if _, err := Parse("1password-1pux", strings.NewReader("")); err == nil {
+		t.Error("expected error for unknown format")
+	}
+}
@@ -0,0 +1,162 @@
+package importer
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/zarlcorp/zvault/internal/secret"
+)
+
+type kpFile struct {
+	XMLName xml.Name `xml:"KeePassFile"`
+	Meta    struct {
+		RecycleBinUUID string `xml:"RecycleBinUUID"`
+	} `xml:"Meta"`
+	Root struct {
+		Groups []kpGroup `xml:"Group"`
+	} `xml:"Root"`
+}
+
+type kpGroup struct {
+	UUID    string    `xml:"UUID"`
+	Name    string    `xml:"Name"`
+	Entries []kpEntry `xml:"Entry"`
+	Groups  []kpGroup `xml:"Group"`
+}
+
+// kpEntry is one entry; its History holds older versions, which are not
+// imported.
+type kpEntry struct {
+	Strings []kpString `xml:"String"`
+	Times   struct {
+		CreationTime         string `xml:"CreationTime"`
+		LastModificationTime string `xml:"LastModificationTime"`
+	} `xml:"Times"`
+}
+
+type kpString struct {
+	Key   string `xml:"Key"`
+	Value string `xml:"Value"`
+}
+
+// kpStandardKeys are mapped onto secret fields rather than kept as custom
+// fields.
+var kpStandardKeys = map[string]bool{
+	"Title": true, "UserName": true, "Password": true, "URL": true, "Notes": true,
+	"otp": true, "TOTP Seed": true, "TOTP Settings": true,
+}
+
+// kpEpoch is where the base64 timestamps of KDBX 4 XML count from.
+var kpEpoch = time.Date(1, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// parseKeePass reads a KeePass 2 or KeePassXC XML export. Groups below the
+// root become tags, joined with "/" when nested. The recycle bin is
+// skipped.
+func parseKeePass(r io.Reader) (Result, error) {
+	var f kpFile
+	if err := xml.NewDecoder(r).Decode(&f); err != nil {
+		return Result{}, fmt.Errorf("parse keepass export: %w", err)
+	}
+
+	var res Result
+	for _, root := range f.Root.Groups {
+		// the root group is the database itself; its name is not a folder
+		walkKeePass(root, "", f.Meta.RecycleBinUUID, &res)
+	}
+	return res, nil
+}
+
+func walkKeePass(g kpGroup, path, recycleBin string, res *Result) {
+	if recycleBin != "" && g.UUID == recycleBin {
+		return
+	}
+
+	for _, e := range g.Entries {
+		sec, err := keepassSecret(e, res)
+		if err != nil {
+			res.skipf("%s: %v", kpValue(e, "Title"), err)
+			continue
+		}
+		if path != "" {
+			addTag(&sec, path)
+		}
+		res.Secrets = append(res.Secrets, sec)
+	}
+
+	for _, sub := range g.Groups {
+		subPath := sub.Name
+		if path != "" {
+			subPath = path + "/" + sub.Name
+		}
+		walkKeePass(sub, subPath, recycleBin, res)
+	}
+}
+
+func keepassSecret(e kpEntry, res *Result) (secret.Secret, error) {
+	title := kpValue(e, "Title")
+	username := kpValue(e, "UserName")
+	password := kpValue(e, "Password")
+	url := kpValue(e, "URL")
+	notes := kpValue(e, "Notes")
+
+	var sec secret.Secret
+	var err error
+	if username == "" && password == "" && url == "" {
+		sec, err = newNote(title, notes, "")
+	} else {
+		sec, err = newLogin(title, url, username, password)
+		if err == nil {
+			setField(&sec, "notes", notes)
+			seed := kpValue(e, "otp")
+			if seed == "" {
+				seed = kpValue(e, "TOTP Seed")
+			}
+			if terr := setTOTP(&sec, seed); terr != nil {
+				res.warnf("%s: totp not imported: %v", sec.Name, terr)
+			}
+		}
+	}
+	if err != nil {
+		return sec, err
+	}
+
+	for _, s := range e.Strings {
+		if !kpStandardKeys[s.Key] {
+			setField(&sec, s.Key, s.Value)
+		}
+	}
+	setTimes(&sec, kpTime(e.Times.CreationTime), kpTime(e.Times.LastModificationTime))
+	return sec, nil
+}
+
+func kpValue(e kpEntry, key string) string {
+	for _, s := range e.Strings {
+		if s.Key == key {
+			return s.Value
+		}
+	}
+	return ""
+}
+
+// kpTime parses an ISO 8601 timestamp, or the base64 seconds-since-year-1
+// form KDBX 4 uses. Anything else is treated as missing.
+func kpTime(s string) time.Time {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return time.Time{}
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t
+	}
+	if b, err := base64.StdEncoding.DecodeString(s); err == nil && len(b) == 8 {
+		secs := int64(binary.LittleEndian.Uint64(b))
+		// too many seconds for a Duration, so add whole days first
+		return kpEpoch.AddDate(0, 0, int(secs/86400)).Add(time.Duration(secs%86400) * time.Second)
+	}
+	return time.Time{}
+}
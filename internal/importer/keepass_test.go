@@ -0,0 +1,48 @@
+package importer
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zarlcorp/zvault/internal/secret"
+)
+
+func TestParseKeePass(t *testing.T) {
+	res := parseFile(t, KeePassXML, "testdata/keepass.xml")
+
+	// history and recycle bin skipped
+	if len(res.Secrets) != 3 {
+		t.Fatalf("got %d secrets, want 3: %v", len(res.Secrets), res.Warnings)
+	}
+
+	mail := byName(t, res, "Mail")
+	if mail.Username() != "alice" || mail.Password() != "mail-pass" || mail.URL() != "https://mail.example.com" {
+		t.Errorf("mail = %+v", mail.Fields)
+	}
+	if mail.Fields["notes"] != "main account" || mail.Fields["pin"] != "1234" {
+		t.Errorf("mail fields = %+v", mail.Fields)
+	}
+	if mail.TOTPSecret() != "JBSWY3DPEHPK3PXP" || mail.TOTPPeriod() != "60" {
+		t.Errorf("mail totp = %+v", mail.Fields)
+	}
+	if len(mail.Tags) != 0 {
+		t.Errorf("root entries should have no tags, got %q", mail.Tags)
+	}
+	if !mail.CreatedAt.Equal(time.Date(2022, 5, 6, 7, 8, 9, 0, time.UTC)) || !mail.UpdatedAt.Equal(time.Date(2023, 5, 6, 7, 8, 9, 0, time.UTC)) {
+		t.Errorf("times = %v, %v", mail.CreatedAt, mail.UpdatedAt)
+	}
+
+	shop := byName(t, res, "Shop")
+	if strings.Join(shop.Tags, ",") != "internet/online-shops" {
+		t.Errorf("shop tags = %q", shop.Tags)
+	}
+	if !shop.CreatedAt.Equal(time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC)) {
+		t.Errorf("kdbx4 time = %v", shop.CreatedAt)
+	}
+
+	door := byName(t, res, "Door code")
+	if door.Type != secret.TypeNote || door.Content() != "4321" {
+		t.Errorf("door = %+v", door)
+	}
+}
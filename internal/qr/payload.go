@@ -0,0 +1,82 @@
+package qr
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/zarlcorp/zvault/internal/secret"
+	"github.com/zarlcorp/zvault/internal/totp"
+)
+
+// Payload returns the text to encode for a secret.
+//
+// An empty field picks a sensible default: the otpauth URI when the secret
+// has a totp_secret, otherwise its primary value (password, key, public key
+// or note content). The pseudo-fields "totp" and "wifi" build an otpauth URI
+// and a Wi-Fi join string; any other field is encoded verbatim.
+func Payload(sec secret.Secret, field string) (string, error) {
+	if field == "" {
+		field = defaultField(sec)
+	}
+
+	switch field {
+	case "totp", "totp_secret":
+		if sec.TOTPSecret() == "" {
+			return "", fmt.Errorf("secret %q has no totp secret", sec.Name)
+		}
+		p, err := totp.ParseParams(sec.TOTPAlgorithm(), sec.TOTPDigits(), sec.TOTPPeriod())
+		if err != nil {
+			return "", err
+		}
+		issuer := sec.TOTPIssuer()
+		if issuer == "" {
+			issuer = sec.Name
+		}
+		return totp.URI(sec.TOTPSecret(), issuer, sec.Username(), p), nil
+	case "wifi":
+		if sec.Type != secret.TypePassword {
+			return "", fmt.Errorf("wifi codes need a password secret, %q is %s", sec.Name, sec.Type)
+		}
+		return WiFi(wifiSSID(sec), sec.Password()), nil
+	}
+
+	val, ok := sec.Fields[field]
+	if !ok || val == "" {
+		return "", fmt.Errorf("secret %q has no %s field", sec.Name, field)
+	}
+	return val, nil
+}
+
+func defaultField(sec secret.Secret) string {
+	if sec.TOTPSecret() != "" {
+		return "totp"
+	}
+	switch sec.Type {
+	case secret.TypePassword:
+		return "password"
+	case secret.TypeAPIKey:
+		return "key"
+	case secret.TypeSSHKey:
+		return "public_key"
+	default:
+		return "content"
+	}
+}
+
+// wifiSSID uses the username as the network name, falling back to the secret name.
+func wifiSSID(sec secret.Secret) string {
+	if sec.Username() != "" {
+		return sec.Username()
+	}
+	return sec.Name
+}
+
+// WiFi returns a WPA network join string in the format phones recognise.
+// An empty password produces an open network entry.
+func WiFi(ssid, password string) string {
+	esc := strings.NewReplacer(`\`, `\\`, `;`, `\;`, `,`, `\,`, `:`, `\:`, `"`, `\"`)
+	if password == "" {
+		return fmt.Sprintf("WIFI:T:nopass;S:%s;;", esc.Replace(ssid))
+	}
+	return fmt.Sprintf("WIFI:T:WPA;S:%s;P:%s;;", esc.Replace(ssid), esc.Replace(password))
+}
@@ -0,0 +1,587 @@
+// Package qr encodes text as QR codes (ISO/IEC 18004) and renders them for
+// the terminal. It supports byte mode only, which covers otpauth URIs, Wi-Fi
+// strings and passwords, and needs no network access or external tools.
+package qr
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Level is the error correction level.
+type Level int
+
+const (
+	L Level = iota // recovers ~7% of codewords
+	M              // recovers ~15% of codewords
+	Q              // recovers ~25% of codewords
+	H              // recovers ~30% of codewords
+)
+
+// formatBits returns the two-bit level indicator used in format information.
+func (l Level) formatBits() int {
+	switch l {
+	case L:
+		return 1
+	case M:
+		return 0
+	case Q:
+		return 3
+	default:
+		return 2
+	}
+}
+
+// ErrTooLong is returned when the data does not fit in a version 40 symbol.
+var ErrTooLong = errors.New("data too long for a qr code")
+
+// quietZone is the light border around the symbol, in modules. ISO/IEC
+// 18004 asks for 4; scanners struggle with less on dark terminals.
+const quietZone = 4
+
+// Code is an encoded QR symbol.
+type Code struct {
+	Size    int // modules per side
+	Version int // 1 through 40
+
+	dark     [][]bool
+	reserved [][]bool // function patterns, excluded from masking
+}
+
+// Encode encodes text in byte mode at the smallest version that fits.
+func Encode(text string, level Level) (*Code, error) {
+	data := []byte(text)
+
+	version := 0
+	for v := 1; v <= 40; v++ {
+		if len(data) <= capacity(v, level) {
+			version = v
+			break
+		}
+	}
+	if version == 0 {
+		return nil, fmt.Errorf("encode %d bytes: %w", len(data), ErrTooLong)
+	}
+
+	codewords := encodeData(data, version, level)
+	all := addECC(codewords, version, level)
+
+	c := newCode(version)
+	c.drawFunctionPatterns()
+	c.drawCodewords(all)
+
+	best, bestPenalty := 0, -1
+	for mask := 0; mask < 8; mask++ {
+		c.applyMask(mask)
+		c.drawFormat(level, mask)
+		if p := c.penalty(); bestPenalty < 0 || p < bestPenalty {
+			best, bestPenalty = mask, p
+		}
+		c.applyMask(mask) // xor again to undo
+	}
+	c.applyMask(best)
+	c.drawFormat(level, best)
+
+	return c, nil
+}
+
+// Black reports whether the module at (x, y) is dark.
+// Coordinates outside the symbol are light.
+func (c *Code) Black(x, y int) bool {
+	if x < 0 || y < 0 || x >= c.Size || y >= c.Size {
+		return false
+	}
+	return c.dark[y][x]
+}
+
+// Terminal renders the code with Unicode half blocks so that each text row
+// holds two module rows. Colors are forced to black on white so the code
+// scans on both dark and light terminal themes.
+func (c *Code) Terminal() string {
+	var b strings.Builder
+	lo, hi := -quietZone, c.Size+quietZone
+	for y := lo; y < hi; y += 2 {
+		b.WriteString("\x1b[30;47m")
+		for x := lo; x < hi; x++ {
+			top, bottom := c.Black(x, y), c.Black(x, y+1)
+			switch {
+			case top && bottom:
+				b.WriteString("█")
+			case top:
+				b.WriteString("▀")
+			case bottom:
+				b.WriteString("▄")
+			default:
+				b.WriteString(" ")
+			}
+		}
+		b.WriteString("\x1b[0m\n")
+	}
+	return b.String()
+}
+
+// eccPerBlock is the number of error correction codewords per block,
+// indexed by level then version.
+var eccPerBlock = [4][41]int{
+	{0, 7, 10, 15, 20, 26, 18, 20, 24, 30, 18, 20, 24, 26, 30, 22, 24, 28, 30, 28, 28, 28, 28, 30, 30, 26, 28, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30},
+	{0, 10, 16, 26, 18, 24, 16, 18, 22, 22, 26, 30, 22, 22, 24, 24, 28, 28, 26, 26, 26, 26, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28},
+	{0, 13, 22, 18, 26, 18, 24, 18, 22, 20, 24, 28, 26, 24, 20, 30, 24, 28, 28, 26, 30, 28, 30, 30, 30, 30, 28, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30},
+	{0, 17, 28, 22, 16, 22, 28, 26, 26, 24, 28, 24, 28, 22, 24, 24, 30, 28, 28, 26, 28, 30, 24, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30},
+}
+
+// numBlocks is the number of error correction blocks, indexed by level then version.
+var numBlocks = [4][41]int{
+	{0, 1, 1, 1, 1, 1, 2, 2, 2, 2, 4, 4, 4, 4, 4, 6, 6, 6, 6, 7, 8, 8, 9, 9, 10, 12, 12, 12, 13, 14, 15, 16, 17, 18, 19, 19, 20, 21, 22, 24, 25},
+	{0, 1, 1, 1, 2, 2, 4, 4, 4, 5, 5, 5, 8, 9, 9, 10, 10, 11, 13, 14, 16, 17, 17, 18, 20, 21, 23, 25, 26, 28, 29, 31, 33, 35, 37, 38, 40, 43, 45, 47, 49},
+	{0, 1, 1, 2, 2, 4, 4, 6, 6, 8, 8, 8, 10, 12, 16, 12, 17, 16, 18, 21, 20, 23, 23, 25, 27, 29, 34, 34, 35, 38, 40, 43, 45, 48, 51, 53, 56, 59, 62, 65, 68},
+	{0, 1, 1, 2, 4, 4, 4, 5, 6, 8, 8, 11, 11, 16, 16, 18, 16, 19, 21, 25, 25, 25, 34, 30, 32, 35, 37, 40, 42, 45, 48, 51, 54, 57, 60, 63, 66, 70, 74, 77, 81},
+}
+
+// rawModules returns the number of modules available for codewords and
+// remainder bits in a symbol of the given version.
+func rawModules(version int) int {
+	n := (16*version+128)*version + 64
+	if version >= 2 {
+		align := version/7 + 2
+		n -= (25*align-10)*align - 55
+		if version >= 7 {
+			n -= 36
+		}
+	}
+	return n
+}
+
+// dataCodewords returns the number of data codewords for a version and level.
+func dataCodewords(version int, level Level) int {
+	return rawModules(version)/8 - eccPerBlock[level][version]*numBlocks[level][version]
+}
+
+// capacity returns the number of bytes that fit in byte mode.
+func capacity(version int, level Level) int {
+	countBits := 8
+	if version >= 10 {
+		countBits = 16
+	}
+	bits := dataCodewords(version, level)*8 - 4 - countBits
+	return bits / 8
+}
+
+// bitBuffer accumulates bits most significant first.
+type bitBuffer []bool
+
+func (b *bitBuffer) append(val, n int) {
+	for i := n - 1; i >= 0; i-- {
+		*b = append(*b, (val>>i)&1 == 1)
+	}
+}
+
+// encodeData builds the padded data codeword sequence for byte mode.
+func encodeData(data []byte, version int, level Level) []byte {
+	countBits := 8
+	if version >= 10 {
+		countBits = 16
+	}
+
+	var bb bitBuffer
+	bb.append(0x4, 4) // byte mode indicator
+	bb.append(len(data), countBits)
+	for _, d := range data {
+		bb.append(int(d), 8)
+	}
+
+	capBits := dataCodewords(version, level) * 8
+	bb.append(0, min(4, capBits-len(bb))) // terminator
+	bb.append(0, (8-len(bb)%8)%8)
+
+	out := make([]byte, 0, capBits/8)
+	for i := 0; i < len(bb); i += 8 {
+		var v byte
+		for j := 0; j < 8; j++ {
+			if bb[i+j] {
+				v |= 1 << (7 - j)
+			}
+		}
+		out = append(out, v)
+	}
+	for pad := byte(0xEC); len(out) < capBits/8; pad ^= 0xEC ^ 0x11 {
+		out = append(out, pad)
+	}
+	return out
+}
+
+// addECC splits data into blocks, appends Reed-Solomon codewords and
+// interleaves the result.
+func addECC(data []byte, version int, level Level) []byte {
+	nBlocks := numBlocks[level][version]
+	eccLen := eccPerBlock[level][version]
+	raw := rawModules(version) / 8
+	nShort := nBlocks - raw%nBlocks
+	shortLen := raw / nBlocks
+
+	gen := rsGenerator(eccLen)
+	blocks := make([][]byte, 0, nBlocks)
+	k := 0
+	for i := 0; i < nBlocks; i++ {
+		n := shortLen - eccLen
+		if i >= nShort {
+			n++
+		}
+		dat := data[k : k+n]
+		k += n
+		block := make([]byte, 0, shortLen+1)
+		block = append(block, dat...)
+		if i < nShort {
+			block = append(block, 0) // placeholder keeps columns aligned
+		}
+		block = append(block, rsRemainder(dat, gen)...)
+		blocks = append(blocks, block)
+	}
+
+	out := make([]byte, 0, raw)
+	for i := 0; i <= shortLen; i++ {
+		for j, block := range blocks {
+			// skip the placeholder in short blocks
+			if i == shortLen-eccLen && j < nShort {
+				continue
+			}
+			out = append(out, block[i])
+		}
+	}
+	return out
+}
+
+// gfMul multiplies in GF(2^8) with the QR polynomial 0x11D.
+func gfMul(x, y byte) byte {
+	var z byte
+	for i := 7; i >= 0; i-- {
+		hi := z >> 7
+		z <<= 1
+		z ^= hi * 0x1D
+		z ^= ((y >> i) & 1) * x
+	}
+	return z
+}
+
+// rsGenerator returns the coefficients of the Reed-Solomon generator
+// polynomial of the given degree, highest power first, without the leading 1.
+func rsGenerator(degree int) []byte {
+	gen := make([]byte, degree)
+	gen[degree-1] = 1
+	root := byte(1)
+	for i := 0; i < degree; i++ {
+		for j := 0; j < degree; j++ {
+			gen[j] = gfMul(gen[j], root)
+			if j+1 < degree {
+				gen[j] ^= gen[j+1]
+			}
+		}
+		root = gfMul(root, 0x02)
+	}
+	return gen
+}
+
+// rsRemainder returns the error correction codewords for data.
+func rsRemainder(data, gen []byte) []byte {
+	rem := make([]byte, len(gen))
+	for _, b := range data {
+		factor := b ^ rem[0]
+		copy(rem, rem[1:])
+		rem[len(rem)-1] = 0
+		for i, g := range gen {
+			rem[i] ^= gfMul(g, factor)
+		}
+	}
+	return rem
+}
+
+func newCode(version int) *Code {
+	size := version*4 + 17
+	c := &Code{Size: size, Version: version}
+	c.dark = make([][]bool, size)
+	c.reserved = make([][]bool, size)
+	for i := range size {
+		c.dark[i] = make([]bool, size)
+		c.reserved[i] = make([]bool, size)
+	}
+	return c
+}
+
+func (c *Code) setFunction(x, y int, dark bool) {
+	c.dark[y][x] = dark
+	c.reserved[y][x] = true
+}
+
+func (c *Code) drawFunctionPatterns() {
+	// timing patterns
+	for i := 0; i < c.Size; i++ {
+		c.setFunction(6, i, i%2 == 0)
+		c.setFunction(i, 6, i%2 == 0)
+	}
+
+	c.drawFinder(3, 3)
+	c.drawFinder(c.Size-4, 3)
+	c.drawFinder(3, c.Size-4)
+
+	pos := alignmentPositions(c.Version)
+	n := len(pos)
+	for i := 0; i < n; i++ {
+		for j := 0; j < n; j++ {
+			// skip the three corners occupied by finder patterns
+			if (i == 0 && j == 0) || (i == 0 && j == n-1) || (i == n-1 && j == 0) {
+				continue
+			}
+			c.drawAlignment(pos[i], pos[j])
+		}
+	}
+
+	// reserve format areas; real bits are drawn per mask
+	c.drawFormat(L, 0)
+	c.drawVersion()
+}
+
+// drawFinder draws a finder pattern and its separator centred on (x, y).
+func (c *Code) drawFinder(x, y int) {
+	for dy := -4; dy <= 4; dy++ {
+		for dx := -4; dx <= 4; dx++ {
+			xx, yy := x+dx, y+dy
+			if xx < 0 || yy < 0 || xx >= c.Size || yy >= c.Size {
+				continue
+			}
+			d := max(abs(dx), abs(dy))
+			c.setFunction(xx, yy, d != 2 && d != 4)
+		}
+	}
+}
+
+func (c *Code) drawAlignment(x, y int) {
+	for dy := -2; dy <= 2; dy++ {
+		for dx := -2; dx <= 2; dx++ {
+			c.setFunction(x+dx, y+dy, max(abs(dx), abs(dy)) != 1)
+		}
+	}
+}
+
+// alignmentPositions returns the centre coordinates of alignment patterns.
+func alignmentPositions(version int) []int {
+	if version == 1 {
+		return nil
+	}
+	n := version/7 + 2
+	step := 26
+	if version != 32 {
+		step = (version*4 + n*2 + 1) / (n*2 - 2) * 2
+	}
+	pos := make([]int, n)
+	pos[0] = 6
+	for i, p := n-1, version*4+10; i >= 1; i, p = i-1, p-step {
+		pos[i] = p
+	}
+	return pos
+}
+
+// drawFormat writes the 15-bit format information twice.
+func (c *Code) drawFormat(level Level, mask int) {
+	data := level.formatBits()<<3 | mask
+	rem := data
+	for i := 0; i < 10; i++ {
+		rem = (rem << 1) ^ ((rem >> 9) * 0x537)
+	}
+	bits := (data<<10 | rem) ^ 0x5412
+
+	bit := func(i int) bool { return (bits>>i)&1 == 1 }
+
+	// around the top-left finder
+	for i := 0; i <= 5; i++ {
+		c.setFunction(8, i, bit(i))
+	}
+	c.setFunction(8, 7, bit(6))
+	c.setFunction(8, 8, bit(7))
+	c.setFunction(7, 8, bit(8))
+	for i := 9; i < 15; i++ {
+		c.setFunction(14-i, 8, bit(i))
+	}
+
+	// split between the other two finders
+	for i := 0; i < 8; i++ {
+		c.setFunction(c.Size-1-i, 8, bit(i))
+	}
+	for i := 8; i < 15; i++ {
+		c.setFunction(8, c.Size-15+i, bit(i))
+	}
+	c.setFunction(8, c.Size-8, true) // dark module
+}
+
+// drawVersion writes the 18-bit version information for versions 7 and up.
+func (c *Code) drawVersion() {
+	if c.Version < 7 {
+		return
+	}
+	rem := c.Version
+	for i := 0; i < 12; i++ {
+		rem = (rem << 1) ^ ((rem >> 11) * 0x1F25)
+	}
+	bits := c.Version<<12 | rem
+
+	for i := 0; i < 18; i++ {
+		dark := (bits>>i)&1 == 1
+		a, b := c.Size-11+i%3, i/3
+		c.setFunction(a, b, dark)
+		c.setFunction(b, a, dark)
+	}
+}
+
+// drawCodewords places data in the zigzag column-pair order.
+func (c *Code) drawCodewords(data []byte) {
+	i := 0
+	total := len(data) * 8
+	for right := c.Size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5 // skip the vertical timing column
+		}
+		for vert := 0; vert < c.Size; vert++ {
+			for j := 0; j < 2; j++ {
+				x := right - j
+				upward := (right+1)&2 == 0
+				y := vert
+				if upward {
+					y = c.Size - 1 - vert
+				}
+				if c.reserved[y][x] {
+					continue
+				}
+				if i < total {
+					c.dark[y][x] = (data[i>>3]>>(7-(i&7)))&1 == 1
+					i++
+				}
+				// remainder bits stay light
+			}
+		}
+	}
+}
+
+// applyMask xors the given mask pattern onto all non-function modules.
+func (c *Code) applyMask(mask int) {
+	for y := 0; y < c.Size; y++ {
+		for x := 0; x < c.Size; x++ {
+			if c.reserved[y][x] {
+				continue
+			}
+			var invert bool
+			switch mask {
+			case 0:
+				invert = (x+y)%2 == 0
+			case 1:
+				invert = y%2 == 0
+			case 2:
+				invert = x%3 == 0
+			case 3:
+				invert = (x+y)%3 == 0
+			case 4:
+				invert = (x/3+y/2)%2 == 0
+			case 5:
+				invert = x*y%2+x*y%3 == 0
+			case 6:
+				invert = (x*y%2+x*y%3)%2 == 0
+			case 7:
+				invert = ((x+y)%2+x*y%3)%2 == 0
+			}
+			if invert {
+				c.dark[y][x] = !c.dark[y][x]
+			}
+		}
+	}
+}
+
+// penalty scores the symbol using the four rules from the specification.
+func (c *Code) penalty() int {
+	score := 0
+	n := c.Size
+
+	// rule 1: runs of five or more same-colour modules
+	// rule 3: finder-like 1:1:3:1:1 patterns
+	for y := 0; y < n; y++ {
+		score += runPenalty(n, func(i int) bool { return c.dark[y][i] })
+	}
+	for x := 0; x < n; x++ {
+		score += runPenalty(n, func(i int) bool { return c.dark[i][x] })
+	}
+
+	// rule 2: 2x2 blocks of the same colour
+	for y := 0; y < n-1; y++ {
+		for x := 0; x < n-1; x++ {
+			d := c.dark[y][x]
+			if d == c.dark[y][x+1] && d == c.dark[y+1][x] && d == c.dark[y+1][x+1] {
+				score += 3
+			}
+		}
+	}
+
+	// rule 4: dark/light balance
+	dark := 0
+	for y := 0; y < n; y++ {
+		for x := 0; x < n; x++ {
+			if c.dark[y][x] {
+				dark++
+			}
+		}
+	}
+	total := n * n
+	k := (abs(dark*20-total*10)+total-1)/total - 1
+	score += k * 10
+
+	return score
+}
+
+// runPenalty scores one row or column for rules 1 and 3.
+func runPenalty(n int, at func(int) bool) int {
+	score := 0
+	run := 1
+	for i := 1; i <= n; i++ {
+		if i < n && at(i) == at(i-1) {
+			run++
+			continue
+		}
+		if run >= 5 {
+			score += 3 + run - 5
+		}
+		run = 1
+	}
+
+	// finder-like pattern with four light modules on either side;
+	// positions outside the symbol count as light
+	get := func(i int) bool { return i >= 0 && i < n && at(i) }
+	pattern := []bool{true, false, true, true, true, false, true}
+	for i := -4; i < n; i++ {
+		match := true
+		for j, p := range pattern {
+			if get(i+j) != p {
+				match = false
+				break
+			}
+		}
+		if !match {
+			continue
+		}
+		before, after := true, true
+		for j := 1; j <= 4; j++ {
+			if get(i - j) {
+				before = false
+			}
+			if get(i + 6 + j) {
+				after = false
+			}
+		}
+		if before || after {
+			score += 40
+		}
+	}
+	return score
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
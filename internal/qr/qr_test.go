@@ -0,0 +1,195 @@
+package qr
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/zarlcorp/zvault/internal/secret"
+)
+
+func TestCapacity(t *testing.T) {
+	tests := []struct {
+		version int
+		level   Level
+		want    int
+	}{
+		{1, L, 17},
+		{1, M, 14},
+		{1, Q, 11},
+		{1, H, 7},
+		{10, M, 213},
+		{40, L, 2953},
+		{40, H, 1273},
+	}
+
+	for _, tt := range tests {
+		if got := capacity(tt.version, tt.level); got != tt.want {
+			t.Errorf("capacity(%d, %d) = %d, want %d", tt.version, tt.level, got, tt.want)
+		}
+	}
+}
+
+func TestEncodeVersionSelection(t *testing.T) {
+	tests := []struct {
+		n    int
+		want int
+	}{
+		{1, 1},
+		{14, 1},
+		{15, 2},
+		{100, 6},
+	}
+
+	for _, tt := range tests {
+		c, err := Encode(strings.Repeat("a", tt.n), M)
+		if err != nil {
+			t.Fatalf("encode %d bytes: %v", tt.n, err)
+		}
+		if c.Version != tt.want {
+			t.Errorf("%d bytes: version = %d, want %d", tt.n, c.Version, tt.want)
+		}
+		if c.Size != tt.want*4+17 {
+			t.Errorf("%d bytes: size = %d, want %d", tt.n, c.Size, tt.want*4+17)
+		}
+	}
+}
+
+func TestEncodeTooLong(t *testing.T) {
+	_, err := Encode(strings.Repeat("a", 3000), L)
+	if !errors.Is(err, ErrTooLong) {
+		t.Fatalf("err = %v, want ErrTooLong", err)
+	}
+}
+
+func TestEncodeFinderPatterns(t *testing.T) {
+	c, err := Encode("otpauth://totp/GitHub:alice?secret=JBSWY3DPEHPK3PXP", M)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	corners := [][2]int{{0, 0}, {c.Size - 7, 0}, {0, c.Size - 7}}
+	for _, o := range corners {
+		for d := 0; d < 7; d++ {
+			// outer ring is dark on every side
+			for _, p := range [][2]int{{d, 0}, {d, 6}, {0, d}, {6, d}} {
+				if !c.Black(o[0]+p[0], o[1]+p[1]) {
+					t.Fatalf("finder at %v: module %v should be dark", o, p)
+				}
+			}
+		}
+		if c.Black(o[0]+1, o[1]+1) {
+			t.Fatalf("finder at %v: inner ring should be light", o)
+		}
+		if !c.Black(o[0]+3, o[1]+3) {
+			t.Fatalf("finder at %v: centre should be dark", o)
+		}
+	}
+
+	// dark module next to the bottom-left finder
+	if !c.Black(8, c.Size-8) {
+		t.Fatal("dark module missing")
+	}
+}
+
+func TestRSRemainder(t *testing.T) {
+	// a valid codeword evaluates to zero at every generator root
+	data := []byte("zvault reed-solomon")
+	gen := rsGenerator(10)
+	word := append(append([]byte{}, data...), rsRemainder(data, gen)...)
+
+	root := byte(1)
+	for i := 0; i < 10; i++ {
+		var sum byte
+		for _, b := range word {
+			sum = gfMul(sum, root) ^ b
+		}
+		if sum != 0 {
+			t.Fatalf("codeword does not vanish at root %d", i)
+		}
+		root = gfMul(root, 0x02)
+	}
+}
+
+func TestTerminal(t *testing.T) {
+	c, err := Encode("hello", L)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out := c.Terminal()
+	lines := strings.Split(strings.TrimSuffix(out, "\n"), "\n")
+
+	width := c.Size + 2*quietZone
+	if want := (width + 1) / 2; len(lines) != want {
+		t.Fatalf("lines = %d, want %d", len(lines), want)
+	}
+	for i, l := range lines {
+		l = strings.TrimPrefix(l, "\x1b[30;47m")
+		l = strings.TrimSuffix(l, "\x1b[0m")
+		if n := len([]rune(l)); n != width {
+			t.Fatalf("line %d width = %d, want %d", i, n, width)
+		}
+	}
+}
+
+func TestPayload(t *testing.T) {
+	pw, err := secret.NewPassword("GitHub", "https://github.com", "alice", "hunter2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	withTOTP := pw
+	withTOTP.Fields = map[string]string{"username": "alice", "password": "hunter2", "totp_secret": "JBSWY3DPEHPK3PXP"}
+
+	key, err := secret.NewAPIKey("stripe", "stripe.com", "sk_123")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		sec     secret.Secret
+		field   string
+		want    string
+		wantErr bool
+	}{
+		{"default password", pw, "", "hunter2", false},
+		{"default totp", withTOTP, "", "otpauth://totp/GitHub:alice?issuer=GitHub&secret=JBSWY3DPEHPK3PXP", false},
+		{"default apikey", key, "", "sk_123", false},
+		{"explicit field", pw, "url", "https://github.com", false},
+		{"wifi", pw, "wifi", "WIFI:T:WPA;S:alice;P:hunter2;;", false},
+		{"totp missing", pw, "totp", "", true},
+		{"wifi wrong type", key, "wifi", "", true},
+		{"unknown field", pw, "nope", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Payload(tt.sec, tt.field)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got %q", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("Payload() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWiFiEscaping(t *testing.T) {
+	got := WiFi(`my;net`, `p:a"ss`)
+	want := `WIFI:T:WPA;S:my\;net;P:p\:a\"ss;;`
+	if got != want {
+		t.Errorf("WiFi() = %q, want %q", got, want)
+	}
+
+	if got := WiFi("cafe", ""); got != "WIFI:T:nopass;S:cafe;;" {
+		t.Errorf("open network = %q", got)
+	}
+}
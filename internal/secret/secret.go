@@ -5,6 +5,8 @@ import (
 	"crypto/rand"
 	"encoding/hex"
 	"fmt"
+	"slices"
+	"strings"
 	"time"
 )
 
@@ -127,6 +129,18 @@ func (s Secret) Password() string { return s.field("password") }
 // TOTPSecret returns the totp_secret field (password type).
 func (s Secret) TOTPSecret() string { return s.field("totp_secret") }
 
+// TOTPIssuer returns the totp_issuer field (password type).
+func (s Secret) TOTPIssuer() string { return s.field("totp_issuer") }
+
+// TOTPAlgorithm returns the totp_algorithm field (password type).
+func (s Secret) TOTPAlgorithm() string { return s.field("totp_algorithm") }
+
+// TOTPDigits returns the totp_digits field (password type).
+func (s Secret) TOTPDigits() string { return s.field("totp_digits") }
+
+// TOTPPeriod returns the totp_period field (password type).
+func (s Secret) TOTPPeriod() string { return s.field("totp_period") }
+
 // Notes returns the notes field.
 func (s Secret) Notes() string { return s.field("notes") }
 
@@ -148,9 +162,87 @@ func (s Secret) PublicKey() string { return s.field("public_key") }
 // Passphrase returns the passphrase field (sshkey type).
 func (s Secret) Passphrase() string { return s.field("passphrase") }
 
+// KeyType returns the key_type field (sshkey type, read-only).
+func (s Secret) KeyType() string { return s.field("key_type") }
+
+// KeyBits returns the key_bits field (sshkey type, read-only).
+func (s Secret) KeyBits() string { return s.field("key_bits") }
+
+// KeyComment returns the key_comment field (sshkey type, read-only).
+func (s Secret) KeyComment() string { return s.field("key_comment") }
+
+// Fingerprint returns the SHA256 fingerprint field (sshkey type, read-only).
+func (s Secret) Fingerprint() string { return s.field("fingerprint") }
+
 // Content returns the content field (note type).
 func (s Secret) Content() string { return s.field("content") }
 
+// publicFields are the fields that identify a secret without revealing it.
+var publicFields = map[string]bool{
+	"url": true, "username": true, "service": true, "label": true,
+	"public_key": true, "key_type": true, "key_bits": true,
+	"key_comment": true, "fingerprint": true, "totp_issuer": true,
+}
+
+// typeFields are the fields each type of secret is made of, which detail
+// views show by name. The totp fields may be set on any type.
+var typeFields = map[Type][]string{
+	TypePassword: {"url", "username", "password", "notes"},
+	TypeAPIKey:   {"service", "key", "notes"},
+	TypeSSHKey: {"label", "private_key", "public_key", "passphrase", "notes",
+		"key_type", "key_bits", "key_comment", "fingerprint"},
+	TypeNote: {"content"},
+}
+
+// CustomFields returns the names of the fields that aren't part of s's
+// type, such as card details or custom fields imported from another
+// password manager, in sorted order.
+func (s Secret) CustomFields() []string {
+	var names []string
+	for k := range s.Fields {
+		if !slices.Contains(typeFields[s.Type], k) && !strings.HasPrefix(k, "totp_") {
+			names = append(names, k)
+		}
+	}
+	slices.Sort(names)
+	return names
+}
+
+// Redacted returns a copy of s holding only the fields that identify it:
+// urls, usernames, services, labels and public key details. Passwords,
+// keys, notes and custom fields are dropped.
+func (s Secret) Redacted() Secret {
+	fields := make(map[string]string)
+	for k, v := range s.Fields {
+		if publicFields[k] {
+			fields[k] = v
+		}
+	}
+	s.Fields = fields
+	s.Tags = append([]string(nil), s.Tags...)
+	return s
+}
+
+// Clone returns a copy of s with a new id and the current time, for
+// storing it as a separate secret.
+func (s Secret) Clone() (Secret, error) {
+	id, err := generateID()
+	if err != nil {
+		return Secret{}, fmt.Errorf("clone secret: %w", err)
+	}
+	fields := make(map[string]string, len(s.Fields))
+	for k, v := range s.Fields {
+		fields[k] = v
+	}
+	now := time.Now()
+	s.ID = id
+	s.Fields = fields
+	s.Tags = append([]string(nil), s.Tags...)
+	s.CreatedAt = now
+	s.UpdatedAt = now
+	return s, nil
+}
+
 // generateID returns an 8-character hex string from 4 random bytes.
 func generateID() (string, error) {
 	b := make([]byte, 4)
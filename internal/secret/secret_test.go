@@ -2,7 +2,9 @@ package secret_test
 
 import (
 	"encoding/hex"
+	"slices"
 	"testing"
+	"time"
 
 	"github.com/zarlcorp/zvault/internal/secret"
 )
@@ -238,6 +240,27 @@ func TestSSHKeyPassphrase(t *testing.T) {
 	}
 }
 
+func TestSSHKeyInfoFields(t *testing.T) {
+	s, err := secret.NewSSHKey("test", "lbl", "priv", "pub")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Fields["key_type"] = "ed25519"
+	s.Fields["key_bits"] = "256"
+	s.Fields["key_comment"] = "me@host"
+	s.Fields["fingerprint"] = "SHA256:abc"
+
+	if s.KeyType() != "ed25519" || s.KeyBits() != "256" {
+		t.Fatalf("key = %q %q, want ed25519 256", s.KeyType(), s.KeyBits())
+	}
+	if s.KeyComment() != "me@host" {
+		t.Fatalf("comment = %q, want %q", s.KeyComment(), "me@host")
+	}
+	if s.Fingerprint() != "SHA256:abc" {
+		t.Fatalf("fingerprint = %q, want %q", s.Fingerprint(), "SHA256:abc")
+	}
+}
+
 func TestIDUniqueness(t *testing.T) {
 	seen := make(map[string]bool)
 	for range 100 {
@@ -296,3 +319,82 @@ func assertTimestampsSet(t *testing.T, s secret.Secret) {
 		t.Fatal("updated_at is zero")
 	}
 }
+
+func TestRedacted(t *testing.T) {
+	s, err := secret.NewPassword("github", "https://github.com", "user", "pass123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Fields["totp_secret"] = "JBSWY3DPEHPK3PXP"
+	s.Fields["notes"] = "recovery codes"
+	s.Tags = []string{"work"}
+
+	r := s.Redacted()
+	if r.URL() != "https://github.com" || r.Username() != "user" {
+		t.Fatalf("redacted lost public fields: %v", r.Fields)
+	}
+	for _, k := range []string{"password", "totp_secret", "notes"} {
+		if _, ok := r.Fields[k]; ok {
+			t.Fatalf("redacted kept %s", k)
+		}
+	}
+	if r.ID != s.ID || r.Name != s.Name || !r.CreatedAt.Equal(s.CreatedAt) {
+		t.Fatal("redacted changed identity or timestamps")
+	}
+	if s.Password() != "pass123" {
+		t.Fatal("redacted modified the original")
+	}
+}
+
+func TestCustomFields(t *testing.T) {
+	s, err := secret.NewNote("Visa", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Fields["number"] = "4111111111111111"
+	s.Fields["code"] = "123"
+	s.Fields["totp_secret"] = "JBSWY3DPEHPK3PXP"
+
+	if got := s.CustomFields(); !slices.Equal(got, []string{"code", "number"}) {
+		t.Fatalf("custom fields = %q", got)
+	}
+
+	p, err := secret.NewPassword("github", "https://github.com", "user", "pass")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.Fields["notes"] = "recovery codes"
+	if got := p.CustomFields(); got != nil {
+		t.Fatalf("custom fields = %q, want none", got)
+	}
+}
+
+func TestClone(t *testing.T) {
+	s, err := secret.NewPassword("github", "https://github.com", "user", "pass123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Tags = []string{"work"}
+	s.CreatedAt = s.CreatedAt.Add(-time.Hour)
+
+	c, err := s.Clone()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertValidID(t, c.ID)
+	if c.ID == s.ID {
+		t.Fatal("clone kept the id")
+	}
+	if !c.CreatedAt.After(s.CreatedAt) {
+		t.Fatal("clone kept the creation time")
+	}
+	if c.Name != s.Name || c.Type != s.Type || c.Password() != "pass123" {
+		t.Fatalf("clone lost data: %+v", c)
+	}
+
+	c.Fields["password"] = "changed"
+	c.Tags[0] = "home"
+	if s.Password() != "pass123" || s.Tags[0] != "work" {
+		t.Fatal("clone shares fields or tags with the original")
+	}
+}
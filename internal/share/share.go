@@ -0,0 +1,216 @@
+// Package share encrypts a single secret for someone else in the age
+// format, to X25519 or SSH public keys or a passphrase, and decrypts it
+// on the other side.
+package share
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"filippo.io/age"
+	"filippo.io/age/agessh"
+	"filippo.io/age/armor"
+	"github.com/zarlcorp/zvault/internal/secret"
+	"golang.org/x/crypto/ssh"
+)
+
+// payloadVersion is written into every shared secret.
+const payloadVersion = 1
+
+type payload struct {
+	Version int           `json:"zvault_share"`
+	Secret  secret.Secret `json:"secret"`
+}
+
+// ParseRecipients reads an age recipient (age1...), an SSH public key
+// line, or the path of a file holding them one per line, as age -R does.
+func ParseRecipients(s string) ([]age.Recipient, error) {
+	s = strings.TrimSpace(s)
+	if r, err := parseRecipient(s); err == nil {
+		return []age.Recipient{r}, nil
+	} else if looksLikeKey(s) {
+		return nil, err
+	}
+
+	f, err := os.Open(s)
+	if err != nil {
+		return nil, fmt.Errorf("recipient %q is not a public key or a readable file", s)
+	}
+	defer f.Close()
+
+	var rs []age.Recipient
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		r, err := parseRecipient(line)
+		if err != nil {
+			return nil, fmt.Errorf("%s line %d: %w", s, n, err)
+		}
+		rs = append(rs, r)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read %s: %w", s, err)
+	}
+	if len(rs) == 0 {
+		return nil, fmt.Errorf("%s has no recipients", s)
+	}
+	return rs, nil
+}
+
+func looksLikeKey(s string) bool {
+	return strings.HasPrefix(s, "age1") || strings.HasPrefix(s, "ssh-")
+}
+
+func parseRecipient(s string) (age.Recipient, error) {
+	switch {
+	case strings.HasPrefix(s, "age1"):
+		return age.ParseX25519Recipient(s)
+	case strings.HasPrefix(s, "ssh-"):
+		r, err := agessh.ParseRecipient(s)
+		if err != nil {
+			return nil, fmt.Errorf("ssh recipient: %w (only ed25519 and rsa keys are supported)", err)
+		}
+		return r, nil
+	}
+	return nil, fmt.Errorf("unknown recipient %q", s)
+}
+
+// Encrypt writes sec, with all of its fields, as ASCII-armored age
+// encrypted to recipients.
+func Encrypt(w io.Writer, sec secret.Secret, recipients ...age.Recipient) error {
+	if len(recipients) == 0 {
+		return errors.New("no recipients")
+	}
+	data, err := json.Marshal(payload{Version: payloadVersion, Secret: sec})
+	if err != nil {
+		return fmt.Errorf("marshal secret: %w", err)
+	}
+
+	aw := armor.NewWriter(w)
+	ew, err := age.Encrypt(aw, recipients...)
+	if err != nil {
+		return fmt.Errorf("encrypt: %w", err)
+	}
+	if _, err := ew.Write(data); err != nil {
+		return fmt.Errorf("encrypt: %w", err)
+	}
+	if err := ew.Close(); err != nil {
+		return fmt.Errorf("encrypt: %w", err)
+	}
+	return aw.Close()
+}
+
+// ErrNoIdentity is returned by Decrypt when none of the identities can
+// open the file.
+var ErrNoIdentity = errors.New("none of the available keys can decrypt this secret")
+
+// Decrypt opens a shared secret, armored or binary.
+func Decrypt(data []byte, identities ...age.Identity) (secret.Secret, error) {
+	r, err := age.Decrypt(dearmor(data), identities...)
+	if err != nil {
+		var noMatch *age.NoIdentityMatchError
+		if errors.As(err, &noMatch) {
+			return secret.Secret{}, ErrNoIdentity
+		}
+		return secret.Secret{}, fmt.Errorf("decrypt: %w", err)
+	}
+	plain, err := io.ReadAll(r)
+	if err != nil {
+		return secret.Secret{}, fmt.Errorf("decrypt: %w", err)
+	}
+
+	var p payload
+	if err := json.Unmarshal(plain, &p); err != nil || p.Version == 0 {
+		return secret.Secret{}, errors.New("not a shared zvault secret")
+	}
+	if p.Version > payloadVersion {
+		return secret.Secret{}, fmt.Errorf("shared secret version %d is newer than this zvault supports", p.Version)
+	}
+	if p.Secret.Name == "" || p.Secret.Type == "" {
+		return secret.Secret{}, errors.New("shared secret has no name or type")
+	}
+	if p.Secret.Fields == nil {
+		p.Secret.Fields = make(map[string]string)
+	}
+	return p.Secret, nil
+}
+
+func dearmor(data []byte) io.Reader {
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte(armor.Header)) {
+		return armor.NewReader(bytes.NewReader(data))
+	}
+	return bytes.NewReader(data)
+}
+
+// Passphrase reports whether data was encrypted with a passphrase rather
+// than to public keys.
+func Passphrase(data []byte) bool {
+	br := bufio.NewReader(dearmor(data))
+	for {
+		line, err := br.ReadString('\n')
+		if strings.HasPrefix(line, "-> scrypt ") {
+			return true
+		}
+		if err != nil || strings.HasPrefix(line, "---") {
+			return false
+		}
+	}
+}
+
+// SSHIdentity builds an identity from a parsed SSH private key. Only
+// ed25519 and rsa keys can be used.
+func SSHIdentity(key any) (age.Identity, error) {
+	switch k := key.(type) {
+	case ed25519.PrivateKey:
+		return agessh.NewEd25519Identity(k)
+	case *ed25519.PrivateKey:
+		return agessh.NewEd25519Identity(*k)
+	case *rsa.PrivateKey:
+		return agessh.NewRSAIdentity(k)
+	}
+	return nil, fmt.Errorf("unsupported key type %T (only ed25519 and rsa)", key)
+}
+
+// ParseIdentityFile reads an age identity file or an SSH private key. For
+// an encrypted SSH key, passphrase is called only if the key is needed.
+func ParseIdentityFile(data []byte, passphrase func() ([]byte, error)) ([]age.Identity, error) {
+	if bytes.Contains(data, []byte("AGE-SECRET-KEY-")) {
+		return age.ParseIdentities(bytes.NewReader(data))
+	}
+
+	id, err := agessh.ParseIdentity(data)
+	if err == nil {
+		return []age.Identity{id}, nil
+	}
+	var missing *ssh.PassphraseMissingError
+	if errors.As(err, &missing) && missing.PublicKey != nil {
+		id, err := agessh.NewEncryptedSSHIdentity(missing.PublicKey, data, passphrase)
+		if err != nil {
+			return nil, err
+		}
+		return []age.Identity{id}, nil
+	}
+	return nil, err
+}
+
+// PassphraseRecipient returns a recipient that encrypts to passphrase.
+func PassphraseRecipient(passphrase string) (age.Recipient, error) {
+	return age.NewScryptRecipient(passphrase)
+}
+
+// PassphraseIdentity returns the identity that opens a secret encrypted to
+// passphrase.
+func PassphraseIdentity(passphrase string) (age.Identity, error) {
+	return age.NewScryptIdentity(passphrase)
+}
@@ -0,0 +1,245 @@
+package share
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"filippo.io/age"
+	"github.com/zarlcorp/zvault/internal/secret"
+	"golang.org/x/crypto/ssh"
+)
+
+func testSecret(t *testing.T) secret.Secret {
+	t.Helper()
+	sec, err := secret.NewPassword("github", "https://github.com", "alice", "hunter2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sec.Fields["totp_secret"] = "JBSWY3DPEHPK3PXP"
+	sec.Tags = []string{"work"}
+	return sec
+}
+
+func encrypt(t *testing.T, sec secret.Secret, rs ...age.Recipient) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := Encrypt(&buf, sec, rs...); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestX25519RoundTrip(t *testing.T) {
+	id, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rs, err := ParseRecipients(id.Recipient().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sec := testSecret(t)
+	data := encrypt(t, sec, rs...)
+	if !strings.HasPrefix(string(data), "-----BEGIN AGE ENCRYPTED FILE-----") {
+		t.Fatalf("output is not armored:\n%s", data)
+	}
+	if bytes.Contains(data, []byte("hunter2")) {
+		t.Fatal("output contains the plaintext password")
+	}
+	if Passphrase(data) {
+		t.Fatal("Passphrase = true for a public key recipient")
+	}
+
+	got, err := Decrypt(data, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.ID != sec.ID || got.Name != "github" || got.Password() != "hunter2" ||
+		got.TOTPSecret() != "JBSWY3DPEHPK3PXP" || len(got.Tags) != 1 {
+		t.Fatalf("got %+v", got)
+	}
+
+	other, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Decrypt(data, other); !errors.Is(err, ErrNoIdentity) {
+		t.Fatalf("wrong key: err = %v, want ErrNoIdentity", err)
+	}
+}
+
+func TestSSHRoundTrip(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sshPub, err := ssh.NewPublicKey(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rs, err := ParseRecipients(string(ssh.MarshalAuthorizedKey(sshPub)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := encrypt(t, testSecret(t), rs...)
+
+	// a key parsed from the vault
+	id, err := SSHIdentity(&priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Decrypt(data, id); err != nil {
+		t.Fatal(err)
+	}
+
+	// a key file on disk
+	block, err := ssh.MarshalPrivateKey(priv, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids, err := ParseIdentityFile(pem.EncodeToMemory(block), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Decrypt(data, ids...); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestEncryptedSSHKeyFile(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sshPub, err := ssh.NewPublicKey(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rs, err := ParseRecipients(string(ssh.MarshalAuthorizedKey(sshPub)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := encrypt(t, testSecret(t), rs...)
+
+	block, err := ssh.MarshalPrivateKeyWithPassphrase(priv, "", []byte("pw"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	asked := 0
+	ids, err := ParseIdentityFile(pem.EncodeToMemory(block), func() ([]byte, error) {
+		asked++
+		return []byte("pw"), nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if asked != 0 {
+		t.Fatal("passphrase asked for before the key was needed")
+	}
+	if _, err := Decrypt(data, ids...); err != nil {
+		t.Fatal(err)
+	}
+	if asked != 1 {
+		t.Fatalf("passphrase asked %d times, want 1", asked)
+	}
+}
+
+func TestPassphraseRoundTrip(t *testing.T) {
+	r, err := PassphraseRecipient("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := encrypt(t, testSecret(t), r)
+	if !Passphrase(data) {
+		t.Fatal("Passphrase = false for a passphrase recipient")
+	}
+
+	id, err := PassphraseIdentity("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := Decrypt(data, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Password() != "hunter2" {
+		t.Fatalf("password = %q", got.Password())
+	}
+
+	wrong, err := PassphraseIdentity("wrong")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Decrypt(data, wrong); err == nil {
+		t.Fatal("expected error for wrong passphrase")
+	}
+}
+
+func TestParseRecipientsFile(t *testing.T) {
+	a, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "team.txt")
+	content := "# team\n" + a.Recipient().String() + "\n\n" + b.Recipient().String() + "\n"
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	rs, err := ParseRecipients(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rs) != 2 {
+		t.Fatalf("got %d recipients, want 2", len(rs))
+	}
+
+	data := encrypt(t, testSecret(t), rs...)
+	for _, id := range []age.Identity{a, b} {
+		if _, err := Decrypt(data, id); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestParseRecipientsErrors(t *testing.T) {
+	for _, s := range []string{
+		"age1notvalid",
+		"ssh-ed25519 AAAAnotbase64",
+		filepath.Join(t.TempDir(), "missing.txt"),
+	} {
+		if _, err := ParseRecipients(s); err == nil {
+			t.Errorf("ParseRecipients(%q): expected error", s)
+		}
+	}
+}
+
+func TestDecryptNotShare(t *testing.T) {
+	id, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	w, err := age.Encrypt(&buf, id.Recipient())
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write([]byte("just some text"))
+	w.Close()
+
+	if _, err := Decrypt(buf.Bytes(), id); err == nil {
+		t.Fatal("expected error for a file that is not a shared secret")
+	}
+}
@@ -0,0 +1,17 @@
+//go:build !windows
+
+package sshagent
+
+import (
+	"net"
+	"syscall"
+)
+
+// listenUnix creates the socket with no access for group or others from
+// the start, rather than chmodding it after others could connect. The
+// umask is process-wide, so this runs before the agent starts serving.
+func listenUnix(path string) (net.Listener, error) {
+	old := syscall.Umask(0o177)
+	defer syscall.Umask(old)
+	return net.Listen("unix", path)
+}
@@ -0,0 +1,9 @@
+package sshagent
+
+import "net"
+
+// listenUnix creates the socket. Windows has no umask; the socket gets the
+// permissions of the directory it is in.
+func listenUnix(path string) (net.Listener, error) {
+	return net.Listen("unix", path)
+}
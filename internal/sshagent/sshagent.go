@@ -0,0 +1,208 @@
+// Package sshagent serves SSH keys from the vault over the OpenSSH agent
+// protocol, so private keys never need to exist as plaintext files.
+package sshagent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+
+	"github.com/zarlcorp/zvault/internal/secret"
+	"github.com/zarlcorp/zvault/internal/sshkey"
+)
+
+// ConfirmFunc asks the user whether a signature may be made with the key
+// described by comment. It must return false if the user cannot be asked.
+type ConfirmFunc func(comment string) bool
+
+// Agent is an in-memory keyring that can require confirmation per key.
+// It wraps the x/crypto keyring, which handles lifetimes but ignores the
+// confirm constraint.
+type Agent struct {
+	keyring agent.ExtendedAgent
+	confirm ConfirmFunc
+
+	mu          sync.Mutex
+	mustConfirm map[string]bool // keyed by marshalled public key
+}
+
+var _ agent.ExtendedAgent = (*Agent)(nil)
+
+// New creates an empty agent. confirm is called before signing with keys
+// that were added with confirmation required; nil denies such requests.
+func New(confirm ConfirmFunc) *Agent {
+	return &Agent{
+		keyring:     agent.NewKeyring().(agent.ExtendedAgent),
+		confirm:     confirm,
+		mustConfirm: make(map[string]bool),
+	}
+}
+
+// AddSecret parses the private key of an SSH key secret, decrypting it with
+// the stored passphrase, and adds it to the agent.
+func (a *Agent) AddSecret(sec secret.Secret, lifetime time.Duration, confirm bool) error {
+	if sec.Type != secret.TypeSSHKey {
+		return fmt.Errorf("secret %q is not an ssh key", sec.Name)
+	}
+
+	key, err := sshkey.ParsePrivateKey(sec.PrivateKey(), sec.Passphrase())
+	if err != nil {
+		return fmt.Errorf("secret %q: %w", sec.Name, err)
+	}
+
+	comment := sec.Label()
+	if comment == "" {
+		comment = sec.Name
+	}
+
+	return a.Add(agent.AddedKey{
+		PrivateKey:       key,
+		Comment:          comment,
+		LifetimeSecs:     uint32(lifetime / time.Second),
+		ConfirmBeforeUse: confirm,
+	})
+}
+
+// Add adds a key, honouring the confirm constraint.
+func (a *Agent) Add(key agent.AddedKey) error {
+	signer, err := ssh.NewSignerFromKey(key.PrivateKey)
+	if err != nil {
+		return err
+	}
+	if err := a.keyring.Add(key); err != nil {
+		return err
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	blob := string(signer.PublicKey().Marshal())
+	if key.ConfirmBeforeUse {
+		a.mustConfirm[blob] = true
+	} else {
+		delete(a.mustConfirm, blob)
+	}
+	return nil
+}
+
+// List returns the identities in the agent.
+func (a *Agent) List() ([]*agent.Key, error) { return a.keyring.List() }
+
+// Sign signs data with the given key, asking for confirmation if required.
+func (a *Agent) Sign(key ssh.PublicKey, data []byte) (*ssh.Signature, error) {
+	return a.SignWithFlags(key, data, 0)
+}
+
+// SignWithFlags signs data with the given key and signature flags.
+func (a *Agent) SignWithFlags(key ssh.PublicKey, data []byte, flags agent.SignatureFlags) (*ssh.Signature, error) {
+	if err := a.checkConfirm(key); err != nil {
+		return nil, err
+	}
+	return a.keyring.SignWithFlags(key, data, flags)
+}
+
+func (a *Agent) checkConfirm(key ssh.PublicKey) error {
+	a.mu.Lock()
+	must := a.mustConfirm[string(key.Marshal())]
+	a.mu.Unlock()
+	if !must {
+		return nil
+	}
+
+	// A locked agent lists no keys, and removed or expired keys are gone
+	// from the list; the keyring refuses those without asking anyone.
+	keys, err := a.keyring.List()
+	if err != nil {
+		return err
+	}
+	i := slices.IndexFunc(keys, func(k *agent.Key) bool { return string(k.Blob) == string(key.Marshal()) })
+	if i < 0 {
+		return nil
+	}
+	comment := keys[i].Comment + " " + ssh.FingerprintSHA256(key)
+
+	if a.confirm == nil || !a.confirm(comment) {
+		return errors.New("agent refused operation")
+	}
+	return nil
+}
+
+// Remove removes a key from the agent.
+func (a *Agent) Remove(key ssh.PublicKey) error {
+	a.mu.Lock()
+	delete(a.mustConfirm, string(key.Marshal()))
+	a.mu.Unlock()
+	return a.keyring.Remove(key)
+}
+
+// RemoveAll removes all keys from the agent.
+func (a *Agent) RemoveAll() error {
+	a.mu.Lock()
+	a.mustConfirm = make(map[string]bool)
+	a.mu.Unlock()
+	return a.keyring.RemoveAll()
+}
+
+// Lock locks the agent until Unlock is called with the same passphrase.
+func (a *Agent) Lock(passphrase []byte) error { return a.keyring.Lock(passphrase) }
+
+// Unlock undoes Lock.
+func (a *Agent) Unlock(passphrase []byte) error { return a.keyring.Unlock(passphrase) }
+
+// Signers returns signers for all keys. Used by in-process callers only;
+// confirmation is not applied.
+func (a *Agent) Signers() ([]ssh.Signer, error) { return a.keyring.Signers() }
+
+// Extension reports that no extensions are supported.
+func (a *Agent) Extension(string, []byte) ([]byte, error) {
+	return nil, agent.ErrExtensionUnsupported
+}
+
+// Listen creates a Unix socket at path readable only by the current user.
+// A stale socket left behind by a previous run is replaced.
+func Listen(path string) (net.Listener, error) {
+	if _, err := os.Stat(path); err == nil {
+		if c, err := net.Dial("unix", path); err == nil {
+			c.Close()
+			return nil, fmt.Errorf("agent already running at %s", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, fmt.Errorf("remove stale socket: %w", err)
+		}
+	}
+
+	l, err := listenUnix(path)
+	if err != nil {
+		return nil, fmt.Errorf("listen: %w", err)
+	}
+	return l, nil
+}
+
+// Serve accepts connections until ctx is cancelled, serving each one with a.
+func Serve(ctx context.Context, l net.Listener, a agent.Agent) error {
+	go func() {
+		<-ctx.Done()
+		l.Close()
+	}()
+
+	for {
+		c, err := l.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("accept: %w", err)
+		}
+		go func() {
+			defer c.Close()
+			_ = agent.ServeAgent(a, c)
+		}()
+	}
+}
@@ -0,0 +1,227 @@
+package sshagent
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+
+	"github.com/zarlcorp/zvault/internal/secret"
+)
+
+func newKeySecret(t *testing.T, name, passphrase string) (secret.Secret, ssh.PublicKey) {
+	t.Helper()
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var block *pem.Block
+	if passphrase != "" {
+		block, err = ssh.MarshalPrivateKeyWithPassphrase(priv, name, []byte(passphrase))
+	} else {
+		block, err = ssh.MarshalPrivateKey(priv, name)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sshPub, err := ssh.NewPublicKey(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sec, err := secret.NewSSHKey(name, name+"@test", string(pem.EncodeToMemory(block)), string(ssh.MarshalAuthorizedKey(sshPub)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if passphrase != "" {
+		sec.Fields["passphrase"] = passphrase
+	}
+	return sec, sshPub
+}
+
+// client serves a over an in-memory pipe and returns a protocol client.
+func client(t *testing.T, a agent.Agent) agent.ExtendedAgent {
+	t.Helper()
+	c1, c2 := net.Pipe()
+	go func() { _ = agent.ServeAgent(a, c2) }()
+	t.Cleanup(func() { c1.Close(); c2.Close() })
+	return agent.NewClient(c1)
+}
+
+func TestAddSecretAndSign(t *testing.T) {
+	a := New(nil)
+	sec, pub := newKeySecret(t, "deploy", "")
+	if err := a.AddSecret(sec, 0, false); err != nil {
+		t.Fatal(err)
+	}
+
+	c := client(t, a)
+	keys, err := c.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 1 || keys[0].Comment != "deploy@test" {
+		t.Fatalf("keys = %v", keys)
+	}
+
+	data := []byte("challenge")
+	sig, err := c.Sign(pub, data)
+	if err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+	if err := pub.Verify(data, sig); err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+}
+
+func TestAddSecretEncrypted(t *testing.T) {
+	a := New(nil)
+	sec, _ := newKeySecret(t, "enc", "hunter2")
+	if err := a.AddSecret(sec, 0, false); err != nil {
+		t.Fatalf("add with stored passphrase: %v", err)
+	}
+
+	sec.Fields["passphrase"] = ""
+	if err := New(nil).AddSecret(sec, 0, false); err == nil {
+		t.Fatal("expected error for encrypted key without passphrase")
+	}
+
+	sec.Fields["passphrase"] = "wrong"
+	if err := New(nil).AddSecret(sec, 0, false); err == nil {
+		t.Fatal("expected error for wrong passphrase")
+	}
+}
+
+func TestAddSecretWrongType(t *testing.T) {
+	note, err := secret.NewNote("n", "x")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := New(nil).AddSecret(note, 0, false); err == nil {
+		t.Fatal("expected error for non-ssh secret")
+	}
+}
+
+func TestConfirm(t *testing.T) {
+	var asked []string
+	allow := false
+	a := New(func(comment string) bool {
+		asked = append(asked, comment)
+		return allow
+	})
+
+	sec, pub := newKeySecret(t, "prod", "")
+	if err := a.AddSecret(sec, 0, true); err != nil {
+		t.Fatal(err)
+	}
+	c := client(t, a)
+
+	if _, err := c.Sign(pub, []byte("x")); err == nil {
+		t.Fatal("expected refusal when confirm returns false")
+	}
+
+	allow = true
+	if _, err := c.Sign(pub, []byte("x")); err != nil {
+		t.Fatalf("sign after confirm: %v", err)
+	}
+	if len(asked) != 2 {
+		t.Fatalf("confirm called %d times, want 2", len(asked))
+	}
+
+	// a locked agent refuses without asking
+	if err := a.Lock([]byte("pw")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Sign(pub, []byte("x")); err == nil {
+		t.Fatal("expected refusal while locked")
+	}
+	if len(asked) != 2 {
+		t.Fatalf("confirm called while locked")
+	}
+}
+
+func TestConfirmNilDenies(t *testing.T) {
+	a := New(nil)
+	sec, pub := newKeySecret(t, "prod", "")
+	if err := a.AddSecret(sec, 0, true); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.Sign(pub, []byte("x")); err == nil {
+		t.Fatal("expected refusal with no confirm func")
+	}
+}
+
+func TestLifetime(t *testing.T) {
+	a := New(nil)
+	sec, _ := newKeySecret(t, "short", "")
+	if err := a.AddSecret(sec, time.Second, false); err != nil {
+		t.Fatal(err)
+	}
+
+	keys, _ := a.List()
+	if len(keys) != 1 {
+		t.Fatalf("keys = %d, want 1", len(keys))
+	}
+
+	time.Sleep(1100 * time.Millisecond)
+	keys, _ = a.List()
+	if len(keys) != 0 {
+		t.Fatalf("keys = %d after lifetime, want 0", len(keys))
+	}
+}
+
+func TestListenAndServe(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "agent.sock")
+	l, err := Listen(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi, err := os.Stat(path); err != nil {
+		t.Fatal(err)
+	} else if runtime.GOOS != "windows" && fi.Mode().Perm() != 0o600 {
+		t.Errorf("socket mode = %v, want 0600", fi.Mode().Perm())
+	}
+
+	a := New(nil)
+	sec, _ := newKeySecret(t, "sock", "")
+	if err := a.AddSecret(sec, 0, false); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- Serve(ctx, l, a) }()
+
+	conn, err := net.Dial("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys, err := agent.NewClient(conn).List()
+	conn.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 1 {
+		t.Fatalf("keys = %d, want 1", len(keys))
+	}
+
+	if _, err := Listen(path); err == nil {
+		t.Fatal("expected error when an agent is already listening")
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("serve: %v", err)
+	}
+}
@@ -0,0 +1,223 @@
+package sshkey
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+
+	"github.com/zarlcorp/zvault/internal/secret"
+)
+
+// Info describes a validated key pair.
+type Info struct {
+	Type        string // ed25519, rsa, ecdsa, or the ssh algorithm name
+	Bits        int
+	Comment     string // from the public key line
+	Fingerprint string // SHA256:..., as printed by ssh-keygen -l
+	PublicKey   string // authorized_keys line
+	PrivateKey  string // the private key with line breaks restored
+}
+
+// ParsePrivateKey parses a PEM private key, decrypting it with passphrase
+// when the key is encrypted.
+func ParsePrivateKey(pemData, passphrase string) (any, error) {
+	if pemData == "" {
+		return nil, errors.New("no private key")
+	}
+
+	key, err := ssh.ParseRawPrivateKey([]byte(normalizePEM(pemData)))
+	if err == nil {
+		return key, nil
+	}
+
+	var missing *ssh.PassphraseMissingError
+	if !errors.As(err, &missing) {
+		return nil, fmt.Errorf("parse private key: %w", err)
+	}
+	if passphrase == "" {
+		return nil, errors.New("private key is encrypted and no passphrase is stored")
+	}
+
+	key, err = ssh.ParseRawPrivateKeyWithPassphrase([]byte(normalizePEM(pemData)), []byte(passphrase))
+	if err != nil {
+		return nil, fmt.Errorf("decrypt private key: %w", err)
+	}
+	return key, nil
+}
+
+// Inspect parses a key pair and checks that the public key belongs to the
+// private key. publicKey may be empty, in which case it is derived. An
+// encrypted private key without a passphrase is accepted when its public
+// half can still be read.
+func Inspect(privateKey, publicKey, passphrase string) (Info, error) {
+	privateKey = normalizePEM(privateKey)
+	if privateKey == "" {
+		return Info{}, errors.New("private key is required")
+	}
+
+	derived, err := privatePublicKey(privateKey, passphrase)
+	if err != nil {
+		return Info{}, err
+	}
+
+	var comment string
+	if publicKey = strings.TrimSpace(publicKey); publicKey != "" {
+		pub, c, _, _, err := ssh.ParseAuthorizedKey([]byte(publicKey))
+		if err != nil {
+			return Info{}, fmt.Errorf("malformed public key: %w", err)
+		}
+		if derived == nil {
+			derived = pub
+		} else if string(pub.Marshal()) != string(derived.Marshal()) {
+			return Info{}, errors.New("public key does not match private key")
+		}
+		comment = c
+	}
+	if derived == nil {
+		return Info{}, errors.New("private key is encrypted; add the passphrase or the public key")
+	}
+
+	if publicKey == "" {
+		publicKey = strings.TrimSpace(string(ssh.MarshalAuthorizedKey(derived)))
+	}
+
+	typ, bits := keySize(derived)
+	return Info{
+		Type:        typ,
+		Bits:        bits,
+		Comment:     comment,
+		Fingerprint: ssh.FingerprintSHA256(derived),
+		PublicKey:   publicKey,
+		PrivateKey:  privateKey,
+	}, nil
+}
+
+// privatePublicKey returns the public half of a private key. It returns nil
+// without error for an encrypted key whose public half is not readable.
+func privatePublicKey(privateKey, passphrase string) (ssh.PublicKey, error) {
+	raw, err := ParsePrivateKey(privateKey, passphrase)
+	if err == nil {
+		signer, err := ssh.NewSignerFromKey(raw)
+		if err != nil {
+			return nil, fmt.Errorf("parse private key: %w", err)
+		}
+		return signer.PublicKey(), nil
+	}
+
+	if passphrase == "" {
+		_, perr := ssh.ParseRawPrivateKey([]byte(privateKey))
+		var missing *ssh.PassphraseMissingError
+		if errors.As(perr, &missing) {
+			return missing.PublicKey, nil
+		}
+	}
+	return nil, err
+}
+
+func keySize(pub ssh.PublicKey) (string, int) {
+	cpk, ok := pub.(ssh.CryptoPublicKey)
+	if !ok {
+		return pub.Type(), 0
+	}
+	switch k := cpk.CryptoPublicKey().(type) {
+	case ed25519.PublicKey:
+		return TypeEd25519, 256
+	case *rsa.PublicKey:
+		return TypeRSA, k.N.BitLen()
+	case *ecdsa.PublicKey:
+		return TypeECDSA, k.Curve.Params().BitSize
+	}
+	return pub.Type(), 0
+}
+
+// Validate checks the key material of an ssh key secret and records the
+// read-only key_type, key_bits, key_comment and fingerprint fields. A
+// missing public key is filled in from the private key.
+func Validate(sec *secret.Secret) error {
+	info, err := Inspect(sec.PrivateKey(), sec.PublicKey(), sec.Passphrase())
+	if err != nil {
+		return err
+	}
+
+	sec.Fields["private_key"] = info.PrivateKey
+	sec.Fields["public_key"] = info.PublicKey
+	sec.Fields["key_type"] = info.Type
+	if info.Bits > 0 {
+		sec.Fields["key_bits"] = strconv.Itoa(info.Bits)
+	} else {
+		delete(sec.Fields, "key_bits")
+	}
+	sec.Fields["fingerprint"] = info.Fingerprint
+	if info.Comment != "" {
+		sec.Fields["key_comment"] = info.Comment
+	} else {
+		delete(sec.Fields, "key_comment")
+	}
+	return nil
+}
+
+// Summary formats the recorded key details of a secret like ssh-keygen -l:
+// "256 SHA256:... comment (ED25519)".
+func Summary(sec secret.Secret) string {
+	var parts []string
+	if sec.KeyBits() != "" {
+		parts = append(parts, sec.KeyBits())
+	}
+	parts = append(parts, sec.Fingerprint())
+	if sec.KeyComment() != "" {
+		parts = append(parts, sec.KeyComment())
+	}
+	if sec.KeyType() != "" {
+		parts = append(parts, "("+strings.ToUpper(sec.KeyType())+")")
+	}
+	return strings.Join(parts, " ")
+}
+
+// normalizePEM restores the line breaks of a PEM block that was pasted
+// into a single-line input, where newlines become spaces.
+func normalizePEM(s string) string {
+	s = strings.TrimSpace(s)
+	if strings.Contains(s, "\n") || !strings.HasPrefix(s, "-----BEGIN ") {
+		return s
+	}
+
+	hdrEnd := strings.Index(s[len("-----BEGIN "):], "-----")
+	end := strings.LastIndex(s, "-----END ")
+	if hdrEnd < 0 || end < 0 {
+		return s
+	}
+	header := s[:len("-----BEGIN ")+hdrEnd+len("-----")]
+	if end < len(header) {
+		return s
+	}
+
+	var lines []string
+	lines = append(lines, header)
+
+	// legacy encrypted PEM has "Name: value" headers before the body
+	fields := strings.Fields(s[len(header):end])
+	inHeaders := false
+	for i := 0; i < len(fields); i++ {
+		f := fields[i]
+		if strings.HasSuffix(f, ":") && i+1 < len(fields) {
+			lines = append(lines, f+" "+fields[i+1])
+			i++
+			inHeaders = true
+			continue
+		}
+		if inHeaders {
+			lines = append(lines, "")
+			inHeaders = false
+		}
+		lines = append(lines, f)
+	}
+
+	lines = append(lines, strings.TrimSpace(s[end:]))
+	return strings.Join(lines, "\n")
+}
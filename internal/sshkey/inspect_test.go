@@ -0,0 +1,172 @@
+package sshkey
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/zarlcorp/zvault/internal/secret"
+)
+
+func mustGenerate(t *testing.T, typ string, bits int, comment, passphrase string) Pair {
+	t.Helper()
+	p, err := Generate(typ, bits, comment, passphrase)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestInspect(t *testing.T) {
+	tests := []struct {
+		typ      string
+		bits     int
+		wantBits int
+	}{
+		{TypeEd25519, 0, 256},
+		{TypeRSA, 2048, 2048},
+		{TypeECDSA, 384, 384},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.typ, func(t *testing.T) {
+			p := mustGenerate(t, tt.typ, tt.bits, "me@host", "")
+
+			info, err := Inspect(p.PrivateKey, p.PublicKey, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if info.Type != tt.typ {
+				t.Errorf("type = %q, want %q", info.Type, tt.typ)
+			}
+			if info.Bits != tt.wantBits {
+				t.Errorf("bits = %d, want %d", info.Bits, tt.wantBits)
+			}
+			if info.Comment != "me@host" {
+				t.Errorf("comment = %q, want %q", info.Comment, "me@host")
+			}
+			if !strings.HasPrefix(info.Fingerprint, "SHA256:") {
+				t.Errorf("fingerprint = %q", info.Fingerprint)
+			}
+		})
+	}
+}
+
+func TestInspectDerivesPublicKey(t *testing.T) {
+	p := mustGenerate(t, TypeEd25519, 0, "", "")
+
+	info, err := Inspect(p.PrivateKey, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.PublicKey != p.PublicKey {
+		t.Errorf("public key = %q, want %q", info.PublicKey, p.PublicKey)
+	}
+}
+
+func TestInspectRejects(t *testing.T) {
+	p := mustGenerate(t, TypeEd25519, 0, "", "")
+	other := mustGenerate(t, TypeEd25519, 0, "", "")
+	enc := mustGenerate(t, TypeEd25519, 0, "", "hunter2")
+
+	lines := strings.Split(p.PrivateKey, "\n")
+	truncated := strings.Join(append(lines[:len(lines)/2], lines[len(lines)-1]), "\n")
+
+	tests := []struct {
+		name       string
+		priv, pub  string
+		passphrase string
+		want       string
+	}{
+		{"empty", "", p.PublicKey, "", "required"},
+		{"truncated", truncated, p.PublicKey, "", "parse private key"},
+		{"garbage", "not a key", "", "", "parse private key"},
+		{"malformed public", p.PrivateKey, "ssh-ed25519 AAAA", "", "malformed public key"},
+		{"mismatch", p.PrivateKey, other.PublicKey, "", "does not match"},
+		{"wrong passphrase", enc.PrivateKey, enc.PublicKey, "nope", "decrypt"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Inspect(tt.priv, tt.pub, tt.passphrase)
+			if err == nil {
+				t.Fatal("expected error")
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("error = %q, want it to contain %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestInspectEncrypted(t *testing.T) {
+	p := mustGenerate(t, TypeEd25519, 0, "", "hunter2")
+	other := mustGenerate(t, TypeEd25519, 0, "", "")
+
+	if _, err := Inspect(p.PrivateKey, p.PublicKey, "hunter2"); err != nil {
+		t.Fatalf("with passphrase: %v", err)
+	}
+	// the public half of an OpenSSH key is readable without the passphrase
+	if _, err := Inspect(p.PrivateKey, p.PublicKey, ""); err != nil {
+		t.Fatalf("without passphrase: %v", err)
+	}
+	if _, err := Inspect(p.PrivateKey, other.PublicKey, ""); err == nil {
+		t.Fatal("expected mismatch without passphrase")
+	}
+}
+
+func TestInspectSingleLinePaste(t *testing.T) {
+	p := mustGenerate(t, TypeEd25519, 0, "", "")
+	pasted := strings.ReplaceAll(p.PrivateKey, "\n", " ")
+
+	info, err := Inspect(pasted, p.PublicKey, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.PrivateKey != p.PrivateKey {
+		t.Errorf("line breaks not restored:\n%s", info.PrivateKey)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	p := mustGenerate(t, TypeRSA, 2048, "deploy", "")
+	sec, err := secret.NewSSHKey("deploy", "", p.PrivateKey, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := Validate(&sec); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]string{
+		"key_type": "rsa",
+		"key_bits": "2048",
+	}
+	for k, v := range want {
+		if sec.Fields[k] != v {
+			t.Errorf("%s = %q, want %q", k, sec.Fields[k], v)
+		}
+	}
+	if sec.Fingerprint() == "" || sec.PublicKey() == "" {
+		t.Errorf("fingerprint %q, public key %q should be set", sec.Fingerprint(), sec.PublicKey())
+	}
+	if _, ok := sec.Fields["key_comment"]; ok {
+		t.Error("derived public key has no comment")
+	}
+}
+
+func TestSummary(t *testing.T) {
+	sec, err := secret.NewSSHKey("test", "", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sec.Fields["key_type"] = "ed25519"
+	sec.Fields["key_bits"] = "256"
+	sec.Fields["key_comment"] = "me@host"
+	sec.Fields["fingerprint"] = "SHA256:abc"
+
+	want := "256 SHA256:abc me@host (ED25519)"
+	if got := Summary(sec); got != want {
+		t.Errorf("Summary = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,93 @@
+// Package sshkey generates SSH key pairs in OpenSSH format and validates
+// key material before it is stored.
+package sshkey
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/pem"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// Key types accepted by Generate.
+const (
+	TypeEd25519 = "ed25519"
+	TypeRSA     = "rsa"
+	TypeECDSA   = "ecdsa"
+)
+
+// Pair is a generated key pair, ready to store in a secret.
+type Pair struct {
+	PrivateKey string // OpenSSH PEM, encrypted when a passphrase was given
+	PublicKey  string // authorized_keys line, including the comment
+}
+
+// Generate creates a key pair. bits is ignored for ed25519; zero selects
+// 3072 for rsa and 256 for ecdsa.
+func Generate(typ string, bits int, comment, passphrase string) (Pair, error) {
+	var priv crypto.Signer
+	var err error
+
+	switch strings.ToLower(typ) {
+	case "", TypeEd25519:
+		_, priv, err = ed25519.GenerateKey(rand.Reader)
+	case TypeRSA:
+		if bits == 0 {
+			bits = 3072
+		}
+		if bits < 2048 || bits > 16384 {
+			return Pair{}, fmt.Errorf("rsa key size must be between 2048 and 16384 bits, got %d", bits)
+		}
+		priv, err = rsa.GenerateKey(rand.Reader, bits)
+	case TypeECDSA:
+		var curve elliptic.Curve
+		switch bits {
+		case 0, 256:
+			curve = elliptic.P256()
+		case 384:
+			curve = elliptic.P384()
+		case 521:
+			curve = elliptic.P521()
+		default:
+			return Pair{}, fmt.Errorf("ecdsa key size must be 256, 384 or 521 bits, got %d", bits)
+		}
+		priv, err = ecdsa.GenerateKey(curve, rand.Reader)
+	default:
+		return Pair{}, fmt.Errorf("unknown key type %q (use ed25519, rsa, ecdsa)", typ)
+	}
+	if err != nil {
+		return Pair{}, fmt.Errorf("generate %s key: %w", typ, err)
+	}
+
+	var block *pem.Block
+	if passphrase != "" {
+		block, err = ssh.MarshalPrivateKeyWithPassphrase(priv, comment, []byte(passphrase))
+	} else {
+		block, err = ssh.MarshalPrivateKey(priv, comment)
+	}
+	if err != nil {
+		return Pair{}, fmt.Errorf("marshal private key: %w", err)
+	}
+
+	pub, err := ssh.NewPublicKey(priv.Public())
+	if err != nil {
+		return Pair{}, fmt.Errorf("marshal public key: %w", err)
+	}
+
+	line := strings.TrimSpace(string(ssh.MarshalAuthorizedKey(pub)))
+	if comment != "" {
+		line += " " + comment
+	}
+
+	return Pair{
+		PrivateKey: strings.TrimSpace(string(pem.EncodeToMemory(block))),
+		PublicKey:  line,
+	}, nil
+}
@@ -0,0 +1,84 @@
+package sshkey
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+)
+
+func TestGenerate(t *testing.T) {
+	tests := []struct {
+		typ      string
+		bits     int
+		wantType string
+	}{
+		{"", 0, ssh.KeyAlgoED25519},
+		{"ed25519", 0, ssh.KeyAlgoED25519},
+		{"rsa", 2048, ssh.KeyAlgoRSA},
+		{"ecdsa", 0, ssh.KeyAlgoECDSA256},
+		{"ecdsa", 384, ssh.KeyAlgoECDSA384},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.wantType, func(t *testing.T) {
+			p, err := Generate(tt.typ, tt.bits, "me@host", "")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			pub, comment, _, _, err := ssh.ParseAuthorizedKey([]byte(p.PublicKey))
+			if err != nil {
+				t.Fatalf("parse public key: %v", err)
+			}
+			if pub.Type() != tt.wantType {
+				t.Errorf("type = %q, want %q", pub.Type(), tt.wantType)
+			}
+			if comment != "me@host" {
+				t.Errorf("comment = %q, want %q", comment, "me@host")
+			}
+
+			signer, err := ssh.ParsePrivateKey([]byte(p.PrivateKey))
+			if err != nil {
+				t.Fatalf("parse private key: %v", err)
+			}
+			if string(signer.PublicKey().Marshal()) != string(pub.Marshal()) {
+				t.Error("public key does not match private key")
+			}
+		})
+	}
+}
+
+func TestGeneratePassphrase(t *testing.T) {
+	p, err := Generate("ed25519", 0, "", "hunter2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Count(p.PublicKey, " ") != 1 {
+		t.Errorf("public key without comment should have no trailing field: %q", p.PublicKey)
+	}
+
+	if _, err := ssh.ParsePrivateKey([]byte(p.PrivateKey)); err == nil {
+		t.Fatal("expected encrypted key to need a passphrase")
+	}
+	if _, err := ssh.ParsePrivateKeyWithPassphrase([]byte(p.PrivateKey), []byte("hunter2")); err != nil {
+		t.Fatalf("decrypt: %v", err)
+	}
+}
+
+func TestGenerateInvalid(t *testing.T) {
+	tests := []struct {
+		typ  string
+		bits int
+	}{
+		{"dsa", 0},
+		{"rsa", 1024},
+		{"ecdsa", 512},
+	}
+
+	for _, tt := range tests {
+		if _, err := Generate(tt.typ, tt.bits, "", ""); err == nil {
+			t.Errorf("Generate(%q, %d): expected error", tt.typ, tt.bits)
+		}
+	}
+}
@@ -0,0 +1,188 @@
+package totp
+
+import (
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// Account is one entry from a Google Authenticator export.
+type Account struct {
+	Secret  string // base32, unpadded
+	Issuer  string
+	Name    string // account name, without the issuer prefix
+	Params  Params
+	HOTP    bool // counter-based; zvault only generates TOTP codes
+	Counter int64
+}
+
+// ParseMigration decodes an otpauth-migration://offline?data=... URI, as
+// produced by Google Authenticator's "transfer accounts" QR codes.
+//
+// The payload is a protobuf MigrationPayload message. It is decoded by hand
+// to avoid pulling in a protobuf dependency for a single message type.
+func ParseMigration(uri string) ([]Account, error) {
+	u, err := url.Parse(strings.TrimSpace(uri))
+	if err != nil {
+		return nil, fmt.Errorf("parse migration uri: %w", err)
+	}
+	if u.Scheme != "otpauth-migration" {
+		return nil, fmt.Errorf("not an otpauth-migration uri: %q", u.Scheme)
+	}
+
+	data := u.Query().Get("data")
+	if data == "" {
+		return nil, errors.New("migration uri has no data parameter")
+	}
+	// an unescaped '+' in the query decodes as a space
+	data = strings.ReplaceAll(data, " ", "+")
+
+	raw, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		raw, err = base64.RawStdEncoding.DecodeString(strings.TrimRight(data, "="))
+		if err != nil {
+			return nil, fmt.Errorf("decode migration data: %w", err)
+		}
+	}
+
+	return decodePayload(raw)
+}
+
+// decodePayload walks MigrationPayload, keeping only otp_parameters (field 1).
+func decodePayload(b []byte) ([]Account, error) {
+	var accounts []Account
+	err := walkFields(b, func(num int, wire int, val []byte, _ uint64) error {
+		if num != 1 || wire != wireBytes {
+			return nil
+		}
+		acc, err := decodeOtpParameters(val)
+		if err != nil {
+			return err
+		}
+		accounts = append(accounts, acc)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("decode migration payload: %w", err)
+	}
+	return accounts, nil
+}
+
+func decodeOtpParameters(b []byte) (Account, error) {
+	var acc Account
+	var secret []byte
+	var name string
+
+	err := walkFields(b, func(num int, wire int, val []byte, n uint64) error {
+		switch num {
+		case 1: // secret
+			secret = val
+		case 2: // name
+			name = string(val)
+		case 3: // issuer
+			acc.Issuer = string(val)
+		case 4: // algorithm: 1=SHA1 2=SHA256 3=SHA512 4=MD5
+			switch n {
+			case 2:
+				acc.Params.Algorithm = "SHA256"
+			case 3:
+				acc.Params.Algorithm = "SHA512"
+			case 4:
+				acc.Params.Algorithm = "MD5"
+			}
+		case 5: // digits: 1=six 2=eight
+			if n == 2 {
+				acc.Params.Digits = 8
+			}
+		case 6: // type: 1=HOTP 2=TOTP
+			acc.HOTP = n == 1
+		case 7: // counter
+			acc.Counter = int64(n)
+		}
+		return nil
+	})
+	if err != nil {
+		return Account{}, err
+	}
+	if len(secret) == 0 {
+		return Account{}, errors.New("account has no secret")
+	}
+
+	acc.Secret = base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(secret)
+	acc.Params = acc.Params.withDefaults()
+
+	// names are often "Issuer:account"; strip the duplicated issuer
+	acc.Name = name
+	if prefix, rest, ok := strings.Cut(name, ":"); ok && (acc.Issuer == "" || strings.EqualFold(prefix, acc.Issuer)) {
+		if acc.Issuer == "" {
+			acc.Issuer = prefix
+		}
+		acc.Name = strings.TrimSpace(rest)
+	}
+
+	return acc, nil
+}
+
+// protobuf wire types used by the migration payload.
+const (
+	wireVarint = 0
+	wireI64    = 1
+	wireBytes  = 2
+	wireI32    = 5
+)
+
+var errTruncated = errors.New("truncated protobuf message")
+
+// walkFields calls fn for each field in a protobuf message. Length-delimited
+// values are passed as val; varints as n. Fixed-width values are skipped.
+func walkFields(b []byte, fn func(num, wire int, val []byte, n uint64) error) error {
+	for len(b) > 0 {
+		tag, k := binary.Uvarint(b)
+		if k <= 0 {
+			return errTruncated
+		}
+		b = b[k:]
+		num, wire := int(tag>>3), int(tag&7)
+
+		var val []byte
+		var n uint64
+		switch wire {
+		case wireVarint:
+			n, k = binary.Uvarint(b)
+			if k <= 0 {
+				return errTruncated
+			}
+			b = b[k:]
+		case wireBytes:
+			l, k := binary.Uvarint(b)
+			if k <= 0 || uint64(len(b)-k) < l {
+				return errTruncated
+			}
+			val = b[k : k+int(l)]
+			b = b[k+int(l):]
+		case wireI64:
+			if len(b) < 8 {
+				return errTruncated
+			}
+			b = b[8:]
+			continue
+		case wireI32:
+			if len(b) < 4 {
+				return errTruncated
+			}
+			b = b[4:]
+			continue
+		default:
+			return fmt.Errorf("unsupported protobuf wire type %d", wire)
+		}
+
+		if err := fn(num, wire, val, n); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+package totp
+
+import (
+	"encoding/base64"
+	"net/url"
+	"testing"
+)
+
+func TestParseMigrationKnownExample(t *testing.T) {
+	// single TOTP account "Example:alice@google.com" with secret "Hello!\xde\xad\xbe\xef"
+	uri := "otpauth-migration://offline?data=CjEKCkhlbGxvId6tvu8SGEV4YW1wbGU6YWxpY2VAZ29vZ2xlLmNvbRoHRXhhbXBsZTAC"
+
+	accounts, err := ParseMigration(uri)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(accounts) != 1 {
+		t.Fatalf("accounts = %d, want 1", len(accounts))
+	}
+
+	a := accounts[0]
+	if a.Secret != "JBSWY3DPEHPK3PXP" {
+		t.Errorf("secret = %q", a.Secret)
+	}
+	if a.Issuer != "Example" {
+		t.Errorf("issuer = %q", a.Issuer)
+	}
+	if a.Name != "alice@google.com" {
+		t.Errorf("name = %q", a.Name)
+	}
+	if a.HOTP {
+		t.Error("expected TOTP account")
+	}
+	if a.Params != Default {
+		t.Errorf("params = %+v, want defaults", a.Params)
+	}
+}
+
+// pbField encodes a protobuf length-delimited or varint field for tests.
+func pbField(num int, v any) []byte {
+	switch v := v.(type) {
+	case int:
+		return append([]byte{byte(num<<3 | wireVarint)}, byte(v))
+	case string:
+		return append([]byte{byte(num<<3 | wireBytes), byte(len(v))}, v...)
+	case []byte:
+		return append([]byte{byte(num<<3 | wireBytes), byte(len(v))}, v...)
+	}
+	return nil
+}
+
+func TestParseMigrationBatch(t *testing.T) {
+	var first, second []byte
+	first = append(first, pbField(1, []byte("12345678901234567890"))...)
+	first = append(first, pbField(2, "bob")...)
+	first = append(first, pbField(3, "GitLab")...)
+	first = append(first, pbField(4, 2)...) // SHA256
+	first = append(first, pbField(5, 2)...) // eight digits
+	first = append(first, pbField(6, 2)...) // TOTP
+
+	second = append(second, pbField(1, []byte("abc"))...)
+	second = append(second, pbField(2, "Corp:carol")...)
+	second = append(second, pbField(6, 1)...) // HOTP
+	second = append(second, pbField(7, 5)...)
+
+	var payload []byte
+	payload = append(payload, pbField(1, first)...)
+	payload = append(payload, pbField(1, second)...)
+	payload = append(payload, pbField(2, 1)...) // version
+	payload = append(payload, pbField(3, 1)...) // batch size
+
+	uri := "otpauth-migration://offline?data=" + url.QueryEscape(base64.StdEncoding.EncodeToString(payload))
+	accounts, err := ParseMigration(uri)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(accounts) != 2 {
+		t.Fatalf("accounts = %d, want 2", len(accounts))
+	}
+
+	a := accounts[0]
+	if a.Issuer != "GitLab" || a.Name != "bob" {
+		t.Errorf("first = %q/%q", a.Issuer, a.Name)
+	}
+	if a.Params != (Params{Algorithm: "SHA256", Digits: 8, Period: 30}) {
+		t.Errorf("first params = %+v", a.Params)
+	}
+
+	b := accounts[1]
+	if b.Issuer != "Corp" || b.Name != "carol" {
+		t.Errorf("second = %q/%q, want issuer split from name", b.Issuer, b.Name)
+	}
+	if !b.HOTP || b.Counter != 5 {
+		t.Errorf("second hotp = %v counter = %d", b.HOTP, b.Counter)
+	}
+}
+
+func TestParseMigrationUnescapedPlus(t *testing.T) {
+	// '+' left unescaped decodes to a space in the query string
+	payload := pbField(1, append(pbField(1, []byte{0xfb, 0xff}), pbField(2, "x")...))
+	data := base64.StdEncoding.EncodeToString(payload)
+	accounts, err := ParseMigration("otpauth-migration://offline?data=" + data)
+	if err != nil {
+		t.Fatalf("data %q: %v", data, err)
+	}
+	if len(accounts) != 1 {
+		t.Fatalf("accounts = %d, want 1", len(accounts))
+	}
+}
+
+func TestParseMigrationErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		uri  string
+	}{
+		{"wrong scheme", "otpauth://totp/x?secret=AAAA"},
+		{"no data", "otpauth-migration://offline"},
+		{"bad base64", "otpauth-migration://offline?data=!!!"},
+		{"truncated", "otpauth-migration://offline?data=" + base64.StdEncoding.EncodeToString([]byte{0x0a, 0x10, 0x0a})},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseMigration(tt.uri); err == nil {
+				t.Fatal("expected error")
+			}
+		})
+	}
+}
@@ -3,38 +3,203 @@ package totp
 
 import (
 	"crypto/hmac"
+	"crypto/md5"
 	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
 	"encoding/base32"
 	"encoding/binary"
 	"fmt"
+	"hash"
+	"net/url"
+	"strconv"
 	"strings"
 	"time"
 )
 
-const (
-	period = 30 // seconds
-	digits = 6
-)
+// Params controls code generation. The zero value of each field means the
+// RFC 6238 default.
+type Params struct {
+	Algorithm string // SHA1, SHA256, SHA512 or MD5
+	Digits    int    // 6 or 8
+	Period    int    // seconds
+}
+
+// Default is what authenticator apps assume when no parameters are given.
+var Default = Params{Algorithm: "SHA1", Digits: 6, Period: 30}
+
+// ParseParams builds Params from stored string fields. Empty strings
+// fall back to the defaults.
+func ParseParams(algorithm, digits, period string) (Params, error) {
+	p := Default
+	if algorithm != "" {
+		p.Algorithm = strings.ToUpper(algorithm)
+		if _, err := hashFunc(p.Algorithm); err != nil {
+			return Params{}, err
+		}
+	}
+	if digits != "" {
+		n, err := strconv.Atoi(digits)
+		if err != nil || n < 6 || n > 8 {
+			return Params{}, fmt.Errorf("invalid totp digits %q", digits)
+		}
+		p.Digits = n
+	}
+	if period != "" {
+		n, err := strconv.Atoi(period)
+		if err != nil || n <= 0 {
+			return Params{}, fmt.Errorf("invalid totp period %q", period)
+		}
+		p.Period = n
+	}
+	return p, nil
+}
+
+func (p Params) withDefaults() Params {
+	if p.Algorithm == "" {
+		p.Algorithm = Default.Algorithm
+	}
+	if p.Digits == 0 {
+		p.Digits = Default.Digits
+	}
+	if p.Period == 0 {
+		p.Period = Default.Period
+	}
+	return p
+}
 
 // now is a function variable for testing.
 var now = time.Now
 
-// Generate returns a TOTP code and seconds remaining in the current period.
+// Generate returns a TOTP code and seconds remaining in the current period
+// using the default parameters.
 // The secret must be base32-encoded (standard TOTP format).
 func Generate(secret string) (string, int, error) {
+	return GenerateWith(secret, Default)
+}
+
+// GenerateWith is Generate with explicit algorithm, digits and period.
+func GenerateWith(secret string, p Params) (string, int, error) {
+	p = p.withDefaults()
+
 	key, err := decodeSecret(secret)
 	if err != nil {
 		return "", 0, fmt.Errorf("decode totp secret: %w", err)
 	}
 
+	h, err := hashFunc(p.Algorithm)
+	if err != nil {
+		return "", 0, err
+	}
+
 	t := now().Unix()
+	period := int64(p.Period)
 	counter := uint64(t / period)
-	remaining := period - int(t%period)
+	remaining := int(period - t%period)
 
-	code := hotp(key, counter)
+	code := hotp(h, key, counter, p.Digits)
 	return code, remaining, nil
 }
 
+// URI returns an otpauth:// key URI for the secret, as understood by
+// authenticator apps when scanned from a QR code. issuer and account are
+// optional labels shown in the app. Non-default parameters are included.
+func URI(secret, issuer, account string, p Params) string {
+	p = p.withDefaults()
+
+	label := account
+	if issuer != "" {
+		label = issuer + ":" + account
+	}
+
+	q := url.Values{}
+	q.Set("secret", strings.ToUpper(strings.ReplaceAll(secret, " ", "")))
+	if issuer != "" {
+		q.Set("issuer", issuer)
+	}
+	if p.Algorithm != Default.Algorithm {
+		q.Set("algorithm", p.Algorithm)
+	}
+	if p.Digits != Default.Digits {
+		q.Set("digits", strconv.Itoa(p.Digits))
+	}
+	if p.Period != Default.Period {
+		q.Set("period", strconv.Itoa(p.Period))
+	}
+
+	u := url.URL{
+		Scheme:   "otpauth",
+		Host:     "totp",
+		Path:     "/" + label,
+		RawQuery: q.Encode(),
+	}
+	return u.String()
+}
+
+// ParseURI reads an otpauth:// key URI, the inverse of URI. Counter-based
+// (hotp) URIs are returned with HOTP set.
+func ParseURI(s string) (Account, error) {
+	u, err := url.Parse(strings.TrimSpace(s))
+	if err != nil {
+		return Account{}, fmt.Errorf("parse otpauth uri: %w", err)
+	}
+	if u.Scheme != "otpauth" {
+		return Account{}, fmt.Errorf("not an otpauth uri: %q", u.Scheme)
+	}
+
+	var acc Account
+	switch strings.ToLower(u.Host) {
+	case "totp":
+	case "hotp":
+		acc.HOTP = true
+	default:
+		return Account{}, fmt.Errorf("unknown otpauth type %q", u.Host)
+	}
+
+	q := u.Query()
+	acc.Secret = strings.ToUpper(strings.TrimRight(strings.ReplaceAll(q.Get("secret"), " ", ""), "="))
+	if acc.Secret == "" {
+		return Account{}, fmt.Errorf("otpauth uri has no secret")
+	}
+	if _, err := decodeSecret(acc.Secret); err != nil {
+		return Account{}, fmt.Errorf("invalid totp secret: %w", err)
+	}
+
+	label := strings.TrimPrefix(u.Path, "/")
+	if issuer, name, ok := strings.Cut(label, ":"); ok {
+		acc.Issuer, acc.Name = strings.TrimSpace(issuer), strings.TrimSpace(name)
+	} else {
+		acc.Name = strings.TrimSpace(label)
+	}
+	if issuer := q.Get("issuer"); issuer != "" {
+		acc.Issuer = issuer
+	}
+
+	acc.Params, err = ParseParams(q.Get("algorithm"), q.Get("digits"), q.Get("period"))
+	if err != nil {
+		return Account{}, err
+	}
+	if c := q.Get("counter"); c != "" {
+		acc.Counter, _ = strconv.ParseInt(c, 10, 64)
+	}
+	return acc, nil
+}
+
+func hashFunc(algorithm string) (func() hash.Hash, error) {
+	switch algorithm {
+	case "SHA1":
+		return sha1.New, nil
+	case "SHA256":
+		return sha256.New, nil
+	case "SHA512":
+		return sha512.New, nil
+	case "MD5":
+		return md5.New, nil
+	default:
+		return nil, fmt.Errorf("unsupported totp algorithm %q", algorithm)
+	}
+}
+
 // decodeSecret strips spaces and decodes a base32 string.
 func decodeSecret(s string) ([]byte, error) {
 	s = strings.ToUpper(strings.ReplaceAll(s, " ", ""))
@@ -45,13 +210,13 @@ func decodeSecret(s string) ([]byte, error) {
 	return base32.StdEncoding.DecodeString(s)
 }
 
-// hotp implements HOTP (RFC 4226) — HMAC-SHA1 with dynamic truncation.
-func hotp(key []byte, counter uint64) string {
+// hotp implements HOTP (RFC 4226) — HMAC with dynamic truncation.
+func hotp(h func() hash.Hash, key []byte, counter uint64, digits int) string {
 	// counter as 8-byte big-endian
 	var buf [8]byte
 	binary.BigEndian.PutUint64(buf[:], counter)
 
-	mac := hmac.New(sha1.New, key)
+	mac := hmac.New(h, key)
 	mac.Write(buf[:])
 	sum := mac.Sum(nil)
 
@@ -59,5 +224,9 @@ func hotp(key []byte, counter uint64) string {
 	offset := sum[len(sum)-1] & 0x0f
 	code := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
 
-	return fmt.Sprintf("%0*d", digits, code%1000000)
+	mod := uint32(1)
+	for range digits {
+		mod *= 10
+	}
+	return fmt.Sprintf("%0*d", digits, code%mod)
 }
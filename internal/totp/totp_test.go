@@ -1,6 +1,7 @@
 package totp
 
 import (
+	"encoding/base32"
 	"testing"
 	"time"
 )
@@ -146,3 +147,157 @@ func TestRFC6238Vectors(t *testing.T) {
 	}
 	now = time.Now
 }
+
+func TestURI(t *testing.T) {
+	tests := []struct {
+		name    string
+		issuer  string
+		account string
+		want    string
+	}{
+		{"issuer and account", "GitHub", "alice", "otpauth://totp/GitHub:alice?issuer=GitHub&secret=JBSWY3DPEHPK3PXP"},
+		{"account only", "", "alice", "otpauth://totp/alice?secret=JBSWY3DPEHPK3PXP"},
+		{"spaces escaped", "My Bank", "a b", "otpauth://totp/My%20Bank:a%20b?issuer=My+Bank&secret=JBSWY3DPEHPK3PXP"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := URI("jbsw y3dp ehpk 3pxp", tt.issuer, tt.account, Default)
+			if got != tt.want {
+				t.Errorf("URI() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestURIParams(t *testing.T) {
+	got := URI("JBSWY3DPEHPK3PXP", "Example", "alice", Params{Algorithm: "SHA256", Digits: 8, Period: 60})
+	want := "otpauth://totp/Example:alice?algorithm=SHA256&digits=8&issuer=Example&period=60&secret=JBSWY3DPEHPK3PXP"
+	if got != want {
+		t.Errorf("URI() = %q, want %q", got, want)
+	}
+}
+
+func TestParseURI(t *testing.T) {
+	uri := URI("JBSWY3DPEHPK3PXP", "My Bank", "alice", Params{Algorithm: "SHA256", Digits: 8, Period: 60})
+	acc, err := ParseURI(uri)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Account{
+		Secret: "JBSWY3DPEHPK3PXP",
+		Issuer: "My Bank",
+		Name:   "alice",
+		Params: Params{Algorithm: "SHA256", Digits: 8, Period: 60},
+	}
+	if acc != want {
+		t.Errorf("got %+v, want %+v", acc, want)
+	}
+
+	acc, err = ParseURI("otpauth://totp/alice?secret=jbsw%20y3dp%20ehpk%203pxp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if acc.Secret != "JBSWY3DPEHPK3PXP" || acc.Issuer != "" || acc.Params != Default {
+		t.Errorf("got %+v", acc)
+	}
+
+	acc, err = ParseURI("otpauth://hotp/x?secret=JBSWY3DPEHPK3PXP&counter=7")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !acc.HOTP || acc.Counter != 7 {
+		t.Errorf("hotp: got %+v", acc)
+	}
+
+	for _, bad := range []string{
+		"https://example.com",
+		"otpauth://totp/x",
+		"otpauth://totp/x?secret=!!!",
+		"otpauth://totp/x?secret=JBSWY3DPEHPK3PXP&digits=12",
+		"otpauth://push/x?secret=JBSWY3DPEHPK3PXP",
+	} {
+		if _, err := ParseURI(bad); err == nil {
+			t.Errorf("ParseURI(%q) should fail", bad)
+		}
+	}
+}
+
+// RFC 6238 appendix B vectors for 8-digit codes across all three hashes.
+func TestGenerateWithRFC6238(t *testing.T) {
+	enc := base32.StdEncoding.WithPadding(base32.NoPadding)
+	keys := map[string]string{
+		"SHA1":   enc.EncodeToString([]byte("12345678901234567890")),
+		"SHA256": enc.EncodeToString([]byte("12345678901234567890123456789012")),
+		"SHA512": enc.EncodeToString([]byte("1234567890123456789012345678901234567890123456789012345678901234")),
+	}
+
+	tests := []struct {
+		time int64
+		alg  string
+		want string
+	}{
+		{59, "SHA1", "94287082"},
+		{59, "SHA256", "46119246"},
+		{59, "SHA512", "90693936"},
+		{1111111109, "SHA256", "68084774"},
+		{1111111109, "SHA512", "25091201"},
+		{20000000000, "SHA256", "77737706"},
+		{20000000000, "SHA512", "47863826"},
+	}
+
+	for _, tt := range tests {
+		now = func() time.Time { return time.Unix(tt.time, 0) }
+		code, _, err := GenerateWith(keys[tt.alg], Params{Algorithm: tt.alg, Digits: 8})
+		if err != nil {
+			t.Fatalf("%s at t=%d: %v", tt.alg, tt.time, err)
+		}
+		if code != tt.want {
+			t.Errorf("%s at t=%d: code = %q, want %q", tt.alg, tt.time, code, tt.want)
+		}
+	}
+	now = time.Now
+}
+
+func TestGenerateWithPeriod(t *testing.T) {
+	now = func() time.Time { return time.Unix(45, 0) }
+	defer func() { now = time.Now }()
+
+	_, remaining, err := GenerateWith("JBSWY3DPEHPK3PXP", Params{Period: 60})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if remaining != 15 {
+		t.Errorf("remaining = %d, want 15", remaining)
+	}
+}
+
+func TestParseParams(t *testing.T) {
+	tests := []struct {
+		alg, digits, period string
+		want                Params
+		wantErr             bool
+	}{
+		{"", "", "", Default, false},
+		{"sha256", "8", "60", Params{"SHA256", 8, 60}, false},
+		{"SHA3", "", "", Params{}, true},
+		{"", "5", "", Params{}, true},
+		{"", "", "0", Params{}, true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseParams(tt.alg, tt.digits, tt.period)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("ParseParams(%q, %q, %q): expected error", tt.alg, tt.digits, tt.period)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("ParseParams(%q, %q, %q): %v", tt.alg, tt.digits, tt.period, err)
+		}
+		if got != tt.want {
+			t.Errorf("ParseParams(%q, %q, %q) = %+v, want %+v", tt.alg, tt.digits, tt.period, got, tt.want)
+		}
+	}
+}
@@ -4,7 +4,7 @@ import (
 	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
-	"github.com/zarlcorp/core/pkg/zclipboard"
+	"github.com/zarlcorp/zvault/internal/clipboard"
 )
 
 const clipboardClearDelay = 10 * time.Second
@@ -21,19 +21,21 @@ type clipboardClearedMsg struct{}
 func copyToClipboard(field, val string) tea.Cmd {
 	return tea.Batch(
 		func() tea.Msg {
-			if err := zclipboard.Copy(val); err != nil {
+			if err := clipboard.Copy(val); err != nil {
 				return errMsg{err: err}
 			}
 			return clipboardCopiedMsg{field: field}
 		},
-		scheduleClipboardClear(),
+		scheduleClipboardClear(clipboard.Sum(val)),
 	)
 }
 
-// scheduleClipboardClear returns a tick command that fires after the clear delay.
-func scheduleClipboardClear() tea.Cmd {
+// scheduleClipboardClear returns a tick command that fires after the clear
+// delay. The clipboard is only cleared if it still holds the copied value,
+// identified by sum, so anything copied since survives.
+func scheduleClipboardClear(sum string) tea.Cmd {
 	return tea.Tick(clipboardClearDelay, func(time.Time) tea.Msg {
-		_ = zclipboard.Clear()
+		_, _ = clipboard.ClearIf(sum)
 		return clipboardClearedMsg{}
 	})
 }
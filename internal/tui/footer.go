@@ -35,6 +35,7 @@ func helpFor(id viewID) []zstyle.HelpPair {
 		return []zstyle.HelpPair{
 			{Key: "enter", Desc: "copy/open"},
 			{Key: "s", Desc: "show/hide"},
+			{Key: "r", Desc: "qr"},
 			{Key: "e", Desc: "edit"},
 			{Key: "d", Desc: "delete"},
 			{Key: "esc", Desc: "back"},
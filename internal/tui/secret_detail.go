@@ -11,7 +11,9 @@ import (
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/zarlcorp/core/pkg/zstyle"
+	"github.com/zarlcorp/zvault/internal/qr"
 	"github.com/zarlcorp/zvault/internal/secret"
+	"github.com/zarlcorp/zvault/internal/sshkey"
 	"github.com/zarlcorp/zvault/internal/totp"
 	"github.com/zarlcorp/zvault/internal/vault"
 )
@@ -32,6 +34,9 @@ type secretDetailModel struct {
 	// delete confirmation
 	confirmDelete bool
 
+	// rendered QR code, shown in place of the fields when set
+	qrCode string
+
 	// field cursor for copy
 	cursor int
 	fields []detailField
@@ -88,7 +93,14 @@ func (m secretDetailModel) load() secretDetailModel {
 }
 
 func (m *secretDetailModel) refreshTOTP() {
-	code, remaining, err := totp.Generate(m.secret.TOTPSecret())
+	s := m.secret
+	p, err := totp.ParseParams(s.TOTPAlgorithm(), s.TOTPDigits(), s.TOTPPeriod())
+	if err != nil {
+		m.totpCode = ""
+		m.totpRemaining = 0
+		return
+	}
+	code, remaining, err := totp.GenerateWith(s.TOTPSecret(), p)
 	if err != nil {
 		m.totpCode = ""
 		m.totpRemaining = 0
@@ -137,6 +149,9 @@ func buildDetailFields(s secret.Secret) []detailField {
 		fields = append(fields, detailField{label: "label", value: s.Label(), labelColor: normalColor})
 		fields = append(fields, detailField{label: "private key", value: s.PrivateKey(), sensitive: true, labelColor: sensitiveColor, action: actionCopy})
 		fields = append(fields, detailField{label: "public key", value: s.PublicKey(), labelColor: normalColor, action: actionCopy})
+		if s.Fingerprint() != "" {
+			fields = append(fields, detailField{label: "fingerprint", value: sshkey.Summary(s), labelColor: normalColor})
+		}
 		if s.Passphrase() != "" {
 			fields = append(fields, detailField{label: "passphrase", value: s.Passphrase(), sensitive: true, labelColor: sensitiveColor, action: actionCopy})
 		}
@@ -144,7 +159,16 @@ func buildDetailFields(s secret.Secret) []detailField {
 			fields = append(fields, detailField{label: "notes", value: s.Notes(), labelColor: normalColor})
 		}
 	case secret.TypeNote:
-		fields = append(fields, detailField{label: "content", value: s.Content(), labelColor: normalColor})
+		// env secrets and imported cards keep everything in fields
+		if s.Content() != "" || len(s.CustomFields()) == 0 {
+			fields = append(fields, detailField{label: "content", value: s.Content(), labelColor: normalColor})
+		}
+	}
+
+	// card details and imported custom fields, masked like the rest
+	for _, k := range s.CustomFields() {
+		label := strings.ReplaceAll(k, "_", " ")
+		fields = append(fields, detailField{label: label, value: s.Fields[k], sensitive: true, labelColor: sensitiveColor, action: actionCopy})
 	}
 
 	metaColor := zstyle.Subtext1
@@ -168,6 +192,7 @@ func (m secretDetailModel) Update(msg tea.Msg) (secretDetailModel, tea.Cmd) {
 				m.showSensitive = false
 				m.confirmDelete = false
 				m.clipboardMsg = ""
+				m.qrCode = ""
 				m.cursor = 0
 				m = m.load()
 				if m.hasTOTP {
@@ -196,6 +221,9 @@ func (m secretDetailModel) Update(msg tea.Msg) (secretDetailModel, tea.Cmd) {
 		if m.confirmDelete {
 			return m.handleDeleteConfirm(msg)
 		}
+		if m.qrCode != "" {
+			return m.handleQRKeys(msg)
+		}
 		return m.handleKeys(msg)
 	}
 	return m, nil
@@ -242,6 +270,30 @@ func (m secretDetailModel) handleKeys(msg tea.KeyMsg) (secretDetailModel, tea.Cm
 		}
 	case msg.String() == "d":
 		m.confirmDelete = true
+	case msg.String() == "r":
+		return m.showQR()
+	}
+	return m, nil
+}
+
+// showQR renders the secret's default QR payload (otpauth uri or main value).
+func (m secretDetailModel) showQR() (secretDetailModel, tea.Cmd) {
+	payload, err := qr.Payload(m.secret, "")
+	if err != nil {
+		return m, func() tea.Msg { return errMsg{err: err} }
+	}
+	code, err := qr.Encode(payload, qr.M)
+	if err != nil {
+		return m, func() tea.Msg { return errMsg{err: err} }
+	}
+	m.qrCode = code.Terminal()
+	return m, nil
+}
+
+// handleQRKeys closes the QR view on esc or r.
+func (m secretDetailModel) handleQRKeys(msg tea.KeyMsg) (secretDetailModel, tea.Cmd) {
+	if key.Matches(msg, zstyle.KeyBack) || msg.String() == "r" {
+		m.qrCode = ""
 	}
 	return m, nil
 }
@@ -295,6 +347,17 @@ func (m secretDetailModel) View() string {
 		return b.String()
 	}
 
+	if m.qrCode != "" {
+		b.WriteString("\n")
+		for _, line := range strings.Split(strings.TrimSuffix(m.qrCode, "\n"), "\n") {
+			b.WriteString("  " + line + "\n")
+		}
+		b.WriteString("\n")
+		b.WriteString(zstyle.MutedText.Render("  scan with your phone — esc to close"))
+		b.WriteString("\n")
+		return b.String()
+	}
+
 	valueStyle := lipgloss.NewStyle().Foreground(zstyle.Text)
 	maskedStyle := lipgloss.NewStyle().Foreground(zstyle.Surface2)
 	cursorStyle := lipgloss.NewStyle().Foreground(zstyle.ZvaultAccent)
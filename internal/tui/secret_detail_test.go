@@ -341,6 +341,36 @@ func TestBuildDetailFieldsSSHKey(t *testing.T) {
 	}
 }
 
+func TestBuildDetailFieldsSSHFingerprint(t *testing.T) {
+	s, err := secret.NewSSHKey("Server", "prod", "-----BEGIN-----", "ssh-ed25519 AAAA")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, f := range buildDetailFields(s) {
+		if f.label == "fingerprint" {
+			t.Fatal("fingerprint should be hidden when not recorded")
+		}
+	}
+
+	s.Fields["key_type"] = "ed25519"
+	s.Fields["key_bits"] = "256"
+	s.Fields["fingerprint"] = "SHA256:abc"
+
+	var found bool
+	for _, f := range buildDetailFields(s) {
+		if f.label == "fingerprint" {
+			found = true
+			if f.value != "256 SHA256:abc (ED25519)" {
+				t.Errorf("fingerprint value = %q", f.value)
+			}
+		}
+	}
+	if !found {
+		t.Error("ssh key should show fingerprint")
+	}
+}
+
 func TestBuildDetailFieldsNote(t *testing.T) {
 	s, err := secret.NewNote("Ideas", "some content")
 	if err != nil {
@@ -362,6 +392,27 @@ func TestBuildDetailFieldsNote(t *testing.T) {
 	}
 }
 
+func TestBuildDetailFieldsCustom(t *testing.T) {
+	s, err := secret.NewNote("Visa", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Fields["number"] = "4111111111111111"
+	s.Fields["exp_year"] = "2030"
+	fields := buildDetailFields(s)
+
+	got := map[string]detailField{}
+	for _, f := range fields {
+		got[f.label] = f
+	}
+	if f, ok := got["number"]; !ok || f.value != "4111111111111111" || !f.sensitive {
+		t.Errorf("number = %+v, want a sensitive field", f)
+	}
+	if _, ok := got["exp year"]; !ok {
+		t.Error("missing exp year field")
+	}
+}
+
 func TestBuildDetailFieldsActions(t *testing.T) {
 	s, err := secret.NewPassword("Test", "http://example.com", "user", "pass123")
 	if err != nil {
@@ -461,3 +512,57 @@ func TestBuildDetailFieldsMetadata(t *testing.T) {
 		}
 	}
 }
+
+func TestSecretDetailQRToggle(t *testing.T) {
+	s, err := secret.NewPassword("test", "http://example.com", "user", "pass123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := newSecretDetail()
+	m.secretID = "test"
+	m.secret = s
+	m.fields = buildDetailFields(m.secret)
+
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'r'}})
+	if m.qrCode == "" {
+		t.Fatal("r should render a qr code")
+	}
+	view := m.View()
+	if !strings.Contains(view, "▀") && !strings.Contains(view, "▄") {
+		t.Error("qr view should contain half-block modules")
+	}
+	if strings.Contains(view, "pass123") {
+		t.Error("qr view should not print the value as text")
+	}
+
+	// esc closes the qr view without leaving the detail view
+	m, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEscape})
+	if m.qrCode != "" {
+		t.Fatal("esc should close the qr view")
+	}
+	if cmd != nil {
+		t.Fatal("esc on qr view should not navigate")
+	}
+}
+
+func TestSecretDetailQRUsesTOTPURI(t *testing.T) {
+	s, err := secret.NewPassword("GitHub", "", "alice", "pass")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Fields["totp_secret"] = "JBSWY3DPEHPK3PXP"
+
+	m := newSecretDetail()
+	m.secretID = "test"
+	m.secret = s
+
+	withTOTP, _ := m.showQR()
+
+	s.Fields["totp_secret"] = ""
+	m.secret = s
+	plain, _ := m.showQR()
+
+	if withTOTP.qrCode == plain.qrCode {
+		t.Fatal("totp secret should change the qr payload")
+	}
+}
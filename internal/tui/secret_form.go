@@ -10,6 +10,7 @@ import (
 	"github.com/charmbracelet/lipgloss"
 	"github.com/zarlcorp/core/pkg/zstyle"
 	"github.com/zarlcorp/zvault/internal/secret"
+	"github.com/zarlcorp/zvault/internal/sshkey"
 	"github.com/zarlcorp/zvault/internal/vault"
 )
 
@@ -36,6 +37,11 @@ type secretFormModel struct {
 	// confirm discard on esc if changed
 	confirmDiscard bool
 
+	// a private key, generated or being edited, whose line breaks the
+	// single-line input can't hold, and how the input shows it
+	keyPEM   string
+	keyShown string
+
 	width  int
 	height int
 }
@@ -83,6 +89,7 @@ func (m secretFormModel) initForCreate() secretFormModel {
 	m.err = ""
 	m.confirmDiscard = false
 	m.inputs = buildFormInputs(m.secType, secret.Secret{})
+	m.keyPEM, m.keyShown = "", ""
 	m.focused = -1 // type selector focused first
 	m.focusCurrent()
 	return m
@@ -113,6 +120,7 @@ func (m secretFormModel) initForEdit(id string) secretFormModel {
 		}
 	}
 	m.inputs = buildFormInputs(s.Type, s)
+	m.keyPEM, m.keyShown = s.PrivateKey(), m.inputValue("private_key")
 	m.focused = 0
 	m.focusCurrent()
 	return m
@@ -235,6 +243,9 @@ func (m secretFormModel) handleKeys(msg tea.KeyMsg) (secretFormModel, tea.Cmd) {
 	case msg.Type == tea.KeyCtrlS:
 		return m.save()
 
+	case msg.Type == tea.KeyCtrlG && m.secType == secret.TypeSSHKey:
+		return m.generateKey(), nil
+
 	case key.Matches(msg, zstyle.KeyBack):
 		if m.changed {
 			m.confirmDiscard = true
@@ -325,12 +336,55 @@ func (m *secretFormModel) rebuildInputsPreserveName() {
 	m.focusCurrent()
 }
 
-func (m secretFormModel) save() (secretFormModel, tea.Cmd) {
-	// collect values
+// generateKey fills the key fields with a new ed25519 pair, using the label
+// as the key comment and encrypting with the passphrase if one is entered.
+func (m secretFormModel) generateKey() secretFormModel {
+	p, err := sshkey.Generate(sshkey.TypeEd25519, 0, m.inputValue("label"), m.inputValue("passphrase"))
+	if err != nil {
+		m.err = err.Error()
+		return m
+	}
+	m.setInput("private_key", p.PrivateKey)
+	m.setInput("public_key", p.PublicKey)
+	m.keyPEM, m.keyShown = p.PrivateKey, m.inputValue("private_key")
+	m.changed = true
+	m.err = ""
+	return m
+}
+
+func (m secretFormModel) inputValue(fieldKey string) string {
+	for _, inp := range m.inputs {
+		if inp.fieldKey == fieldKey {
+			return inp.input.Value()
+		}
+	}
+	return ""
+}
+
+func (m *secretFormModel) setInput(fieldKey, value string) {
+	for i := range m.inputs {
+		if m.inputs[i].fieldKey == fieldKey {
+			m.inputs[i].input.SetValue(value)
+			return
+		}
+	}
+}
+
+// values collects the inputs by field key. A private key left as it was
+// generated or loaded keeps its line breaks.
+func (m secretFormModel) values() map[string]string {
 	vals := make(map[string]string)
 	for _, inp := range m.inputs {
 		vals[inp.fieldKey] = inp.input.Value()
 	}
+	if m.keyPEM != "" && vals["private_key"] == m.keyShown {
+		vals["private_key"] = m.keyPEM
+	}
+	return vals
+}
+
+func (m secretFormModel) save() (secretFormModel, tea.Cmd) {
+	vals := m.values()
 
 	name := strings.TrimSpace(vals["name"])
 	if name == "" {
@@ -392,6 +446,10 @@ func (m secretFormModel) createSecret(name string, vals map[string]string, tags
 		if v := vals["notes"]; v != "" {
 			s.Fields["notes"] = v
 		}
+		if err := sshkey.Validate(&s); err != nil {
+			m.err = err.Error()
+			return m, nil
+		}
 	case secret.TypeNote:
 		s, err = secret.NewNote(name, vals["content"])
 		if err != nil {
@@ -445,6 +503,10 @@ func (m secretFormModel) updateSecret(name string, vals map[string]string, tags
 		s.Fields["public_key"] = vals["public_key"]
 		s.Fields["passphrase"] = vals["passphrase"]
 		s.Fields["notes"] = vals["notes"]
+		if err := sshkey.Validate(&s); err != nil {
+			m.err = err.Error()
+			return m, nil
+		}
 	case secret.TypeNote:
 		s.Fields["content"] = vals["content"]
 	}
@@ -510,6 +572,12 @@ func (m secretFormModel) View() string {
 		}
 	}
 
+	if m.secType == secret.TypeSSHKey {
+		b.WriteString("\n")
+		b.WriteString(zstyle.MutedText.Render("  ctrl+g to generate an ed25519 key pair"))
+		b.WriteString("\n")
+	}
+
 	// confirm discard
 	if m.confirmDiscard {
 		warn := lipgloss.NewStyle().Foreground(zstyle.Warning)
@@ -0,0 +1,194 @@
+package vault
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/zarlcorp/zvault/internal/secret"
+	"github.com/zarlcorp/zvault/internal/task"
+)
+
+// namesFile is the plain-text index of a vault's names, kept next to the
+// encrypted collections so shell completion can offer them without the
+// password. It holds no values, and only the owner can read it. A vault
+// keeps it only once IndexNames has written it.
+const namesFile = "names.json"
+
+// Names lists the secrets and tasks of a vault by name, without values.
+type Names struct {
+	Secrets []SecretName `json:"secrets"`
+	Tasks   []TaskName   `json:"tasks"`
+}
+
+// SecretName identifies a secret in the name index.
+type SecretName struct {
+	ID   string      `json:"id"`
+	Name string      `json:"name"`
+	Type secret.Type `json:"type"`
+	Tags []string    `json:"tags,omitempty"`
+}
+
+// TaskName identifies a task in the name index.
+type TaskName struct {
+	ID    string   `json:"id"`
+	Title string   `json:"title"`
+	Done  bool     `json:"done,omitempty"`
+	Tags  []string `json:"tags,omitempty"`
+}
+
+// ReadNames reads the name index of the vault in dir. It doesn't need the
+// password; once enabled, the index is written whenever the vault changes.
+func ReadNames(dir string) (Names, error) {
+	var n Names
+	data, err := os.ReadFile(filepath.Join(dir, namesFile))
+	if err != nil {
+		return n, err
+	}
+	if err := json.Unmarshal(data, &n); err != nil {
+		return n, fmt.Errorf("read name index: %w", err)
+	}
+	return n, nil
+}
+
+// RemoveNames deletes the name index of the vault in dir, after which the
+// vault no longer keeps one.
+func RemoveNames(dir string) error {
+	err := os.Remove(filepath.Join(dir, namesFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// IndexNames writes the name index that shell completion reads. From then
+// on the vault keeps it up to date, until RemoveNames.
+func (v *Vault) IndexNames() error {
+	secrets, err := v.secrets.col.List()
+	if err != nil {
+		return fmt.Errorf("list secrets: %w", err)
+	}
+	tasks, err := v.tasks.col.List()
+	if err != nil {
+		return fmt.Errorf("list tasks: %w", err)
+	}
+	return v.secrets.names.rebuild(secrets, tasks)
+}
+
+// nameIndex keeps names.json, when it exists, in step with the
+// collections. Writes are best effort: a stale index only costs
+// completions, so it never fails a change to the vault.
+type nameIndex struct {
+	mu   sync.Mutex
+	path string // empty for vaults without a directory
+}
+
+func newNameIndex(dir string) *nameIndex {
+	if dir == "" {
+		return &nameIndex{}
+	}
+	return &nameIndex{path: filepath.Join(dir, namesFile)}
+}
+
+// rebuild writes the index afresh with the given secrets and tasks.
+func (x *nameIndex) rebuild(secrets []secret.Secret, tasks []task.Task) error {
+	if x.path == "" {
+		return errors.New("vault has no directory to index")
+	}
+	return x.write(true, func(n *Names) {
+		n.Secrets = n.Secrets[:0]
+		n.Tasks = n.Tasks[:0]
+		for _, sec := range secrets {
+			n.Secrets = append(n.Secrets, secretName(sec))
+		}
+		for _, tk := range tasks {
+			n.Tasks = append(n.Tasks, taskName(tk))
+		}
+	})
+}
+
+func (x *nameIndex) putSecret(sec secret.Secret) {
+	x.update(func(n *Names) {
+		n.Secrets = slices.DeleteFunc(n.Secrets, func(s SecretName) bool { return s.ID == sec.ID })
+		n.Secrets = append(n.Secrets, secretName(sec))
+	})
+}
+
+func (x *nameIndex) deleteSecret(id string) {
+	x.update(func(n *Names) {
+		n.Secrets = slices.DeleteFunc(n.Secrets, func(s SecretName) bool { return s.ID == id })
+	})
+}
+
+func (x *nameIndex) putTask(tk task.Task) {
+	x.update(func(n *Names) {
+		n.Tasks = slices.DeleteFunc(n.Tasks, func(t TaskName) bool { return t.ID == tk.ID })
+		n.Tasks = append(n.Tasks, taskName(tk))
+	})
+}
+
+func (x *nameIndex) deleteTask(id string) {
+	x.update(func(n *Names) {
+		n.Tasks = slices.DeleteFunc(n.Tasks, func(t TaskName) bool { return t.ID == id })
+	})
+}
+
+// update applies fn to the index on disk, if there is one.
+func (x *nameIndex) update(fn func(*Names)) {
+	if x.path == "" {
+		return
+	}
+	_ = x.write(false, fn)
+}
+
+// write applies fn to the index, replacing the file atomically. Without
+// create, a missing index is left missing.
+func (x *nameIndex) write(create bool, fn func(*Names)) error {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	n, err := ReadNames(filepath.Dir(x.path))
+	switch {
+	case errors.Is(err, os.ErrNotExist) && !create:
+		return nil
+	case err != nil:
+		n = Names{} // missing or unreadable: start over
+	}
+	fn(&n)
+	slices.SortFunc(n.Secrets, func(a, b SecretName) int { return strings.Compare(a.Name, b.Name) })
+	slices.SortFunc(n.Tasks, func(a, b TaskName) int { return strings.Compare(a.ID, b.ID) })
+
+	data, err := json.Marshal(n)
+	if err != nil {
+		return fmt.Errorf("write name index: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(x.path), ".names-*")
+	if err != nil {
+		return fmt.Errorf("write name index: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("write name index: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("write name index: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), x.path); err != nil {
+		return fmt.Errorf("write name index: %w", err)
+	}
+	return nil
+}
+
+func secretName(sec secret.Secret) SecretName {
+	return SecretName{ID: sec.ID, Name: sec.Name, Type: sec.Type, Tags: sec.Tags}
+}
+
+func taskName(tk task.Task) TaskName {
+	return TaskName{ID: tk.ID, Title: tk.Title, Done: tk.Done, Tags: tk.Tags}
+}
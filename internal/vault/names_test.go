@@ -0,0 +1,134 @@
+package vault_test
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/zarlcorp/zvault/internal/secret"
+	"github.com/zarlcorp/zvault/internal/task"
+	"github.com/zarlcorp/zvault/internal/vault"
+)
+
+func TestNameIndex(t *testing.T) {
+	dir := t.TempDir()
+	v, err := vault.Open(dir, "password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer v.Close()
+	if err := v.IndexNames(); err != nil {
+		t.Fatal(err)
+	}
+
+	sec, err := secret.NewPassword("github", "https://github.com", "me", "hunter2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sec.Tags = []string{"work"}
+	if err := v.Secrets().Add(sec); err != nil {
+		t.Fatal(err)
+	}
+	tk, err := task.New("renew cert")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := v.Tasks().Add(tk); err != nil {
+		t.Fatal(err)
+	}
+
+	names, err := vault.ReadNames(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(names.Secrets) != 1 || names.Secrets[0].Name != "github" || names.Secrets[0].Tags[0] != "work" {
+		t.Errorf("secrets = %+v", names.Secrets)
+	}
+	if len(names.Tasks) != 1 || names.Tasks[0].Title != "renew cert" {
+		t.Errorf("tasks = %+v", names.Tasks)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "names.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "hunter2") || strings.Contains(string(data), "https://") {
+		t.Errorf("index holds values: %s", data)
+	}
+	if runtime.GOOS != "windows" {
+		info, err := os.Stat(filepath.Join(dir, "names.json"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Mode().Perm() != 0o600 {
+			t.Errorf("mode = %v, want 0600", info.Mode().Perm())
+		}
+	}
+
+	sec.Name = "github-work"
+	if err := v.Secrets().Update(sec); err != nil {
+		t.Fatal(err)
+	}
+	if err := v.Tasks().Delete(tk.ID); err != nil {
+		t.Fatal(err)
+	}
+	names, err = vault.ReadNames(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(names.Secrets) != 1 || names.Secrets[0].Name != "github-work" {
+		t.Errorf("after rename: %+v", names.Secrets)
+	}
+	if len(names.Tasks) != 0 {
+		t.Errorf("after delete: %+v", names.Tasks)
+	}
+}
+
+func TestNameIndexOptIn(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "names.json")
+	v, err := vault.Open(dir, "password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer v.Close()
+
+	sec, err := secret.NewNote("todo", "x")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := v.Secrets().Add(sec); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("index written without IndexNames: %v", err)
+	}
+
+	// enabling indexes what is already there
+	if err := v.IndexNames(); err != nil {
+		t.Fatal(err)
+	}
+	names, err := vault.ReadNames(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(names.Secrets) != 1 || names.Secrets[0].ID != sec.ID {
+		t.Errorf("secrets = %+v", names.Secrets)
+	}
+
+	// once removed, changes don't bring it back
+	if err := vault.RemoveNames(dir); err != nil {
+		t.Fatal(err)
+	}
+	if err := v.Secrets().Delete(sec.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("index came back after RemoveNames: %v", err)
+	}
+	if err := vault.RemoveNames(dir); err != nil {
+		t.Errorf("removing a missing index: %v", err)
+	}
+}
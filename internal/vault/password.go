@@ -0,0 +1,109 @@
+package vault
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// PasswordFromEnv reads the vault password from the environment. The
+// first of these that is set wins:
+//
+//	ZVAULT_PASSWORD_FILE     a file holding the password
+//	ZVAULT_PASSWORD_COMMAND  a command printing the password, run by the shell
+//	ZVAULT_PASSWORD          the password itself
+//
+// One trailing newline is trimmed from a file or command's output.
+// Returns an empty string if none is set.
+func PasswordFromEnv() (string, error) {
+	if path := os.Getenv("ZVAULT_PASSWORD_FILE"); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("ZVAULT_PASSWORD_FILE: %w", err)
+		}
+		return nonEmpty(trimNewline(string(data)), "ZVAULT_PASSWORD_FILE")
+	}
+	if command := os.Getenv("ZVAULT_PASSWORD_COMMAND"); command != "" {
+		pw, err := passwordFromCommand(command)
+		if err != nil {
+			return "", fmt.Errorf("ZVAULT_PASSWORD_COMMAND: %w", err)
+		}
+		return nonEmpty(pw, "ZVAULT_PASSWORD_COMMAND")
+	}
+	return os.Getenv("ZVAULT_PASSWORD"), nil
+}
+
+// PasswordFromFD reads the vault password from an open file descriptor,
+// up to the first newline, so a pipe can carry it from a parent process
+// without it showing up in the environment. The descriptor is closed,
+// unless it's stdin: nothing past the newline is read, so the password may
+// precede other input there.
+func PasswordFromFD(fd int) (string, error) {
+	f := os.Stdin
+	if fd != 0 {
+		// the descriptor is ours to close; an os.File left to the
+		// garbage collector would close it at some later point anyway
+		f = os.NewFile(uintptr(fd), fmt.Sprintf("fd %d", fd))
+		if f == nil {
+			return "", fmt.Errorf("password fd %d: not open", fd)
+		}
+		defer f.Close()
+	}
+
+	var pw []byte
+	b := make([]byte, 1)
+	for {
+		n, err := f.Read(b)
+		if n == 1 {
+			if b[0] == '\n' {
+				break
+			}
+			pw = append(pw, b[0])
+			continue
+		}
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return "", fmt.Errorf("password fd %d: %w", fd, err)
+		}
+	}
+	return nonEmpty(strings.TrimSuffix(string(pw), "\r"), fmt.Sprintf("password fd %d", fd))
+}
+
+// passwordFromCommand runs command with the system shell and returns what
+// it prints. The command shares zvault's stdin and stderr, so a helper
+// can prompt on the terminal.
+func passwordFromCommand(command string) (string, error) {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/C", command)
+	} else {
+		cmd = exec.Command("/bin/sh", "-c", command)
+	}
+	var out bytes.Buffer
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = &out
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return "", err
+	}
+	return trimNewline(out.String()), nil
+}
+
+func trimNewline(s string) string {
+	s = strings.TrimSuffix(s, "\n")
+	return strings.TrimSuffix(s, "\r")
+}
+
+func nonEmpty(pw, source string) (string, error) {
+	if pw == "" {
+		return "", fmt.Errorf("%s: empty password", source)
+	}
+	return pw, nil
+}
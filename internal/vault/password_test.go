@@ -0,0 +1,97 @@
+package vault_test
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/zarlcorp/zvault/internal/vault"
+)
+
+// clearPasswordEnv unsets every password variable for the test.
+func clearPasswordEnv(t *testing.T) {
+	t.Helper()
+	for _, k := range []string{"ZVAULT_PASSWORD", "ZVAULT_PASSWORD_FILE", "ZVAULT_PASSWORD_COMMAND"} {
+		t.Setenv(k, "")
+	}
+}
+
+func TestPasswordFromEnv(t *testing.T) {
+	clearPasswordEnv(t)
+	t.Setenv("ZVAULT_PASSWORD", "env-pass")
+	if got, err := vault.PasswordFromEnv(); err != nil || got != "env-pass" {
+		t.Fatalf("got %q, %v, want %q", got, err, "env-pass")
+	}
+}
+
+func TestPasswordFromEnvEmpty(t *testing.T) {
+	clearPasswordEnv(t)
+	if got, err := vault.PasswordFromEnv(); err != nil || got != "" {
+		t.Fatalf("got %q, %v, want empty", got, err)
+	}
+}
+
+func TestPasswordFromFile(t *testing.T) {
+	clearPasswordEnv(t)
+	path := filepath.Join(t.TempDir(), "pw")
+	if err := os.WriteFile(path, []byte("file pass \n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("ZVAULT_PASSWORD_FILE", path)
+	t.Setenv("ZVAULT_PASSWORD", "env-pass")
+
+	// the file wins, and only its newline is trimmed
+	if got, err := vault.PasswordFromEnv(); err != nil || got != "file pass " {
+		t.Fatalf("got %q, %v, want %q", got, err, "file pass ")
+	}
+
+	t.Setenv("ZVAULT_PASSWORD_FILE", filepath.Join(t.TempDir(), "missing"))
+	if _, err := vault.PasswordFromEnv(); err == nil {
+		t.Error("missing file: want error")
+	}
+
+	if err := os.WriteFile(path, []byte("\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("ZVAULT_PASSWORD_FILE", path)
+	if _, err := vault.PasswordFromEnv(); err == nil {
+		t.Error("empty file: want error")
+	}
+}
+
+func TestPasswordFromCommand(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh")
+	}
+	clearPasswordEnv(t)
+	t.Setenv("ZVAULT_PASSWORD_COMMAND", "printf 'cmd pass\\n'")
+	t.Setenv("ZVAULT_PASSWORD", "env-pass")
+	if got, err := vault.PasswordFromEnv(); err != nil || got != "cmd pass" {
+		t.Fatalf("got %q, %v, want %q", got, err, "cmd pass")
+	}
+
+	t.Setenv("ZVAULT_PASSWORD_COMMAND", "exit 3")
+	if _, err := vault.PasswordFromEnv(); err == nil {
+		t.Error("failing command: want error")
+	}
+}
+
+func TestPasswordFromFD(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.WriteString("pipe pass\nrest of input"); err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+
+	// PasswordFromFD closes the descriptor, as a caller handing it over
+	// with --password-fd would expect
+	got, err := vault.PasswordFromFD(int(r.Fd()))
+	r.Close() // already closed; this drops r's finalizer
+	if err != nil || got != "pipe pass" {
+		t.Fatalf("got %q, %v, want %q", got, err, "pipe pass")
+	}
+}
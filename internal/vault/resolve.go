@@ -0,0 +1,208 @@
+package vault
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/zarlcorp/zvault/internal/secret"
+	"github.com/zarlcorp/zvault/internal/task"
+)
+
+// MinPrefix is the shortest id prefix a reference may use, as in git.
+const MinPrefix = 4
+
+// NotFoundError reports a reference that matched nothing.
+type NotFoundError struct {
+	Kind string // "secret" or "task"
+	Ref  string
+
+	// Short is set when Ref begins some ids but is shorter than MinPrefix.
+	Short bool
+}
+
+func (e *NotFoundError) Error() string {
+	if e.Short {
+		return fmt.Sprintf("%s %q not found (id prefixes need at least %d characters)", e.Kind, e.Ref, MinPrefix)
+	}
+	return fmt.Sprintf("%s %q not found", e.Kind, e.Ref)
+}
+
+// AmbiguousError reports a reference that matched more than one item.
+type AmbiguousError struct {
+	Kind    string
+	Ref     string
+	Matches []Match
+}
+
+// Match is a candidate of an ambiguous reference: a secret's name or a
+// task's title, with its id.
+type Match struct {
+	ID   string
+	Name string
+}
+
+func (e *AmbiguousError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %q is ambiguous; it matches:", e.Kind, e.Ref)
+	for _, m := range e.Matches {
+		fmt.Fprintf(&b, "\n  %s  %s", m.ID, m.Name)
+	}
+	return b.String()
+}
+
+// Resolve finds the secret a reference names. In order, a reference is:
+//
+//   - a secret's id
+//   - its name, ignoring case
+//   - an id prefix of at least MinPrefix characters
+//   - a name or id prefix qualified by a tag, as tag:name or tag/name;
+//     the tag work also covers work/aws
+//
+// More than one secret at the first step that matches gives an
+// *AmbiguousError, and none a *NotFoundError.
+func (s *SecretStore) Resolve(ref string) (secret.Secret, error) {
+	all, err := s.col.List()
+	if err != nil {
+		return secret.Secret{}, fmt.Errorf("list secrets: %w", err)
+	}
+	items := make([]refItem, len(all))
+	for i, sec := range all {
+		items[i] = refItem{id: sec.ID, name: sec.Name, tags: sec.Tags}
+	}
+	i, err := resolveRef("secret", ref, items, false)
+	if err != nil {
+		return secret.Secret{}, err
+	}
+	return all[i], nil
+}
+
+// Resolve finds the task a reference names, like SecretStore.Resolve,
+// matching titles instead of names. A task may also be named by part of
+// its title, ignoring case, when nothing else matches.
+func (s *TaskStore) Resolve(ref string) (task.Task, error) {
+	all, err := s.col.List()
+	if err != nil {
+		return task.Task{}, fmt.Errorf("list tasks: %w", err)
+	}
+	items := make([]refItem, len(all))
+	for i, tk := range all {
+		items[i] = refItem{id: tk.ID, name: tk.Title, tags: tk.Tags}
+	}
+	i, err := resolveRef("task", ref, items, true)
+	if err != nil {
+		return task.Task{}, err
+	}
+	return all[i], nil
+}
+
+// refItem is what resolveRef matches against.
+type refItem struct {
+	id, name string
+	tags     []string
+}
+
+// resolveRef returns the index of the item ref names. With substrings,
+// part of a name matches as a last resort.
+func resolveRef(kind, ref string, items []refItem, substrings bool) (int, error) {
+	if ref == "" {
+		return 0, &NotFoundError{Kind: kind, Ref: ref}
+	}
+	for i, it := range items {
+		if it.id == ref {
+			return i, nil
+		}
+	}
+
+	all := make([]int, len(items))
+	for i := range items {
+		all[i] = i
+	}
+	if i, err := matchRef(kind, ref, ref, items, all, substrings); i >= 0 || err != nil {
+		return i, err
+	}
+
+	if tag, name, ok := splitQualifier(ref); ok {
+		var tagged []int
+		for i, it := range items {
+			if hasTag(it.tags, tag) {
+				tagged = append(tagged, i)
+			}
+		}
+		if i, err := matchRef(kind, ref, name, items, tagged, substrings); i >= 0 || err != nil {
+			return i, err
+		}
+	}
+
+	short := false
+	if len(ref) < MinPrefix {
+		for _, it := range items {
+			if strings.HasPrefix(it.id, ref) {
+				short = true
+			}
+		}
+	}
+	return 0, &NotFoundError{Kind: kind, Ref: ref, Short: short}
+}
+
+// matchRef looks for name among the items at idx: as a whole name, an id
+// prefix, then, with substrings, part of a name. It returns the index of
+// a single match, an *AmbiguousError for several, or -1 for none.
+func matchRef(kind, ref, name string, items []refItem, idx []int, substrings bool) (int, error) {
+	steps := []func(refItem) bool{
+		func(it refItem) bool { return strings.EqualFold(it.name, name) },
+		func(it refItem) bool { return len(name) >= MinPrefix && strings.HasPrefix(it.id, name) },
+	}
+	if substrings {
+		lower := strings.ToLower(name)
+		steps = append(steps, func(it refItem) bool { return strings.Contains(strings.ToLower(it.name), lower) })
+	}
+
+	for _, match := range steps {
+		var found []int
+		for _, i := range idx {
+			if match(items[i]) {
+				found = append(found, i)
+			}
+		}
+		switch len(found) {
+		case 0:
+			continue
+		case 1:
+			return found[0], nil
+		}
+		err := &AmbiguousError{Kind: kind, Ref: ref}
+		for _, i := range found {
+			err.Matches = append(err.Matches, Match{ID: items[i].id, Name: items[i].name})
+		}
+		slices.SortFunc(err.Matches, func(a, b Match) int {
+			return cmp.Or(strings.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name)), strings.Compare(a.ID, b.ID))
+		})
+		return -1, err
+	}
+	return -1, nil
+}
+
+// splitQualifier splits tag:name at the first colon, or tag/name at the
+// last slash, since tags may hold slashes themselves.
+func splitQualifier(ref string) (tag, name string, ok bool) {
+	if tag, name, ok := strings.Cut(ref, ":"); ok && tag != "" && name != "" {
+		return tag, name, true
+	}
+	if i := strings.LastIndex(ref, "/"); i > 0 && i < len(ref)-1 {
+		return ref[:i], ref[i+1:], true
+	}
+	return "", "", false
+}
+
+// hasTag reports whether tags holds tag or a tag below it.
+func hasTag(tags []string, tag string) bool {
+	tag = strings.TrimSuffix(tag, "/")
+	for _, t := range tags {
+		if t == tag || strings.HasPrefix(t, tag+"/") {
+			return true
+		}
+	}
+	return false
+}
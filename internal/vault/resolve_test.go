@@ -0,0 +1,122 @@
+package vault_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/zarlcorp/zvault/internal/secret"
+	"github.com/zarlcorp/zvault/internal/task"
+	"github.com/zarlcorp/zvault/internal/vault"
+)
+
+func addSecret(t *testing.T, v *vault.Vault, id, name string, tags ...string) {
+	t.Helper()
+	s, err := secret.NewPassword(name, "", "user", "pass")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.ID, s.Tags = id, tags
+	if err := v.Secrets().Add(s); err != nil {
+		t.Fatalf("add: %v", err)
+	}
+}
+
+func TestSecretResolve(t *testing.T) {
+	v := openTestVault(t)
+	addSecret(t, v, "abcd1111", "GitHub", "personal")
+	addSecret(t, v, "abcd2222", "aws", "work/prod")
+	addSecret(t, v, "ef013333", "aws", "home")
+
+	tests := []struct {
+		ref, want string
+	}{
+		{"abcd1111", "abcd1111"},
+		{"github", "abcd1111"},
+		{"abcd2", "abcd2222"},
+		{"work:aws", "abcd2222"},
+		{"work/prod/aws", "abcd2222"},
+		{"work/aws", "abcd2222"},
+		{"home:ef01", "ef013333"},
+	}
+	for _, tt := range tests {
+		got, err := v.Secrets().Resolve(tt.ref)
+		if err != nil || got.ID != tt.want {
+			t.Errorf("Resolve(%q) = %q, %v, want %q", tt.ref, got.ID, err, tt.want)
+		}
+	}
+}
+
+func TestSecretResolveAmbiguous(t *testing.T) {
+	v := openTestVault(t)
+	addSecret(t, v, "abcd2222", "aws")
+	addSecret(t, v, "abcd1111", "AWS")
+
+	for _, ref := range []string{"aws", "abcd"} {
+		_, err := v.Secrets().Resolve(ref)
+		var amb *vault.AmbiguousError
+		if !errors.As(err, &amb) {
+			t.Fatalf("Resolve(%q): want *AmbiguousError, got %v", ref, err)
+		}
+		if len(amb.Matches) != 2 || amb.Matches[0].ID != "abcd1111" || amb.Matches[1].ID != "abcd2222" {
+			t.Errorf("Resolve(%q) matches = %v", ref, amb.Matches)
+		}
+	}
+}
+
+func TestSecretResolveNotFound(t *testing.T) {
+	v := openTestVault(t)
+	addSecret(t, v, "abcd1111", "github")
+
+	tests := []struct {
+		ref   string
+		short bool
+	}{
+		{"abc", true}, // too short to be a prefix
+		{"gitlab", false},
+		{"work:github", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		_, err := v.Secrets().Resolve(tt.ref)
+		var nf *vault.NotFoundError
+		if !errors.As(err, &nf) || nf.Short != tt.short {
+			t.Errorf("Resolve(%q) = %v, want not found (short %v)", tt.ref, err, tt.short)
+		}
+	}
+}
+
+func TestTaskResolve(t *testing.T) {
+	v := openTestVault(t)
+	for _, tt := range []struct{ id, title, tag string }{
+		{"aaaa1111", "deploy api", "work"},
+		{"bbbb2222", "deploy web", "work"},
+		{"cccc3333", "buy milk", "home"},
+	} {
+		tk, err := task.New(tt.title)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tk.ID, tk.Tags = tt.id, []string{tt.tag}
+		if err := v.Tasks().Add(tk); err != nil {
+			t.Fatalf("add: %v", err)
+		}
+	}
+
+	for ref, want := range map[string]string{
+		"buy milk":  "cccc3333",
+		"MILK":      "cccc3333",
+		"bbbb":      "bbbb2222",
+		"work:api":  "aaaa1111",
+		"home/milk": "cccc3333",
+	} {
+		got, err := v.Tasks().Resolve(ref)
+		if err != nil || got.ID != want {
+			t.Errorf("Resolve(%q) = %q, %v, want %q", ref, got.ID, err, want)
+		}
+	}
+
+	var amb *vault.AmbiguousError
+	if _, err := v.Tasks().Resolve("deploy"); !errors.As(err, &amb) || len(amb.Matches) != 2 {
+		t.Errorf("Resolve(deploy) = %v, want two matches", err)
+	}
+}
@@ -22,6 +22,16 @@ type Vault struct {
 	tasks   *TaskStore
 }
 
+// newVault wires the collections to the name index for dir.
+func newVault(store *zstore.Store, secretCol *zstore.Collection[secret.Secret], taskCol *zstore.Collection[task.Task], dir string) *Vault {
+	names := newNameIndex(dir)
+	return &Vault{
+		store:   store,
+		secrets: &SecretStore{col: secretCol, names: names},
+		tasks:   &TaskStore{col: taskCol, names: names},
+	}
+}
+
 // Open opens or creates a vault at the given directory with the provided password.
 func Open(dir string, password string) (*Vault, error) {
 	fs := zfilesystem.NewOSFileSystem(dir)
@@ -46,11 +56,7 @@ func Open(dir string, password string) (*Vault, error) {
 		return nil, fmt.Errorf("open tasks collection: %w", err)
 	}
 
-	return &Vault{
-		store:   store,
-		secrets: &SecretStore{col: secretCol},
-		tasks:   &TaskStore{col: taskCol},
-	}, nil
+	return newVault(store, secretCol, taskCol, dir), nil
 }
 
 // OpenFS opens or creates a vault using the provided filesystem (for testing).
@@ -72,11 +78,7 @@ func OpenFS(fs zfilesystem.ReadWriteFileFS, password string) (*Vault, error) {
 		return nil, fmt.Errorf("open tasks collection: %w", err)
 	}
 
-	return &Vault{
-		store:   store,
-		secrets: &SecretStore{col: secretCol},
-		tasks:   &TaskStore{col: taskCol},
-	}, nil
+	return newVault(store, secretCol, taskCol, ""), nil
 }
 
 // Secrets returns the secret store.
@@ -100,20 +102,19 @@ func DefaultDir() string {
 	return filepath.Join(home, ".local", "share", "zvault")
 }
 
-// PasswordFromEnv reads the vault password from ZVAULT_PASSWORD environment variable.
-// Returns empty string if not set.
-func PasswordFromEnv() string {
-	return os.Getenv("ZVAULT_PASSWORD")
-}
-
 // SecretStore wraps a zstore collection for secrets.
 type SecretStore struct {
-	col *zstore.Collection[secret.Secret]
+	col   *zstore.Collection[secret.Secret]
+	names *nameIndex
 }
 
 // Add stores a new secret.
 func (s *SecretStore) Add(sec secret.Secret) error {
-	return s.col.Put(sec.ID, sec)
+	if err := s.col.Put(sec.ID, sec); err != nil {
+		return err
+	}
+	s.names.putSecret(sec)
+	return nil
 }
 
 // Get retrieves a secret by ID.
@@ -129,12 +130,20 @@ func (s *SecretStore) List() ([]secret.Secret, error) {
 // Update overwrites a secret, setting UpdatedAt.
 func (s *SecretStore) Update(sec secret.Secret) error {
 	sec.UpdatedAt = time.Now()
-	return s.col.Put(sec.ID, sec)
+	if err := s.col.Put(sec.ID, sec); err != nil {
+		return err
+	}
+	s.names.putSecret(sec)
+	return nil
 }
 
 // Delete removes a secret by ID.
 func (s *SecretStore) Delete(id string) error {
-	return s.col.Delete(id)
+	if err := s.col.Delete(id); err != nil {
+		return err
+	}
+	s.names.deleteSecret(id)
+	return nil
 }
 
 // Search returns secrets matching the query against name (case-insensitive
@@ -172,12 +181,17 @@ func matches(sec secret.Secret, query string) bool {
 
 // TaskStore wraps a zstore collection for tasks.
 type TaskStore struct {
-	col *zstore.Collection[task.Task]
+	col   *zstore.Collection[task.Task]
+	names *nameIndex
 }
 
 // Add stores a new task.
 func (s *TaskStore) Add(tk task.Task) error {
-	return s.col.Put(tk.ID, tk)
+	if err := s.col.Put(tk.ID, tk); err != nil {
+		return err
+	}
+	s.names.putTask(tk)
+	return nil
 }
 
 // Get retrieves a task by ID.
@@ -203,12 +217,20 @@ func (s *TaskStore) List(f task.Filter) ([]task.Task, error) {
 
 // Update overwrites a task.
 func (s *TaskStore) Update(tk task.Task) error {
-	return s.col.Put(tk.ID, tk)
+	if err := s.col.Put(tk.ID, tk); err != nil {
+		return err
+	}
+	s.names.putTask(tk)
+	return nil
 }
 
 // Delete removes a task by ID.
 func (s *TaskStore) Delete(id string) error {
-	return s.col.Delete(id)
+	if err := s.col.Delete(id); err != nil {
+		return err
+	}
+	s.names.deleteTask(id)
+	return nil
 }
 
 // ClearDone removes all completed tasks and returns the count deleted.
@@ -224,6 +246,7 @@ func (s *TaskStore) ClearDone() (int, error) {
 			if err := s.col.Delete(tk.ID); err != nil {
 				return count, fmt.Errorf("delete task %s: %w", tk.ID, err)
 			}
+			s.names.deleteTask(tk.ID)
 			count++
 		}
 	}
@@ -763,17 +763,3 @@ func TestDefaultDir(t *testing.T) {
 		t.Fatal("default dir is empty")
 	}
 }
-
-func TestPasswordFromEnv(t *testing.T) {
-	t.Setenv("ZVAULT_PASSWORD", "env-pass")
-	if got := vault.PasswordFromEnv(); got != "env-pass" {
-		t.Fatalf("got %q, want %q", got, "env-pass")
-	}
-}
-
-func TestPasswordFromEnvEmpty(t *testing.T) {
-	t.Setenv("ZVAULT_PASSWORD", "")
-	if got := vault.PasswordFromEnv(); got != "" {
-		t.Fatalf("got %q, want empty", got)
-	}
-}